@@ -8,11 +8,13 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"os/signal"
 	"path"
 	"runtime/debug"
 	"runtime/pprof"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/Vadman97/GolangChessAI/pkg/api/api_handlers"
@@ -20,6 +22,7 @@ import (
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/competition"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/server"
 	"github.com/gorilla/mux"
@@ -53,12 +56,9 @@ func main() {
 
 	if len(os.Args) > 1 {
 		if os.Args[1] == "lichess" {
-			// Restart the server if it exits unexpectedly (rate limits, crashes, etc.)
-			for {
-				server.ConnectLichess().Run()
-				log.Println("lichess server exited — restarting in 30s")
-				time.Sleep(30 * time.Second)
-			}
+			// Runs until SIGTERM or SIGINT; run_bot.sh restarts it.
+			runLichess(server.ConnectLichess(lichessURL()))
+			return
 		} else if os.Args[1] == "lichess-challenge" {
 			// Usage: ./main lichess-challenge <username> [limitSecs] [incrementSecs] [rated]
 			if len(os.Args) < 3 {
@@ -83,7 +83,31 @@ func main() {
 			if len(os.Args) > 5 {
 				cfg.Rated = os.Args[5] != "false"
 			}
-			server.ConnectLichessWithChallenge(cfg).Run()
+			runLichess(server.ConnectLichessWithChallenge(lichessURL(), cfg))
+			return
+		} else if os.Args[1] == "lichess-tournament" {
+			// Usage: ./main lichess-tournament [-swiss] [-berserk] [tournamentID]
+			fs := flag.NewFlagSet("lichess-tournament", flag.ExitOnError)
+			swiss := fs.Bool("swiss", false, "the tournament ID is a Swiss rather than an arena")
+			berserk := fs.Bool("berserk", false, "berserk arena games whose clock the config allows")
+			if err := fs.Parse(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			cfg := game_config.DefaultTournament()
+			if c := game_config.Get().Tournament; c != nil {
+				copied := *c
+				cfg = &copied
+			}
+			if id := fs.Arg(0); id != "" {
+				cfg.ArenaID, cfg.SwissID = id, ""
+				if *swiss {
+					cfg.ArenaID, cfg.SwissID = "", id
+				}
+			}
+			if *berserk {
+				cfg.Berserk = true
+			}
+			runLichess(server.ConnectLichessForTournament(lichessURL(), cfg))
 			return
 		} else if os.Args[1] == "stockfish-analysis" {
 			// Usage: ./main stockfish-analysis [games] [thinkMs] [sfDepth] [stockfishPath]
@@ -206,6 +230,7 @@ func main() {
 			sfDepth := fs.Int("sf-depth", 0, "Stockfish depth for best-move and loss comparison")
 			showRoot := fs.Int("show-root", 0, "print top N ABDADA root move scores before thread benchmark")
 			jsonPath := fs.String("json", "", "optional path to write a JSON benchmark report")
+			noSingular := fs.Bool("no-singular", false, "disable singular extensions and multi-cut")
 			if err := fs.Parse(os.Args[2:]); err != nil {
 				log.Fatal(err)
 			}
@@ -217,15 +242,16 @@ func main() {
 				log.Fatal("use either --depth or --think-ms, not both")
 			}
 			if err := analysis.RunABDADABench(analysis.ABDADABenchConfig{
-				FENPath:        *fenPath,
-				Threads:        threads,
-				Depth:          *depth,
-				ThinkTime:      time.Duration(*thinkMS) * time.Millisecond,
-				Runs:           *runs,
-				StockfishPath:  *stockfishPath,
-				StockfishDepth: *sfDepth,
-				ShowRoot:       *showRoot,
-				JSONPath:       *jsonPath,
+				FENPath:         *fenPath,
+				Threads:         threads,
+				Depth:           *depth,
+				ThinkTime:       time.Duration(*thinkMS) * time.Millisecond,
+				Runs:            *runs,
+				StockfishPath:   *stockfishPath,
+				StockfishDepth:  *sfDepth,
+				ShowRoot:        *showRoot,
+				JSONPath:        *jsonPath,
+				DisableSingular: *noSingular,
 			}); err != nil {
 				log.Fatal(err)
 			}
@@ -239,7 +265,9 @@ func main() {
 			runs := fs.Int("runs", 1, "runs per FEN and mode")
 			stockfishPath := fs.String("stockfish", "", "optional Stockfish binary path")
 			sfDepth := fs.Int("sf-depth", 0, "Stockfish depth for best-move and loss comparison")
-			modes := fs.String("modes", "abdada1tt,abdada8tt,abdada1nott,abdada8nott,abdada1safe,abdada8safe,negascouttt", "comma-separated modes; includes abdada1tt/8tt, abdada1nott/8nott, abdada1safe/8safe, abdada1nolmr/nonull/nofutility/norazor, negascouttt/nott")
+			modes := fs.String("modes", "abdada1tt,abdada8tt,abdada1nott,abdada8nott,abdada1safe,abdada8safe,negascouttt", "comma-separated modes; includes abdada1tt/8tt, abdada1nott/8nott, abdada1safe/8safe, abdada1nolmr/nonull/nofutility/norazor/nosingular/noprobcut, negascouttt/nott/noprobcut")
+			probCutMargin := fs.Int("probcut-margin", 0, "ProbCut margin above beta in centipawns; 0 uses the default")
+			probCutSEE := fs.Int("probcut-see", 0, "ProbCut only tries captures with SEE above this many centipawns")
 			forceMove := fs.String("force-move", "", "optional legal UCI root move to score instead of selecting the best move; requires --depth")
 			if err := fs.Parse(os.Args[2:]); err != nil {
 				log.Fatal(err)
@@ -257,6 +285,49 @@ func main() {
 				StockfishPath:  *stockfishPath,
 				StockfishDepth: *sfDepth,
 				Modes:          *modes,
+
+				ProbCutMargin:       *probCutMargin,
+				ProbCutSEEThreshold: *probCutSEE,
+			}); err != nil {
+				log.Fatal(err)
+			}
+			return
+		} else if os.Args[1] == "eval-symmetry" {
+			fs := flag.NewFlagSet("eval-symmetry", flag.ExitOnError)
+			fenPath := fs.String("fens", "testdata/abdada_fens.txt", "path to ABDADA benchmark FEN file; empty to skip")
+			boardsDir := fs.String("boards", "pkg/chessai/player/ai/evaluation_boards", "directory of evaluation test boards; empty to skip")
+			fen := fs.String("fen", "", "single FEN to check instead of --fens/--boards")
+			if err := fs.Parse(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			if err := analysis.RunEvalSymmetry(analysis.EvalSymmetryConfig{
+				FENPath:   *fenPath,
+				BoardsDir: *boardsDir,
+				FEN:       *fen,
+			}); err != nil {
+				log.Fatal(err)
+			}
+			return
+		} else if os.Args[1] == "mate-solve" {
+			fs := flag.NewFlagSet("mate-solve", flag.ExitOnError)
+			maxNodes := fs.Int("max-nodes", ai.DefaultProofNumberMaxNodes, "proof-number tree node budget")
+			args := os.Args[2:]
+			fen := ""
+			if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+				fen, args = args[0], args[1:]
+			}
+			if err := fs.Parse(args); err != nil {
+				log.Fatal(err)
+			}
+			if fen == "" && fs.NArg() == 1 {
+				fen = fs.Arg(0)
+			}
+			if fen == "" {
+				log.Fatal("usage: mate-solve '<fen>' [--max-nodes N]")
+			}
+			if err := analysis.RunMateSolve(analysis.MateSolveConfig{
+				FEN:      fen,
+				MaxNodes: *maxNodes,
 			}); err != nil {
 				log.Fatal(err)
 			}
@@ -420,21 +491,39 @@ func main() {
 
 	r.HandleFunc("/", HomeHandler).Methods("GET")
 
-	// WebSocket Route
-	r.HandleFunc("/ws", api_handlers.HandleConnections)
+	games := api_handlers.NewGameRegistry(game_config.Get().WebGames)
+	analyzer := api_handlers.NewAnalyzer(game_config.Get().Analysis)
+
+	// WebSocket Routes
+	r.HandleFunc("/ws/{id}", games.HandleConnections)
+	r.HandleFunc("/ws-spectate/{id}", games.HandleSpectatorConnections)
+	r.HandleFunc("/ws-analyze", analyzer.HandleAnalysisConnections)
 
 	// API Routes
 	gameApiRouter := r.PathPrefix("/api/game").Subrouter()
 	gameApiRouter.
 		Path("").
+		Methods("POST").
+		HandlerFunc(games.PostGameCommandHandler)
+
+	gameApiRouter.
+		Path("/{id}").
 		Methods("GET").
-		HandlerFunc(api_handlers.GetGameStateHandler)
+		HandlerFunc(games.GetGameStateHandler)
 
 	gameApiRouter.
-		Path("").
+		Path("/{id}/history").
+		Methods("GET").
+		HandlerFunc(games.GetGameHistoryHandler)
+
+	gameApiRouter.
+		Path("/{id}").
+		Methods("POST").
+		HandlerFunc(games.PostGameCommandHandler)
+
+	r.Path("/api/analyze").
 		Methods("POST").
-		Queries("command", "{command}").
-		HandlerFunc(api_handlers.PostGameCommandHandler)
+		HandlerFunc(analyzer.PostAnalyzeHandler)
 
 	// Set Static Files (MUST be below routes otherwise it'll conflict)
 	r.PathPrefix("/").Handler(http.FileServer(http.Dir("./web/")))
@@ -480,3 +569,35 @@ func parseOptionalPlySet(s string) (map[int]bool, error) {
 	}
 	return out, nil
 }
+
+// exitForced is the exit status when a second SIGTERM or SIGINT cuts a
+// graceful shutdown short. A graceful shutdown exits 0, and run_bot.sh treats
+// any other status (1 from log.Fatal, 2 from a panic) as a crash.
+const exitForced = 3
+
+// runLichess runs a Lichess bot until it stops. The first SIGTERM or SIGINT
+// shuts it down gracefully (see server.Lichess.Shutdown); a second one quits
+// at once.
+func runLichess(s server.Server) {
+	bot := s.(*server.Lichess)
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Printf("%s: shutting down after the current game — signal again to quit now", sig)
+		bot.Shutdown()
+		<-signals
+		log.Println("quitting without waiting for the game")
+		os.Exit(exitForced)
+	}()
+	bot.Run()
+}
+
+// lichessURL returns the Lichess host to play on; LICHESS_URL overrides it,
+// e.g. to point the bot at a local fake server.
+func lichessURL() string {
+	if u := os.Getenv("LICHESS_URL"); u != "" {
+		return u
+	}
+	return server.LichessURL
+}
@@ -0,0 +1,210 @@
+package api_handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Vadman97/GolangChessAI/pkg/api"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/analysis"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	"github.com/gorilla/websocket"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errAnalysisQueueFull = errors.New("too many analyses waiting, please try again later")
+
+// Analyzer runs the position analyses clients ask for. Analyses wait their
+// turn in a queue of limited length and only a few search at once, so that
+// they cannot starve the games of CPU.
+type Analyzer struct {
+	cfg *game_config.Analysis
+	// tickets holds a token for each analysis running or waiting, and
+	// running one for each analysis running.
+	tickets chan struct{}
+	running chan struct{}
+}
+
+// NewAnalyzer returns an Analyzer limited by cfg.
+func NewAnalyzer(cfg *game_config.Analysis) *Analyzer {
+	maxConcurrent, maxQueued := cfg.MaxConcurrent, cfg.MaxQueued
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	if maxQueued < 0 {
+		maxQueued = 0
+	}
+	return &Analyzer{
+		cfg:     cfg,
+		tickets: make(chan struct{}, maxConcurrent+maxQueued),
+		running: make(chan struct{}, maxConcurrent),
+	}
+}
+
+// analyze runs the analysis of position once it is its turn. It returns
+// errAnalysisQueueFull when too many analyses are waiting, or ctx's error if
+// ctx is cancelled before the analysis starts.
+func (a *Analyzer) analyze(ctx context.Context, cfg analysis.AnalyzeConfig, onInfo func(api.AnalysisInfoJSON)) (*api.AnalysisJSON, error) {
+	select {
+	case a.tickets <- struct{}{}:
+	default:
+		return nil, errAnalysisQueueFull
+	}
+	defer func() { <-a.tickets }()
+	select {
+	case a.running <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-a.running }()
+	return analysis.Analyze(ctx, cfg, onInfo), nil
+}
+
+// parseAnalysisRequest reads the parameters of an analysis: fen (the standard
+// starting position if omitted), algorithm and the limits depth, thinkTimeMs,
+// nodes and multiPV, the number of lines to show. The search stops at the
+// first limit it reaches. It searches for the configured default time
+// unless the request sets a limit, and never longer than the maximum.
+func (a *Analyzer) parseAnalysisRequest(req *http.Request) (analysis.AnalyzeConfig, error) {
+	cfg := analysis.AnalyzeConfig{Algorithm: game_config.Get().Algorithm, MultiPV: 1}
+
+	fen := strings.TrimSpace(req.FormValue("fen"))
+	if fen == "" {
+		fen = startFEN
+	}
+	position, err := analysis.ParseFEN(fen)
+	if err != nil {
+		return cfg, err
+	}
+	if err := validatePosition(position); err != nil {
+		return cfg, err
+	}
+	cfg.Position = position
+
+	if name := req.FormValue("algorithm"); name != "" {
+		if err := checkAlgorithm(name); err != nil {
+			return cfg, err
+		}
+		cfg.Algorithm = name
+	}
+
+	var thinkTimeMs int
+	for _, option := range []struct {
+		name     string
+		min, max int
+		value    *int
+	}{
+		{"thinkTimeMs", 1, a.cfg.MaxThinkTimeMs, &thinkTimeMs},
+		{"depth", 1, maxSearchDepth, &cfg.Depth},
+		{"multiPV", 1, a.cfg.MaxMultiPV, &cfg.MultiPV},
+	} {
+		v := req.FormValue(option.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < option.min || n > option.max {
+			return cfg, fmt.Errorf("invalid %s %q, expected %d to %d", option.name, v, option.min, option.max)
+		}
+		*option.value = n
+	}
+	if v := req.FormValue("nodes"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || n == 0 {
+			return cfg, fmt.Errorf("invalid nodes %q, expected a positive number", v)
+		}
+		cfg.Nodes = n
+	}
+
+	switch {
+	case thinkTimeMs != 0:
+		cfg.ThinkTime = time.Duration(thinkTimeMs) * time.Millisecond
+	case cfg.Depth != 0 || cfg.Nodes != 0:
+		cfg.ThinkTime = time.Duration(a.cfg.MaxThinkTimeMs) * time.Millisecond
+	default:
+		cfg.ThinkTime = time.Duration(a.cfg.DefaultThinkTimeMs) * time.Millisecond
+	}
+	return cfg, nil
+}
+
+// PostAnalyzeHandler analyses the position parseAnalysisRequest reads from
+// POST /api/analyze and responds with the analysis.
+func (a *Analyzer) PostAnalyzeHandler(w http.ResponseWriter, req *http.Request) {
+	cfg, err := a.parseAnalysisRequest(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := a.analyze(req.Context(), cfg, nil)
+	if err == errAnalysisQueueFull {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	} else if err != nil {
+		log.Printf("Analysis request abandoned - %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Unable to send analysis - %v", err)
+	}
+}
+
+// HandleAnalysisConnections streams an analysis at /ws-analyze, which takes
+// the parameters of POST /api/analyze in its query. The client is sent an
+// AnalysisInfo message after each depth of the search, then the
+// AnalysisResult, and the analysis stops if it disconnects.
+func (a *Analyzer) HandleAnalysisConnections(w http.ResponseWriter, req *http.Request) {
+	ws, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade error - %v", err)
+		return
+	}
+
+	defer ws.Close()
+
+	cfg, err := a.parseAnalysisRequest(req)
+	if err != nil {
+		rejectConnection(ws, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		// The client only ever closes the connection
+		for {
+			if _, _, err := ws.NextReader(); err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	// The search calls onInfo on this goroutine, so writes never overlap
+	send := func(msg api.ChessMessage) {
+		ws.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if err := ws.WriteJSON(msg); err != nil {
+			log.Printf("Unable to send analysis - %v", err)
+			cancel()
+		}
+	}
+	result, err := a.analyze(ctx, cfg, func(info api.AnalysisInfoJSON) {
+		send(api.CreateChessMessage(api.AnalysisInfo, info))
+	})
+	if err == errAnalysisQueueFull {
+		rejectConnection(ws, err.Error())
+		return
+	} else if err != nil {
+		return
+	}
+	send(api.CreateChessMessage(api.AnalysisResult, result))
+	ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(time.Second))
+}
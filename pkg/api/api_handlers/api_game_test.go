@@ -1,18 +1,36 @@
 package api_handlers
 
 import (
+	"encoding/json"
+	"github.com/Vadman97/GolangChessAI/pkg/api"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/analysis"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/config"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/game"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/piece"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/player"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	os.Exit(config.RunWithTempLogs(m))
+}
+
 func TestGetGameState(t *testing.T) {
 	testBoard := board.Board{}
 	testBoard.ResetDefault()
@@ -33,22 +51,15 @@ func TestGetGameState(t *testing.T) {
 			color.Black: 2,
 		},
 		MovesPlayed:  8,
+		FullMove:     5,
 		PreviousMove: nil,
 		GameStatus:   game.Active,
 	}
-	setGame(testGame)
-
-	req, err := http.NewRequest("GET", "/api/game", nil)
-
-	if err != nil {
-		t.Fatal(err)
-	}
+	registry := NewGameRegistry(game_config.DefaultWebGames())
+	hosted, err := registry.create(func() *game.Game { return testGame })
+	assert.NoError(t, err)
 
-	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(GetGameStateHandler)
-
-	// Perform HTTP Request
-	handler.ServeHTTP(rr, req)
+	rr := getGameState(t, registry, hosted.id)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 	log.Print(rr.Body.String())
@@ -215,3 +226,600 @@ func TestGetGameState(t *testing.T) {
 
 	assert.JSONEq(t, expectedBody, rr.Body.String())
 }
+
+func getGameState(t *testing.T, registry *GameRegistry, id string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("GET", "/api/game/"+id, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(registry.GetGameStateHandler).ServeHTTP(rr, req)
+	return rr
+}
+
+func getGameHistory(t *testing.T, registry *GameRegistry, id string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("GET", "/api/game/"+id+"/history", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(registry.GetGameHistoryHandler).ServeHTTP(rr, req)
+	return rr
+}
+
+func defaultGame(t *testing.T) func() *game.Game {
+	settings, err := parseGameSettings(httptest.NewRequest("POST", "/api/game", nil))
+	assert.NoError(t, err)
+	return func() *game.Game { return newHumanVsAIGame(settings) }
+}
+
+func startGame(t *testing.T, registry *GameRegistry, options ...string) *httptest.ResponseRecorder {
+	query := url.Values{"command": {"start"}}
+	for i := 0; i+1 < len(options); i += 2 {
+		query.Set(options[i], options[i+1])
+	}
+	req, err := http.NewRequest("POST", "/api/game?"+query.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(registry.PostGameCommandHandler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestGamesAddressedByID(t *testing.T) {
+	registry := NewGameRegistry(&game_config.WebGames{MaxGames: 2, IdleTimeoutSec: 180})
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		rr := startGame(t, registry)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var created struct {
+			Success bool   `json:"success"`
+			GameID  string `json:"gameId"`
+		}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+		assert.True(t, created.Success)
+		assert.Len(t, created.GameID, 8)
+		ids = append(ids, created.GameID)
+	}
+	assert.NotEqual(t, ids[0], ids[1])
+
+	rr := startGame(t, registry)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code, "the cap is two games")
+	assert.Contains(t, rr.Body.String(), errRegistryFull.Error())
+
+	for _, id := range ids {
+		assert.Equal(t, http.StatusOK, getGameState(t, registry, id).Code)
+	}
+	assert.Equal(t, http.StatusNotFound, getGameState(t, registry, "missing").Code)
+}
+
+func TestIdleGameEnds(t *testing.T) {
+	registry := NewGameRegistry(&game_config.WebGames{MaxGames: 1})
+	registry.idleTimeout = 50 * time.Millisecond
+	hosted, err := registry.create(defaultGame(t))
+	assert.NoError(t, err)
+
+	select {
+	case <-hosted.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the idle game did not end")
+	}
+	assert.Nil(t, registry.get(hosted.id))
+	assert.Equal(t, game.Aborted, hosted.game.GameStatus)
+	_, err = registry.create(defaultGame(t))
+	assert.NoError(t, err, "the ended game should free its slot")
+}
+
+// TestPlayerMovesAreValidated sends bad moves for a pawn about to promote:
+// each is refused with an error and a resync, and the board is untouched
+// until a legal move arrives.
+func TestPlayerMovesAreValidated(t *testing.T) {
+	g := game.NewGame(player.NewHumanPlayer(color.White), ai.NewAIPlayer(color.Black, &ai.Random{}))
+	defer g.Stop()
+	parsed, err := analysis.ParseFEN("8/P6k/8/8/8/8/8/K7 w - - 0 1")
+	assert.NoError(t, err)
+	g.CurrentBoard = parsed.Board
+
+	// Engine columns run from the h-file, so a7 is (6, 7).
+	kingMove := api.MoveJSON{Start: [2]uint8{6, 0}, End: [2]uint8{5, 0}}
+	promotion := api.MoveJSON{Start: [2]uint8{6, 7}, End: [2]uint8{7, 7}}
+	promoteTo := func(name string) api.MoveJSON {
+		m := promotion
+		m.PromotionPiece = api.PieceJSON{PieceType: name, Color: "White"}
+		return m
+	}
+
+	assert.NoError(t, HandlePlayerMove(g, kingMove))
+	assert.EqualError(t, HandlePlayerMove(g, kingMove), "a move is already waiting to be played")
+	played := make(chan bool)
+	go func() { played <- g.PlayTurn() }()
+	assert.Equal(t, "h7h6 is not a legal move", nextRejection(t, g), "the opponent's king")
+
+	assert.NoError(t, HandlePlayerMove(g, promotion))
+	assert.Equal(t, "a7a8 is not a legal move", nextRejection(t, g), "no promotion piece")
+	assert.EqualError(t, HandlePlayerMove(g, promoteTo("B")), `cannot promote to "B"`)
+	assert.EqualError(t, HandlePlayerMove(g, promoteTo("K")), `cannot promote to "K"`)
+	assert.EqualError(t, HandlePlayerMove(g, api.MoveJSON{Start: [2]uint8{8, 7}, End: [2]uint8{7, 7}}), "square [8 7] is off the board")
+	assert.Equal(t, uint(0), g.MovesPlayed)
+
+	assert.NoError(t, HandlePlayerMove(g, promoteTo("R")))
+	select {
+	case active := <-played:
+		assert.True(t, active)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the legal move was not played")
+	}
+	promoted := g.CurrentBoard.GetPiece(location.NewLocation(7, 7))
+	assert.Equal(t, piece.RookType, promoted.GetPieceType())
+	assert.Equal(t, color.White, promoted.GetColor())
+}
+
+// nextRejection returns the error the game sent for a refused move, checking
+// that the game state follows it.
+func nextRejection(t *testing.T, g *game.Game) string {
+	t.Helper()
+	var msgs []api.ChessMessage
+	for len(msgs) < 2 {
+		select {
+		case msg := <-g.SocketBroadcast:
+			msgs = append(msgs, msg)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no rejection was sent")
+		}
+	}
+	assert.Equal(t, api.InvalidMove, msgs[0].Type)
+	assert.Equal(t, api.GameState, msgs[1].Type)
+	var rejection api.InvalidMoveJSON
+	assert.NoError(t, json.Unmarshal([]byte(msgs[0].Data), &rejection))
+	return rejection.Error
+}
+
+func TestStartOptions(t *testing.T) {
+	registry := NewGameRegistry(&game_config.WebGames{MaxGames: 10, IdleTimeoutSec: 180})
+
+	rr := startGame(t, registry,
+		"fen", "8/P6k/8/8/8/8/8/K7 b - - 0 1",
+		"color", "White",
+		"algorithm", ai.AlgorithmRandom,
+		"thinkTimeMs", "500",
+		"depth", "6",
+		"skill", "5",
+		"clockSec", "300",
+		"incrementSec", "2",
+		"delaySec", "1",
+		"delayType", "Bronstein")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var created struct {
+		GameID   string               `json:"gameId"`
+		Settings api.GameSettingsJSON `json:"settings"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.Equal(t, api.GameSettingsJSON{
+		FEN:          "8/P6k/8/8/8/8/8/K7 b - - 0 1",
+		HumanColor:   "White",
+		Algorithm:    ai.AlgorithmRandom,
+		ThinkTimeMs:  500,
+		Depth:        3, // skill 5 searches at most 3 plies
+		Skill:        5,
+		ClockSec:     300,
+		IncrementSec: 2,
+		DelaySec:     1,
+		DelayType:    "bronstein",
+	}, created.Settings)
+
+	hosted := registry.get(created.GameID)
+	assert.Equal(t, color.Black, hosted.game.CurrentTurnColor)
+	aiPlayer := hosted.game.Players[color.Black].(*ai.AIPlayer)
+	assert.Equal(t, ai.AlgorithmRandom, aiPlayer.Algorithm.GetName())
+	assert.Equal(t, 500*time.Millisecond, aiPlayer.MaxThinkTime)
+	var state api.GameStateJSON
+	assert.NoError(t, json.Unmarshal(getGameState(t, registry, created.GameID).Body.Bytes(), &state))
+	assert.Equal(t, "White", state.HumanColor)
+	assert.Equal(t, map[string]int64{"White": 300000, "Black": 300000}, state.Clocks)
+	assert.Equal(t, game.NewClock(5*time.Minute, 2*time.Second, time.Second, game.BronsteinDelay), hosted.game.Clocks[color.White])
+	assert.Equal(t, &api.PieceJSON{PieceType: "P", Color: "White"}, state.CurrentBoard[6][7])
+
+	rr = startGame(t, registry)
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.Equal(t, startFEN, created.Settings.FEN)
+	assert.Equal(t, game_config.Get().Algorithm, created.Settings.Algorithm)
+	assert.Equal(t, ai.MaxSkillLevel, created.Settings.Skill)
+
+	for _, bad := range [][]string{
+		{"fen", "not a fen"},
+		{"fen", "8/8/8/8/8/8/8/K6K w - - 0 1"},
+		{"fen", "k6R/8/8/8/8/8/8/K7 w - - 0 1"},
+		{"fen", "k7/8/1Q6/8/8/8/8/K7 b - - 0 1"},
+		{"color", "green"},
+		{"algorithm", "Stockfish"},
+		{"thinkTimeMs", "3600000"},
+		{"depth", "0"},
+		{"skill", "21"},
+		{"clockSec", "-1"},
+		{"delayType", "hourglass"},
+	} {
+		rr := startGame(t, registry, bad...)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "%v", bad)
+	}
+}
+
+// nextMessage returns the next message of type msgType the game sent,
+// skipping others.
+func nextMessage(t *testing.T, g *game.Game, msgType string) api.ChessMessage {
+	t.Helper()
+	for {
+		select {
+		case msg := <-g.SocketBroadcast:
+			if msg.Type == msgType {
+				return msg
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %s message was sent", msgType)
+		}
+	}
+}
+
+// TestPlayerActions takes back a move pair, has draw offers declined and
+// accepted, and resigns.
+func TestPlayerActions(t *testing.T) {
+	aiPlayer := ai.NewAIPlayer(color.Black, &ai.Random{})
+	aiPlayer.Opening = ai.OpeningNone
+	g := game.NewGame(player.NewHumanPlayer(color.White), aiPlayer)
+	defer g.Stop()
+	turns := make(chan bool, 1)
+	startTurn := func() { go func() { turns <- g.PlayTurn() }() }
+	finishTurn := func() bool {
+		t.Helper()
+		select {
+		case active := <-turns:
+			return active
+		case <-time.After(5 * time.Second):
+			t.Fatal("the turn was not played")
+		}
+		return false
+	}
+
+	assert.NoError(t, g.RequestAction(game.Takeback))
+	assert.EqualError(t, g.RequestAction(game.Resign), "an action is already waiting to be handled")
+	startTurn()
+	var rejection api.InvalidActionJSON
+	assert.NoError(t, json.Unmarshal([]byte(nextMessage(t, g, api.InvalidAction).Data), &rejection))
+	assert.Equal(t, "there is no move to take back", rejection.Error)
+
+	// e2e4 and the AI's reply, then take both back
+	assert.NoError(t, HandlePlayerMove(g, api.MoveJSON{Start: [2]uint8{1, 3}, End: [2]uint8{3, 3}}))
+	assert.True(t, finishTurn())
+	startTurn()
+	assert.True(t, finishTurn())
+	assert.Equal(t, uint(2), g.MovesPlayed)
+	assert.NoError(t, g.RequestAction(game.Takeback))
+	startTurn()
+	nextMessage(t, g, api.AvailablePlayerMoves)
+	assert.Equal(t, uint(0), g.MovesPlayed)
+	assert.Equal(t, color.White, g.CurrentTurnColor)
+	assert.Nil(t, g.PreviousMove)
+	pawn := g.CurrentBoard.GetPiece(location.NewLocation(1, 3))
+	assert.NotNil(t, pawn)
+	assert.Equal(t, piece.PawnType, pawn.GetPieceType())
+	assert.Nil(t, g.CurrentBoard.GetPiece(location.NewLocation(3, 3)))
+
+	offerDraw := func() bool {
+		t.Helper()
+		assert.NoError(t, g.RequestAction(game.OfferDraw))
+		var answer api.DrawOfferAnswerJSON
+		assert.NoError(t, json.Unmarshal([]byte(nextMessage(t, g, api.DrawOfferAnswer).Data), &answer))
+		return answer.Accepted
+	}
+	assert.False(t, offerDraw(), "the AI has no score after a takeback")
+	aiPlayer.LastScore, aiPlayer.HasLastScore = 300, true
+	assert.False(t, offerDraw(), "the AI is winning")
+	aiPlayer.LastScore = -50
+	assert.True(t, offerDraw(), "the AI is losing")
+	assert.False(t, finishTurn())
+	assert.Equal(t, game.DrawAgreed, g.GameStatus)
+	assert.True(t, g.GetGameOutcome().Tie)
+	assert.EqualError(t, g.RequestAction(game.Resign), "the game is over")
+
+	resigning := game.NewGame(player.NewHumanPlayer(color.White), ai.NewAIPlayer(color.Black, &ai.Random{}))
+	assert.NoError(t, resigning.RequestAction(game.Resign))
+	assert.False(t, resigning.PlayTurn())
+	assert.Equal(t, game.WhiteResigned, resigning.GameStatus)
+	assert.True(t, resigning.GetGameOutcome().Win[color.Black])
+}
+
+func postCommand(t *testing.T, registry *GameRegistry, id, command string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("POST", "/api/game/"+id+"?command="+command, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "" {
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(registry.PostGameCommandHandler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRestartAndConcede(t *testing.T) {
+	registry := NewGameRegistry(&game_config.WebGames{MaxGames: 1, IdleTimeoutSec: 180})
+	hosted, err := registry.create(defaultGame(t))
+	assert.NoError(t, err)
+	old := hosted.current()
+
+	rr := postCommand(t, registry, hosted.id, api.Restart)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), hosted.id)
+	assert.True(t, old != hosted.current(), "restart replaces the game")
+	assert.Equal(t, game.Aborted, old.GameStatus)
+	assert.Equal(t, game.Active, hosted.current().GameStatus)
+	assert.True(t, hosted == registry.get(hosted.id), "the game keeps its ID")
+
+	assert.Equal(t, http.StatusOK, postCommand(t, registry, hosted.id, api.Concede).Code)
+	rr = postCommand(t, registry, hosted.id, api.Concede)
+	assert.Equal(t, http.StatusConflict, rr.Code, "the first concession is still waiting for the loop")
+
+	assert.Equal(t, http.StatusBadRequest, postCommand(t, registry, "", api.Restart).Code)
+	assert.Equal(t, http.StatusBadRequest, postCommand(t, registry, hosted.id, "dance").Code)
+	assert.Equal(t, http.StatusNotFound, postCommand(t, registry, "missing", api.Concede).Code)
+}
+
+func TestGameHistory(t *testing.T) {
+	registry := NewGameRegistry(game_config.DefaultWebGames())
+	rr := startGame(t, registry, "color", "white", "fen", "4k3/8/8/8/8/8/4P3/4K3 w - - 0 40")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var created struct {
+		GameID string `json:"gameId"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	g := registry.get(created.GameID).current()
+	defer g.Stop()
+
+	g.PlayTurnMove(&location.Move{Start: location.NewLocation(1, 3), End: location.NewLocation(3, 3)})
+
+	rr = getGameHistory(t, registry, created.GameID)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var history api.GameHistoryJSON
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &history))
+	assert.Equal(t, "4k3/8/8/8/8/8/4P3/4K3 w - - 0 40", history.StartFEN)
+	if assert.Len(t, history.Moves, 1) {
+		assert.Equal(t, "e4", history.Moves[0].SAN)
+		assert.Equal(t, "4k3/8/8/8/4P3/8/8/4K3 b - e3 0 40", history.Moves[0].FEN)
+	}
+	assert.Equal(t, http.StatusNotFound, getGameHistory(t, registry, "missing").Code)
+}
+
+func dialGame(t *testing.T, server *httptest.Server, id, token, version string) *websocket.Conn {
+	query := url.Values{"token": {token}, "version": {version}}
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/"+id+"?"+query.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ws
+}
+
+// readMessage reads from ws until a message of msgType arrives.
+func readMessage(t *testing.T, ws *websocket.Conn, msgType string) api.ChessMessage {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg api.ChessMessage
+		if err := ws.ReadJSON(&msg); err != nil {
+			t.Fatalf("no %s message: %v", msgType, err)
+		}
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+// TestReconnectAndResume checks the session token and protocol version,
+// resyncs a player who connects again and forfeits one who does not.
+func TestReconnectAndResume(t *testing.T) {
+	registry := NewGameRegistry(game_config.DefaultWebGames())
+	router := mux.NewRouter()
+	router.HandleFunc("/ws/{id}", registry.HandleConnections)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	rr := startGame(t, registry, "color", "white")
+	var created struct {
+		GameID          string `json:"gameId"`
+		Token           string `json:"token"`
+		ProtocolVersion int    `json:"protocolVersion"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.Token)
+	assert.Equal(t, api.ProtocolVersion, created.ProtocolVersion)
+	version := strconv.Itoa(api.ProtocolVersion)
+
+	var rejection api.ConnectionRejectedJSON
+	old := dialGame(t, server, created.GameID, created.Token, "1")
+	assert.NoError(t, json.Unmarshal([]byte(readMessage(t, old, api.ConnectionRejected).Data), &rejection))
+	assert.Contains(t, rejection.Error, "out of date")
+	old.Close()
+	wrongToken := dialGame(t, server, created.GameID, "guess", version)
+	assert.NoError(t, json.Unmarshal([]byte(readMessage(t, wrongToken, api.ConnectionRejected).Data), &rejection))
+	assert.Equal(t, "invalid session token for this game", rejection.Error)
+	wrongToken.Close()
+
+	first := dialGame(t, server, created.GameID, created.Token, version)
+	readMessage(t, first, api.AvailablePlayerMoves)
+
+	// A refresh connects again before the old connection is noticed closing
+	second := dialGame(t, server, created.GameID, created.Token, version)
+	defer second.Close()
+	var resync api.ResyncJSON
+	assert.NoError(t, json.Unmarshal([]byte(readMessage(t, second, api.Resync).Data), &resync))
+	assert.Equal(t, api.ProtocolVersion, resync.ProtocolVersion)
+	assert.Equal(t, "White", resync.State.HumanColor)
+	assert.Equal(t, "Active", resync.Status.GameStatus)
+	if assert.NotNil(t, resync.State.History, "a resync carries the move list") {
+		assert.Empty(t, resync.State.History.Moves)
+	}
+	if assert.NotNil(t, resync.AvailableMoves, "it is the player's turn") {
+		moves := 0
+		for _, pieceMoves := range resync.AvailableMoves.AvailableMoves {
+			moves += len(pieceMoves)
+		}
+		assert.Equal(t, 20, moves)
+	}
+	first.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := first.ReadMessage()
+	assert.Error(t, err, "the old connection is closed")
+
+	hosted := registry.get(created.GameID)
+	registry.reconnectGrace = 50 * time.Millisecond
+	second.Close()
+	g := hosted.current()
+	status := func() string { return g.Snapshot().Status.GameStatus }
+	for deadline := time.Now().Add(5 * time.Second); status() == game.StatusStrings[game.Active] && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, game.StatusStrings[game.WhiteResigned], status(), "the player forfeits after the grace period")
+	assert.NotNil(t, registry.get(created.GameID), "the game is kept to show the result")
+}
+
+func dialSpectator(t *testing.T, server *httptest.Server, id string) *websocket.Conn {
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws-spectate/"+id, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ws
+}
+
+// TestSpectators checks that spectators see the game's moves and the AI's
+// search, that late joiners are sent the current position, and that
+// spectators are told when the game ends.
+func TestSpectators(t *testing.T) {
+	registry := NewGameRegistry(game_config.DefaultWebGames())
+	router := mux.NewRouter()
+	router.HandleFunc("/ws/{id}", registry.HandleConnections)
+	router.HandleFunc("/ws-spectate/{id}", registry.HandleSpectatorConnections)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	unknown := dialSpectator(t, server, "nosuchgame")
+	readMessage(t, unknown, api.GameNotAvailable)
+	unknown.Close()
+
+	// The AI plays White from a position out of its opening book
+	rr := startGame(t, registry, "color", "black", "thinkTimeMs", "200", "fen", "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+	var created struct {
+		GameID string `json:"gameId"`
+		Token  string `json:"token"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+
+	early := dialSpectator(t, server, created.GameID)
+	defer early.Close()
+	player := dialGame(t, server, created.GameID, created.Token, strconv.Itoa(api.ProtocolVersion))
+	defer player.Close()
+
+	readMessage(t, early, api.GameState)
+	var info api.SearchInfoJSON
+	assert.NoError(t, json.Unmarshal([]byte(readMessage(t, early, api.SearchInfo).Data), &info))
+	assert.Equal(t, "White", info.Color)
+	assert.True(t, info.Depth > 0)
+	if assert.NotEmpty(t, info.PV) {
+		assert.Equal(t, len(info.PV), len(info.PVSAN))
+	}
+	var move api.MoveJSON
+	assert.NoError(t, json.Unmarshal([]byte(readMessage(t, early, api.MovePlayed).Data), &move))
+	assert.Equal(t, "White", move.Piece.Color)
+	readMessage(t, player, api.AvailablePlayerMoves)
+
+	late := dialSpectator(t, server, created.GameID)
+	defer late.Close()
+	var state api.GameStateJSON
+	assert.NoError(t, json.Unmarshal([]byte(readMessage(t, late, api.GameState).Data), &state))
+	assert.Equal(t, uint(1), state.MovesPlayed)
+	assert.Equal(t, "Black", state.HumanColor)
+	readMessage(t, late, api.GameStatus)
+
+	hosted := registry.get(created.GameID)
+	player.Close()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		hosted.mu.Lock()
+		disconnected := hosted.client == nil
+		hosted.mu.Unlock()
+		if disconnected {
+			break
+		}
+	}
+	hosted.expire()
+	readMessage(t, late, api.GameNotAvailable)
+	late.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := late.ReadMessage()
+	assert.Error(t, err, "spectators are disconnected once the game has ended")
+}
+
+func postAnalyze(t *testing.T, analyzer *Analyzer, options ...string) *httptest.ResponseRecorder {
+	query := url.Values{}
+	for i := 0; i+1 < len(options); i += 2 {
+		query.Set(options[i], options[i+1])
+	}
+	req, err := http.NewRequest("POST", "/api/analyze?"+query.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(analyzer.PostAnalyzeHandler).ServeHTTP(rr, req)
+	return rr
+}
+
+// TestAnalyze checks the analysis endpoint, its streaming variant and that
+// requests beyond the queue are refused.
+func TestAnalyze(t *testing.T) {
+	const backRankMate = "6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1"
+	analyzer := NewAnalyzer(&game_config.Analysis{MaxConcurrent: 1, DefaultThinkTimeMs: 1000, MaxThinkTimeMs: 5000, MaxMultiPV: 3})
+
+	rr := postAnalyze(t, analyzer, "fen", backRankMate, "depth", "3", "multiPV", "2")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var result api.AnalysisJSON
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, "d1d8", result.BestMove)
+	assert.Equal(t, "Rd8#", result.BestMoveSAN)
+	assert.Equal(t, game_config.Get().Algorithm, result.Algorithm)
+	if assert.Len(t, result.Lines, 2) {
+		if assert.NotNil(t, result.Lines[0].ScoreMate) {
+			assert.Equal(t, 1, *result.Lines[0].ScoreMate)
+		}
+		assert.Equal(t, []string{"Rd8#"}, result.Lines[0].PVSAN)
+		assert.NotNil(t, result.Lines[1].ScoreCP)
+	}
+
+	for _, invalid := range [][]string{
+		{"fen", "8/8/8/8/8/8/8/8 w - - 0 1"},
+		{"algorithm", "Oracle"},
+		{"multiPV", "4"},
+		{"thinkTimeMs", "6000"},
+		{"nodes", "0"},
+	} {
+		rr := postAnalyze(t, analyzer, invalid...)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, invalid)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(analyzer.HandleAnalysisConnections))
+	defer server.Close()
+	query := url.Values{"fen": {backRankMate}, "depth": {"3"}}
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"?"+query.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info api.AnalysisInfoJSON
+	assert.NoError(t, json.Unmarshal([]byte(readMessage(t, ws, api.AnalysisInfo).Data), &info))
+	assert.Equal(t, "d1d8", info.PV[0])
+	assert.NoError(t, json.Unmarshal([]byte(readMessage(t, ws, api.AnalysisResult).Data), &result))
+	assert.Equal(t, "d1d8", result.BestMove)
+	ws.Close()
+
+	// An analysis is running and none may wait
+	analyzer.tickets <- struct{}{}
+	rr = postAnalyze(t, analyzer, "fen", backRankMate)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	<-analyzer.tickets
+}
@@ -9,120 +9,161 @@ import (
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/player"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
 	"github.com/bitly/go-simplejson"
+	"github.com/gorilla/mux"
 	"log"
-	"math/rand"
 	"net/http"
 	"strings"
 	"time"
 )
 
-var g *game.Game
-
-func getGame() *game.Game {
-	return g
-}
+func (r *GameRegistry) GetGameStateHandler(w http.ResponseWriter, req *http.Request) {
+	hosted := r.get(mux.Vars(req)["id"])
+	if hosted == nil {
+		writeError(w, http.StatusNotFound, "No Game is Available")
+		return
+	}
 
-func setGame(gameToSet *game.Game) {
-	g = gameToSet
-}
+	gameJSON := hosted.current().Snapshot().State
 
-func GetGameStateHandler(w http.ResponseWriter, r *http.Request) {
-	if g == nil {
-		errorResponse := simplejson.New()
-		errorResponse.Set("error", "No Game is Available")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 
-		payload, err := errorResponse.MarshalJSON()
-		if err != nil {
-			log.Println(err)
-		}
+	if err := json.NewEncoder(w).Encode(gameJSON); err != nil {
+		panic(err)
+	}
+}
 
-		w.WriteHeader(http.StatusBadRequest)
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(payload)
+// GetGameHistoryHandler responds with the game's record: its starting FEN and
+// every move played with its SAN, the position after it, the think time, the
+// clock and, for AI moves, the search's score, depth and principal variation.
+func (r *GameRegistry) GetGameHistoryHandler(w http.ResponseWriter, req *http.Request) {
+	hosted := r.get(mux.Vars(req)["id"])
+	if hosted == nil {
+		writeError(w, http.StatusNotFound, "No Game is Available")
 		return
 	}
 
-	gameJSON := g.GetJSON()
-
-	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 
-	if err := json.NewEncoder(w).Encode(gameJSON); err != nil {
+	if err := json.NewEncoder(w).Encode(hosted.current().Snapshot().History); err != nil {
 		panic(err)
 	}
 }
 
-func PostGameCommandHandler(w http.ResponseWriter, r *http.Request) {
-	command := strings.ToLower(r.FormValue("command"))
+// PostGameCommandHandler runs a game command. Start creates a game with the
+// options parseGameSettings reads and responds with its ID and the player's
+// session token, which the client then connects with at /ws/{id}, and its
+// effective settings. Restart and
+// concede act on the game at /api/game/{id}: restart replaces it with a new
+// game with the same settings under the same ID, and concede resigns it for
+// the player.
+func (r *GameRegistry) PostGameCommandHandler(w http.ResponseWriter, req *http.Request) {
+	command := strings.ToLower(req.FormValue("command"))
+	id := mux.Vars(req)["id"]
 
-	if command == api.Start {
-		if g != nil {
-			errorResponse := simplejson.New()
-			errorResponse.Set("error", "Game is currently in progress...")
-
-			payload, err := errorResponse.MarshalJSON()
-			if err != nil {
-				log.Println(err)
-			}
+	successResponse := simplejson.New()
+	successResponse.Set("success", true)
 
-			w.WriteHeader(http.StatusBadRequest)
-			w.Header().Set("Content-Type", "application/json")
-			w.Write(payload)
+	var hosted *webGame
+	if id != "" {
+		if hosted = r.get(id); hosted == nil {
+			writeError(w, http.StatusNotFound, "No Game is Available")
 			return
 		}
+	} else if command == api.Restart || command == api.Concede {
+		writeError(w, http.StatusBadRequest, command+" needs a game ID")
+		return
+	}
 
-		// Coin Flip to determine colors
-		algorithmName := game_config.Get().Algorithm
-		playerIsWhite := rand.Intn(2)
-
-		humanColor := color.White
-		if playerIsWhite == 0 {
-			humanColor = color.Black
+	if hosted == nil && (command == api.Start || command == "") {
+		settings, err := parseGameSettings(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
 		}
-
-		aiColor := color.Black
-		if playerIsWhite == 0 {
-			aiColor = color.White
+		hosted, err := r.create(func() *game.Game { return newHumanVsAIGame(settings) })
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+			return
 		}
+		successResponse.Set("gameId", hosted.id)
+		successResponse.Set("token", hosted.token)
+		successResponse.Set("protocolVersion", api.ProtocolVersion)
+		successResponse.Set("settings", settings.GameSettingsJSON)
 
-		humanPlayer := player.NewHumanPlayer(humanColor)
-		aiPlayer := ai.NewAIPlayer(aiColor, ai.NameToAlgorithm[algorithmName])
-		aiPlayer.MaxSearchDepth = game_config.Get().AIMaxSearchDepth
-		aiPlayer.MaxThinkTime = game_config.Get().AIMaxThinkTimeMs * time.Millisecond
-
-		// Create game and start game loop
-		if playerIsWhite == 0 {
-			g = game.NewGame(aiPlayer, humanPlayer)
-		} else {
-			g = game.NewGame(humanPlayer, aiPlayer)
+		// NOTE: The Server WebSocket Listener waits to receive a client before a game is begun
+	} else if hosted != nil && command == api.Restart {
+		if err := r.restart(hosted); err != nil {
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+			return
 		}
+		successResponse.Set("gameId", hosted.id)
+	} else if hosted != nil && command == api.Concede {
+		if err := hosted.current().RequestAction(game.Resign); err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+	} else {
+		writeError(w, http.StatusBadRequest, "unknown command "+command)
+		return
+	}
 
-		g.MoveLimit = game_config.Get().MovesToPlay
-		g.TimeLimit = game_config.Get().SecondsToPlay * time.Second
-
-		// Reset loop flag for the new game
-		clientMutex.Lock()
-		gameLoopStarted = false
-		clientMutex.Unlock()
+	// Send Success Status
+	payload, err := successResponse.MarshalJSON()
+	if err != nil {
+		log.Println(err)
+	}
 
-		// Initialize WebSocket Handler
-		go HandleMessages(g)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
 
-		// NOTE: The Server WebSocket Listener waits to receive a client before a game is begun
-	} else if command == api.Restart {
-		// TODO (Alex) Implement
+// newHumanVsAIGame creates a game between a human and the AI with settings
+// s. It lowers s.Depth to the depth the skill level allows.
+func newHumanVsAIGame(s *gameSettings) *game.Game {
+	humanPlayer := player.NewHumanPlayer(s.humanColor)
+	// Each game needs its own algorithm instance, as games run concurrently.
+	aiPlayer := ai.NewAIPlayer(s.humanColor^1, ai.NewAlgorithm(s.Algorithm))
+	aiPlayer.MaxSearchDepth = s.Depth
+	aiPlayer.MaxThinkTime = time.Duration(s.ThinkTimeMs) * time.Millisecond
+	aiPlayer.SetSkillLevel(s.Skill)
+	s.Depth = aiPlayer.MaxSearchDepth
+
+	var g *game.Game
+	if s.humanColor == color.White {
+		g = game.NewGame(humanPlayer, aiPlayer)
+	} else {
+		g = game.NewGame(aiPlayer, humanPlayer)
+	}
+	if s.position != nil {
+		// The opening book only fits the standard starting position
+		aiPlayer.Opening = ai.OpeningNone
+		// Copy the board so that a restart starts from the same position
+		g.CurrentBoard = s.position.Board.Copy()
+		g.CurrentTurnColor = s.position.Active
+		g.PreviousMove = s.position.Previous
+		g.FullMove = s.position.FullMove
 	}
 
-	// Send Success Status
-	successResponse := simplejson.New()
-	successResponse.Set("success", true)
+	g.MoveLimit = game_config.Get().MovesToPlay
+	g.TimeLimit = game_config.Get().SecondsToPlay * time.Second
+	g.SetClocks(s.clock)
+	g.AIClaimsDraws = true
+	return g
+}
 
-	payload, err := successResponse.MarshalJSON()
+func writeError(w http.ResponseWriter, status int, message string) {
+	errorResponse := simplejson.New()
+	errorResponse.Set("error", message)
+
+	payload, err := errorResponse.MarshalJSON()
 	if err != nil {
 		log.Println(err)
 	}
 
-	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	w.Write(payload)
 }
@@ -0,0 +1,363 @@
+package api_handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"github.com/Vadman97/GolangChessAI/pkg/api"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
+	"github.com/gorilla/websocket"
+	"log"
+	"runtime"
+	"sync"
+	"time"
+)
+
+var errRegistryFull = errors.New("too many games in progress, please try again later")
+var errGameEnded = errors.New("the game has ended")
+
+// GameRegistry hosts the web games, each addressed by its ID. Every game has
+// its own message loop, player connection and spectators, and ends once the
+// player has not been connected to it for the idle timeout. A player who drops out of a game in
+// progress forfeits unless they reconnect within the reconnect grace period.
+type GameRegistry struct {
+	mu             sync.Mutex
+	games          map[string]*webGame
+	maxGames       int
+	idleTimeout    time.Duration
+	reconnectGrace time.Duration
+}
+
+// webGame is a hosted game and the player connected to it.
+type webGame struct {
+	id string
+	// token is the player's session token, which they connect with.
+	token    string
+	registry *GameRegistry
+	// newGame builds the game again on restart.
+	newGame func() *game.Game
+	// spectators relays the game to read-only connections.
+	spectators *SpectatorHub
+
+	// mu guards the fields below and writes to client.
+	mu   sync.Mutex
+	game *game.Game
+	// done is closed when the game ends or is restarted, stopping its
+	// HandleMessages.
+	done        chan struct{}
+	client      *websocket.Conn
+	loopStarted bool
+	// finished is set once the game loop has returned; a finished game no
+	// longer counts against the cap.
+	finished   bool
+	ended      bool
+	idleTimer  *time.Timer
+	graceTimer *time.Timer
+}
+
+// NewGameRegistry returns an empty registry limited by cfg.
+func NewGameRegistry(cfg *game_config.WebGames) *GameRegistry {
+	maxGames := cfg.MaxGames
+	if maxGames <= 0 {
+		maxGames = runtime.NumCPU() / 2
+		if maxGames < 1 {
+			maxGames = 1
+		}
+	}
+	log.Printf("Hosting at most %d concurrent games", maxGames)
+	return &GameRegistry{
+		games:          make(map[string]*webGame),
+		maxGames:       maxGames,
+		idleTimeout:    time.Duration(cfg.IdleTimeoutSec) * time.Second,
+		reconnectGrace: time.Duration(cfg.ReconnectGraceSec) * time.Second,
+	}
+}
+
+// create registers the game built by newGame under a fresh ID and starts its
+// message loop, or returns errRegistryFull when the cap is reached. The game
+// ends unless a player connects within the idle timeout.
+func (r *GameRegistry) create(newGame func() *game.Game) (*webGame, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.activeLocked() >= r.maxGames {
+		return nil, errRegistryFull
+	}
+	id := newGameID()
+	for r.games[id] != nil {
+		id = newGameID()
+	}
+	w := &webGame{
+		id:         id,
+		token:      randomString(18),
+		registry:   r,
+		newGame:    newGame,
+		spectators: NewSpectatorHub(),
+		done:       make(chan struct{}),
+	}
+	w.game = w.build()
+	go w.spectators.Run()
+	w.mu.Lock()
+	w.idleTimer = time.AfterFunc(r.idleTimeout, w.expire)
+	w.mu.Unlock()
+	r.games[id] = w
+	go HandleMessages(w, w.game, w.done)
+	log.Printf("Created game %s (%d hosted)", id, len(r.games))
+	return w, nil
+}
+
+// activeLocked counts the games still being played. Must be called with the
+// registry mutex held.
+func (r *GameRegistry) activeLocked() int {
+	n := 0
+	for _, w := range r.games {
+		w.mu.Lock()
+		if !w.finished {
+			n++
+		}
+		w.mu.Unlock()
+	}
+	return n
+}
+
+// get returns the game id, or nil if there is none.
+func (r *GameRegistry) get(id string) *webGame {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.games[id]
+}
+
+// restart replaces w's game with a new one built the same way. The player
+// stays connected and the new game starts at once if they are. A finished
+// game only restarts if the cap allows another game.
+func (r *GameRegistry) restart(w *webGame) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w.mu.Lock()
+	ended, finished := w.ended, w.finished
+	w.mu.Unlock()
+	if ended {
+		return errGameEnded
+	}
+	if finished && r.activeLocked() >= r.maxGames {
+		return errRegistryFull
+	}
+
+	g, done := w.build(), make(chan struct{})
+	w.mu.Lock()
+	old, oldDone, oldLooping := w.game, w.done, w.loopStarted
+	w.game, w.done = g, done
+	w.finished = false
+	client := w.client
+	w.loopStarted = client != nil
+	w.mu.Unlock()
+
+	quit(old, oldLooping)
+	close(oldDone)
+	go HandleMessages(w, g, done)
+	if client != nil {
+		go w.runLoop(client)
+	}
+	log.Printf("Restarted game %s", w.id)
+	return nil
+}
+
+func (r *GameRegistry) remove(w *webGame) {
+	r.mu.Lock()
+	delete(r.games, w.id)
+	n := len(r.games)
+	r.mu.Unlock()
+	log.Printf("Removed game %s (%d hosted)", w.id, n)
+}
+
+// newGameID returns a random, URL-safe ID of 8 characters. Spectators may
+// know a game's ID, so playing it takes the session token too.
+func newGameID() string {
+	return randomString(6)
+}
+
+// randomString returns n random bytes encoded URL-safe.
+func randomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// authorized reports whether token is the player's session token.
+func (w *webGame) authorized(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(w.token)) == 1
+}
+
+// connect makes ws the game's player, replacing and closing the player's
+// previous connection, which a page refresh can leave open until its pings
+// time out. It reports false if the game has ended, and whether the game
+// loop was already started.
+func (w *webGame) connect(ws *websocket.Conn) (ok bool, reconnect bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ended {
+		return false, false
+	}
+	if w.client != nil {
+		log.Printf("Replacing the player's previous connection to game %s", w.id)
+		w.client.Close()
+	}
+	w.client = ws
+	if w.idleTimer != nil {
+		w.idleTimer.Stop()
+		w.idleTimer = nil
+	}
+	if w.graceTimer != nil {
+		w.graceTimer.Stop()
+		w.graceTimer = nil
+	}
+	reconnect = w.loopStarted
+	w.loopStarted = true
+	return true, reconnect
+}
+
+// disconnect drops the player if ws is still their connection. The player
+// forfeits a game in progress unless they reconnect within the grace period,
+// and the game ends if nobody reconnects within the idle timeout.
+func (w *webGame) disconnect(ws *websocket.Conn) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.client != ws {
+		return
+	}
+	w.client = nil
+	w.idleTimer = time.AfterFunc(w.registry.idleTimeout, w.expire)
+	if w.loopStarted && !w.finished && w.registry.reconnectGrace > 0 {
+		w.graceTimer = time.AfterFunc(w.registry.reconnectGrace, w.forfeit)
+	}
+}
+
+// forfeit resigns the game for the player if they are still disconnected.
+func (w *webGame) forfeit() {
+	w.mu.Lock()
+	if w.client != nil || w.ended {
+		w.mu.Unlock()
+		return
+	}
+	w.graceTimer = nil
+	g := w.game
+	w.mu.Unlock()
+
+	log.Printf("Player did not reconnect - forfeiting game %s", w.id)
+	if err := g.RequestAction(game.Resign); err != nil {
+		log.Printf("Unable to forfeit game %s - %v", w.id, err)
+	}
+}
+
+// expire ends the game if nobody is connected to it.
+func (w *webGame) expire() {
+	w.mu.Lock()
+	if w.client != nil || w.ended {
+		w.mu.Unlock()
+		return
+	}
+	w.ended = true
+	w.idleTimer = nil
+	g, done, looping := w.game, w.done, w.loopStarted
+	w.mu.Unlock()
+
+	log.Printf("Idle timeout - ending abandoned game %s", w.id)
+	w.registry.remove(w)
+	quit(g, looping)
+	close(done)
+	w.spectators.Broadcast(api.ChessMessage{Type: api.GameNotAvailable})
+	w.spectators.Close()
+}
+
+// quit ends g, which is no longer hosted. A running loop aborts g itself;
+// one that never started leaves g to the caller.
+func quit(g *game.Game, looping bool) {
+	g.Quit()
+	if !looping {
+		g.Stop()
+	}
+}
+
+// build builds a new game whose AI shows spectators its search as it thinks.
+func (w *webGame) build() *game.Game {
+	g := w.newGame()
+	g.Publish()
+	for c, p := range g.Players {
+		if aiPlayer, isAI := p.(*ai.AIPlayer); isAI {
+			c := c
+			aiPlayer.OnSearchInfo = func(info ai.SearchInfo) {
+				// The search runs on the game loop, so the board is the
+				// position being searched
+				w.spectators.Broadcast(api.CreateChessMessage(api.SearchInfo, api.SearchInfoJSON{
+					Color:     color.Names[c],
+					Depth:     info.Depth,
+					Score:     info.Score,
+					PV:        board.LineUCI(info.PV),
+					PVSAN:     g.CurrentBoard.LineSAN(info.PV, c, g.PreviousMove),
+					ElapsedMs: int64(info.Elapsed / time.Millisecond),
+				}))
+			}
+		}
+	}
+	return g
+}
+
+// current returns the game being played, which changes on restart.
+func (w *webGame) current() *game.Game {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.game
+}
+
+// runLoop plays the current game until it is over, has ended or has been
+// restarted.
+func (w *webGame) runLoop(ws *websocket.Conn) {
+	g := w.current()
+	g.ClearCaches(true)
+	runtime.GC()
+	g.Loop(ws)
+	w.mu.Lock()
+	if w.game == g {
+		w.finished = true
+	}
+	w.mu.Unlock()
+}
+
+// send writes msg to the player, if one is connected.
+func (w *webGame) send(msg api.ChessMessage) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.client == nil {
+		return nil
+	}
+	return w.client.WriteJSON(msg)
+}
+
+// rejectMove tells the player why their move was refused and resends the
+// game state, as Game.RejectMove does for illegal moves.
+func (w *webGame) rejectMove(reason string) {
+	for _, msg := range []api.ChessMessage{
+		api.CreateChessMessage(api.InvalidMove, api.InvalidMoveJSON{Error: reason}),
+		api.CreateChessMessage(api.GameState, w.current().Snapshot().State),
+	} {
+		if err := w.send(msg); err != nil {
+			log.Printf("Unable to send to client - %v", err)
+			return
+		}
+	}
+}
+
+// rejectAction tells the player why their action was refused, as
+// Game.RejectAction does.
+func (w *webGame) rejectAction(reason string) {
+	msg := api.CreateChessMessage(api.InvalidAction, api.InvalidActionJSON{Error: reason})
+	if err := w.send(msg); err != nil {
+		log.Printf("Unable to send to client - %v", err)
+	}
+}
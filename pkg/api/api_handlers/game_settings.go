@@ -0,0 +1,172 @@
+package api_handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Vadman97/GolangChessAI/pkg/api"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/analysis"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/piece"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	// maxThinkTime bounds the think time a visitor can ask for, as every AI
+	// search competes for the CPUs with the other games.
+	maxThinkTime   = time.Minute
+	maxSearchDepth = 255
+	// maxClockSec bounds the clock to three hours a side, and maxBonusSec the
+	// increment and the delay to three minutes.
+	maxClockSec = 3 * 60 * 60
+	maxBonusSec = 3 * 60
+)
+
+// gameSettings are the options of a new web game. position is nil for the
+// standard starting position and clock nil for an untimed game.
+type gameSettings struct {
+	api.GameSettingsJSON
+	position   *analysis.ParsedFEN
+	humanColor color.Color
+	clock      *game.Clock
+}
+
+// parseGameSettings reads the optional parameters of the start command: fen,
+// color ("white", "black" or "random"), algorithm, thinkTimeMs, depth, skill
+// (1 to ai.MaxSkillLevel) and the clock's clockSec, incrementSec, delaySec
+// and delayType ("simple" or "bronstein"). Omitted parameters take
+// game_config's values, a random color and full strength. In timed games the
+// AI budgets its clock instead of using thinkTimeMs.
+func parseGameSettings(req *http.Request) (*gameSettings, error) {
+	cfg := game_config.Get()
+	s := &gameSettings{GameSettingsJSON: api.GameSettingsJSON{
+		FEN:          startFEN,
+		Algorithm:    cfg.Algorithm,
+		ThinkTimeMs:  int64(cfg.AIMaxThinkTimeMs),
+		Depth:        cfg.AIMaxSearchDepth,
+		Skill:        ai.MaxSkillLevel,
+		ClockSec:     cfg.Clock.InitialSec,
+		IncrementSec: cfg.Clock.IncrementSec,
+		DelaySec:     cfg.Clock.DelaySec,
+		DelayType:    cfg.Clock.DelayType,
+	}}
+
+	if fen := strings.TrimSpace(req.FormValue("fen")); fen != "" {
+		position, err := analysis.ParseFEN(fen)
+		if err != nil {
+			return nil, err
+		}
+		if err := validatePosition(position); err != nil {
+			return nil, err
+		}
+		s.position, s.FEN = position, position.Normalized
+	}
+
+	switch strings.ToLower(req.FormValue("color")) {
+	case "", "random":
+		s.humanColor = color.Color(rand.Intn(2))
+	case "white":
+		s.humanColor = color.White
+	case "black":
+		s.humanColor = color.Black
+	default:
+		return nil, fmt.Errorf("invalid color %q, expected white, black or random", req.FormValue("color"))
+	}
+	s.HumanColor = color.Names[s.humanColor]
+
+	if name := req.FormValue("algorithm"); name != "" {
+		if err := checkAlgorithm(name); err != nil {
+			return nil, err
+		}
+		s.Algorithm = name
+	}
+
+	var thinkTimeMs int
+	for _, option := range []struct {
+		name     string
+		min, max int
+		value    *int
+	}{
+		{"thinkTimeMs", 1, int(maxThinkTime / time.Millisecond), &thinkTimeMs},
+		{"depth", 1, maxSearchDepth, &s.Depth},
+		{"skill", 1, ai.MaxSkillLevel, &s.Skill},
+		{"clockSec", 0, maxClockSec, &s.ClockSec},
+		{"incrementSec", 0, maxBonusSec, &s.IncrementSec},
+		{"delaySec", 0, maxBonusSec, &s.DelaySec},
+	} {
+		v := req.FormValue(option.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < option.min || n > option.max {
+			return nil, fmt.Errorf("invalid %s %q, expected %d to %d", option.name, v, option.min, option.max)
+		}
+		*option.value = n
+	}
+	if thinkTimeMs != 0 {
+		s.ThinkTimeMs = int64(thinkTimeMs)
+	}
+
+	if delayType := req.FormValue("delayType"); delayType != "" {
+		s.DelayType = strings.ToLower(delayType)
+	}
+	clock, err := game.NewClockFromConfig(&game_config.Clock{
+		InitialSec:   s.ClockSec,
+		IncrementSec: s.IncrementSec,
+		DelaySec:     s.DelaySec,
+		DelayType:    s.DelayType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.clock = clock
+	return s, nil
+}
+
+// checkAlgorithm returns an error listing the algorithms unless name is one.
+func checkAlgorithm(name string) error {
+	if _, known := ai.NameToAlgorithm[name]; known {
+		return nil
+	}
+	var names []string
+	for n := range ai.NameToAlgorithm {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("unknown algorithm %q, expected one of: %s", name, strings.Join(names, ", "))
+}
+
+// validatePosition rejects positions a game cannot be played from: each side
+// needs one king, the side that just moved cannot be in check and the side to
+// move needs a legal move.
+func validatePosition(p *analysis.ParsedFEN) error {
+	var kings [color.NumColors]int
+	for r := location.CoordinateType(0); r < board.Height; r++ {
+		for c := location.CoordinateType(0); c < board.Width; c++ {
+			if pc := p.Board.GetPiece(location.NewLocation(r, c)); pc != nil && pc.GetPieceType() == piece.KingType {
+				kings[pc.GetColor()]++
+			}
+		}
+	}
+	if kings[color.White] != 1 || kings[color.Black] != 1 {
+		return errors.New("invalid position: each side needs exactly one king")
+	}
+	if p.Board.IsKingInCheck(p.Active ^ 1) {
+		return fmt.Errorf("invalid position: %s is in check but it is not their move", color.Names[p.Active^1])
+	}
+	if len(*p.Board.GetAllMovesUnShuffled(p.Active, p.Previous)) == 0 {
+		return fmt.Errorf("invalid position: %s has no legal moves", color.Names[p.Active])
+	}
+	return nil
+}
@@ -2,48 +2,68 @@ package api_handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/Vadman97/GolangChessAI/pkg/api"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/game"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/piece"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/player"
+	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 	"log"
 	"net/http"
-	"runtime"
-	"sync"
+	"strconv"
 	"time"
 )
 
 const (
-	pingInterval    = 30 * time.Second
-	pongWait        = 60 * time.Second
-	idleGameTimeout = 3 * time.Minute
+	pingInterval = 30 * time.Second
+	pongWait     = 60 * time.Second
 )
 
-var client *websocket.Conn
-var clientMutex = &sync.Mutex{}
-var gameLoopStarted bool
-var idleTimer *time.Timer
+// playerActions maps the player action messages to the game's actions.
+var playerActions = map[string]game.PlayerAction{
+	api.Takeback:  game.Takeback,
+	api.Resign:    game.Resign,
+	api.OfferDraw: game.OfferDraw,
+	api.ClaimDraw: game.ClaimDraw,
+}
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
 }
 
-func HandleConnections(w http.ResponseWriter, r *http.Request) {
-	ws, err := upgrader.Upgrade(w, r, nil)
+// HandleConnections connects the player to the game at /ws/{id}. The client
+// passes the session token the start command returned and the protocol
+// version it speaks, as /ws/{id}?token=...&version=2. A player connecting
+// again, e.g. after a page refresh, replaces their previous connection and
+// is resynced.
+func (r *GameRegistry) HandleConnections(w http.ResponseWriter, req *http.Request) {
+	ws, err := upgrader.Upgrade(w, req, nil)
 	if err != nil {
-		log.Fatal(err)
+		log.Printf("WebSocket upgrade error - %v", err)
 		return
 	}
 
 	defer ws.Close()
 
-	// If game hasn't started yet, reject the connection
-	if getGame() == nil {
-		log.Print("Client attempted to connect, no game has begun...")
+	query := req.URL.Query()
+	if version := query.Get("version"); version != strconv.Itoa(api.ProtocolVersion) {
+		log.Printf("Client attempted to connect with protocol version %q", version)
+		rejectConnection(ws, fmt.Sprintf("this page is out of date (protocol version %q, the server speaks %d), please reload it",
+			version, api.ProtocolVersion))
+		return
+	}
+
+	// If there is no such game, reject the connection
+	hosted := r.get(mux.Vars(req)["id"])
+	if hosted == nil {
+		log.Print("Client attempted to connect, no such game...")
 
 		msg := api.ChessMessage{
 			Type: api.GameNotAvailable,
@@ -56,15 +76,17 @@ func HandleConnections(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Allow 1 Client to connect at a time
-	// TODO(Alex) Might a client queue, but right now the client will have to reload
-	clientMutex.Lock()
-	if client != nil {
-		clientMutex.Unlock()
+	if !hosted.authorized(query.Get("token")) {
+		log.Printf("Client attempted to connect to game %s without its session token", hosted.id)
+		rejectConnection(ws, "invalid session token for this game")
+		return
+	}
 
-		log.Print("Client attempted to connect, but a game is currently in progress...")
+	ok, isReconnect := hosted.connect(ws)
+	if !ok {
+		log.Printf("Client attempted to connect, but game %s has ended...", hosted.id)
 		msg := api.ChessMessage{
-			Type: api.GameFull,
+			Type: api.GameNotAvailable,
 			Data: "",
 		}
 		err := ws.WriteJSON(msg)
@@ -73,17 +95,7 @@ func HandleConnections(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	clientMutex.Unlock()
-
-	// Initialize Client
-	clientMutex.Lock()
-	client = ws
-	if idleTimer != nil {
-		idleTimer.Stop()
-		idleTimer = nil
-	}
-	clientMutex.Unlock()
-	log.Print("Client connected")
+	log.Printf("Client connected to game %s", hosted.id)
 
 	// Keep connection alive with periodic pings
 	ws.SetReadDeadline(time.Now().Add(pongWait))
@@ -98,9 +110,9 @@ func HandleConnections(w http.ResponseWriter, r *http.Request) {
 		for {
 			select {
 			case <-ticker.C:
-				clientMutex.Lock()
+				hosted.mu.Lock()
 				err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
-				clientMutex.Unlock()
+				hosted.mu.Unlock()
 				if err != nil {
 					return
 				}
@@ -112,21 +124,14 @@ func HandleConnections(w http.ResponseWriter, r *http.Request) {
 	defer close(stopPing)
 
 	// Start the game loop on first connection; resync state on reconnect
-	clientMutex.Lock()
-	isReconnect := gameLoopStarted
-	if !isReconnect {
-		gameLoopStarted = true
-	}
-	clientMutex.Unlock()
-
 	if isReconnect {
 		log.Println("Client reconnected - resyncing game state")
-		getGame().SocketBroadcast <- api.CreateChessMessage(api.GameState, getGame().GetJSON())
+		if err := hosted.send(api.CreateChessMessage(api.Resync, resyncJSON(hosted.current()))); err != nil {
+			log.Printf("Unable to send to client - %v", err)
+		}
 	} else {
 		log.Println("New game - starting loop")
-		getGame().ClearCaches(true)
-		runtime.GC()
-		go getGame().Loop(client)
+		go hosted.runLoop(ws)
 	}
 
 	// Wait for Messages (Loop Forever)
@@ -141,56 +146,111 @@ func HandleConnections(w http.ResponseWriter, r *http.Request) {
 			} else {
 				log.Printf("WebSocket Error - %v", err)
 			}
-			clientMutex.Lock()
-			client = nil
-			idleTimer = time.AfterFunc(idleGameTimeout, func() {
-				clientMutex.Lock()
-				defer clientMutex.Unlock()
-				if client == nil {
-					log.Print("Idle timeout - ending abandoned game")
-					setGame(nil)
-					gameLoopStarted = false
-					idleTimer = nil
-				}
-			})
-			clientMutex.Unlock()
+			hosted.disconnect(ws)
+			return
+		}
+
+		hosted.current().SocketBroadcast <- msg
+	}
+}
+
+// HandleSpectatorConnections connects a read-only spectator to the game at
+// /ws-spectate/{id}. Spectators are sent the current position on joining,
+// then every move, status change and the AI's search while it thinks.
+func (r *GameRegistry) HandleSpectatorConnections(w http.ResponseWriter, req *http.Request) {
+	hosted := r.get(mux.Vars(req)["id"])
+	if hosted == nil {
+		ws, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			log.Printf("WebSocket upgrade error - %v", err)
 			return
 		}
+		defer ws.Close()
+		log.Print("Spectator attempted to connect, no such game...")
+		if err := ws.WriteJSON(api.ChessMessage{Type: api.GameNotAvailable}); err != nil {
+			log.Printf("Client Send Error - %v", err)
+		}
+		return
+	}
+	hosted.spectators.HandleSpectatorConnection(w, req)
+}
+
+// rejectConnection tells a client why its connection is refused.
+func rejectConnection(ws *websocket.Conn, reason string) {
+	msg := api.CreateChessMessage(api.ConnectionRejected, api.ConnectionRejectedJSON{
+		Error:           reason,
+		ProtocolVersion: api.ProtocolVersion,
+	})
+	if err := ws.WriteJSON(msg); err != nil {
+		log.Printf("Client Send Error - %v", err)
+	}
+}
 
-		getGame().SocketBroadcast <- msg
+// resyncJSON is everything a reconnecting player needs to pick g up again,
+// as g's loop last published it.
+func resyncJSON(g *game.Game) api.ResyncJSON {
+	snapshot := g.Snapshot()
+	state := *snapshot.State
+	state.History = snapshot.History
+	return api.ResyncJSON{
+		ProtocolVersion: api.ProtocolVersion,
+		State:           &state,
+		Status:          snapshot.Status,
+		AvailableMoves:  snapshot.AvailableMoves,
 	}
 }
 
-func HandleMessages(g *game.Game) {
+// HandleMessages relays the messages of g, the game hosted at hosted, between
+// its loop and its player until done is closed.
+func HandleMessages(hosted *webGame, g *game.Game, done chan struct{}) {
 	for {
-		msg := <-g.SocketBroadcast
+		var msg api.ChessMessage
+		select {
+		case msg = <-g.SocketBroadcast:
+		case <-done:
+			return
+		}
 		switch msg.Type {
 		// Client -> Server
 		case api.PlayerMove:
 			var moveJSON api.MoveJSON
 
 			err := json.Unmarshal([]byte(msg.Data), &moveJSON)
+			if err == nil {
+				err = HandlePlayerMove(g, moveJSON)
+			}
 			if err != nil {
 				log.Printf("Invalid Player Move - %v", err)
-				continue
+				hosted.rejectMove(err.Error())
+			}
+
+		case api.Takeback, api.Resign, api.OfferDraw, api.ClaimDraw:
+			if err := g.RequestAction(playerActions[msg.Type]); err != nil {
+				log.Printf("Invalid Player Action - %v", err)
+				hosted.rejectAction(err.Error())
 			}
-			HandlePlayerMove(moveJSON)
+
+		// Server -> Spectators
+		case api.MovePlayed, api.SpectatorSync:
+			hosted.spectators.Broadcast(msg)
 
 		// Server -> Client
-		case api.GameState:
+		case api.GameState, api.GameStatus:
+			hosted.spectators.Broadcast(msg)
+			if err := hosted.send(msg); err != nil {
+				log.Printf("Unable to send to client - %v", err)
+				continue
+			}
+		case api.AvailablePlayerMoves:
 			fallthrough
-		case api.GameStatus:
+		case api.InvalidMove:
 			fallthrough
-		case api.AvailablePlayerMoves:
+		case api.InvalidAction:
+			fallthrough
+		case api.DrawOfferAnswer:
 			fallthrough
 		case api.AIMove:
-			var err error
-			clientMutex.Lock()
-			if client != nil {
-				err = client.WriteJSON(msg)
-			}
-			clientMutex.Unlock()
-			if err != nil {
+			if err := hosted.send(msg); err != nil {
 				log.Printf("Unable to send to client - %v", err)
 				continue
 			}
@@ -199,22 +259,60 @@ func HandleMessages(g *game.Game) {
 	}
 }
 
-func HandlePlayerMove(moveJSON api.MoveJSON) {
+// HandlePlayerMove passes the player's move to the game, which plays it if it
+// is legal. It returns an error for a move that cannot be parsed, or when a
+// move is already waiting to be played.
+func HandlePlayerMove(g *game.Game, moveJSON api.MoveJSON) error {
 	for c := color.White; c < color.NumColors; c++ {
-		humanPlayer, isHuman := getGame().Players[c].(*player.HumanPlayer)
+		humanPlayer, isHuman := g.Players[c].(*player.HumanPlayer)
 		if isHuman {
-			move := &location.Move{
-				Start: location.NewLocation(moveJSON.Start[0], moveJSON.Start[1]),
-				End:   location.NewLocation(moveJSON.End[0], moveJSON.End[1]),
+			move, err := parsePlayerMove(moveJSON)
+			if err != nil {
+				return err
 			}
-
-			// Add Pawn Promotion Information if it exists
-			if moveJSON.PromotionPiece != (api.PieceJSON{}) {
-				pieceType := rune(moveJSON.PromotionPiece.PieceType[0])
-				move.End = move.End.CreatePawnPromotion(piece.NameToType[pieceType])
+			select {
+			case humanPlayer.Move <- move:
+				return nil
+			default:
+				return errors.New("a move is already waiting to be played")
 			}
-			humanPlayer.Move <- move
-			return
 		}
 	}
+	return errors.New("no human is playing this game")
+}
+
+func parsePlayerMove(moveJSON api.MoveJSON) (*location.Move, error) {
+	for _, coord := range [][2]uint8{moveJSON.Start, moveJSON.End} {
+		if coord[0] >= board.Height || coord[1] >= board.Width {
+			return nil, fmt.Errorf("square %v is off the board", coord)
+		}
+	}
+	move := &location.Move{
+		Start: location.NewLocation(moveJSON.Start[0], moveJSON.Start[1]),
+		End:   location.NewLocation(moveJSON.End[0], moveJSON.End[1]),
+	}
+
+	// Add Pawn Promotion Information if it exists
+	if moveJSON.PromotionPiece != (api.PieceJSON{}) {
+		name := []rune(moveJSON.PromotionPiece.PieceType)
+		if len(name) != 1 || !canPromoteTo(name[0]) {
+			return nil, fmt.Errorf("cannot promote to %q", moveJSON.PromotionPiece.PieceType)
+		}
+		move.End = move.End.CreatePawnPromotion(piece.NameToType[name[0]])
+	}
+	return move, nil
+}
+
+// canPromoteTo reports whether a pawn can promote to the piece named name.
+func canPromoteTo(name rune) bool {
+	pieceType, known := piece.NameToType[name]
+	if !known {
+		return false
+	}
+	for _, option := range piece.PawnPromotionOptions {
+		if option == pieceType {
+			return true
+		}
+	}
+	return false
 }
@@ -14,10 +14,11 @@ type spectatorClient struct {
 	writeCh chan api.ChessMessage
 }
 
-// SpectatorHub relays tournament game state to any number of read-only WebSocket clients.
+// SpectatorHub relays tournament or web game state to any number of read-only WebSocket clients.
 // Each client gets its own write goroutine so a slow/stalled client never blocks the others.
 type SpectatorHub struct {
 	mu             sync.Mutex
+	closed         bool
 	clients        map[*websocket.Conn]*spectatorClient
 	broadcastCh    chan api.ChessMessage
 	lastState      *api.ChessMessage
@@ -67,6 +68,14 @@ func (h *SpectatorHub) Run() {
 		}
 		h.mu.Unlock()
 	}
+
+	// The hub is closed: let each client's write loop flush and disconnect it.
+	h.mu.Lock()
+	for ws, c := range h.clients {
+		delete(h.clients, ws)
+		close(c.writeCh)
+	}
+	h.mu.Unlock()
 }
 
 // BroadcastCh returns the channel that the tournament writes messages to.
@@ -74,13 +83,40 @@ func (h *SpectatorHub) BroadcastCh() chan api.ChessMessage {
 	return h.broadcastCh
 }
 
+// Broadcast queues msg for the spectators without blocking. Messages sent
+// after Close are dropped.
+func (h *SpectatorHub) Broadcast(msg api.ChessMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	select {
+	case h.broadcastCh <- msg:
+	default:
+		log.Printf("spectator broadcast buffer full, dropping %s", msg.Type)
+	}
+}
+
+// Close stops the hub once it has relayed the messages already queued, and
+// disconnects its spectators. Only hubs fed through Broadcast may be closed.
+func (h *SpectatorHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.closed {
+		h.closed = true
+		close(h.broadcastCh)
+	}
+}
+
 // clientWriteLoop is the sole goroutine that writes to a WebSocket connection.
+// It closes the connection once writeCh is closed.
 func clientWriteLoop(c *spectatorClient) {
+	defer c.ws.Close()
 	for msg := range c.writeCh {
 		c.ws.SetWriteDeadline(time.Now().Add(5 * time.Second))
 		if err := c.ws.WriteJSON(msg); err != nil {
 			log.Printf("spectator write error: %v", err)
-			c.ws.Close()
 			return
 		}
 	}
@@ -124,6 +160,11 @@ func (h *SpectatorHub) HandleSpectatorConnection(w http.ResponseWriter, r *http.
 	go clientWriteLoop(client)
 
 	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		close(client.writeCh)
+		return
+	}
 	h.clients[ws] = client
 	h.mu.Unlock()
 	log.Printf("spectator connected (%d total)", len(h.clients))
@@ -136,8 +177,10 @@ func (h *SpectatorHub) HandleSpectatorConnection(w http.ResponseWriter, r *http.
 	}
 
 	h.mu.Lock()
-	delete(h.clients, ws)
+	if _, registered := h.clients[ws]; registered {
+		delete(h.clients, ws)
+		close(client.writeCh) // signals clientWriteLoop to exit
+	}
 	h.mu.Unlock()
-	close(client.writeCh) // signals clientWriteLoop to exit
 	log.Printf("spectator disconnected (%d remaining)", len(h.clients))
 }
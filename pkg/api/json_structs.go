@@ -16,7 +16,6 @@ const (
 	AvailablePlayerMoves = "availablePlayerMoves"
 	GameState            = "gameState"
 	GameStatus           = "gameStatus"
-	GameFull             = "gameFull"
 	GameNotAvailable     = "gameNotAvailable"
 	TournamentInfo       = "tournamentInfo"
 	// SpectatorSync is server-internal: the hub caches it as the current board
@@ -24,6 +23,43 @@ const (
 	// connected clients, so live AIMove animations are not interrupted.
 	SpectatorSync    = "spectatorSync"
 	TournamentResult = "tournamentResult"
+	// InvalidMove tells the player why their move was refused; a GameState
+	// follows to put their board back.
+	InvalidMove = "invalidMove"
+	// Takeback, Resign, OfferDraw and ClaimDraw are player actions; the game
+	// handles them on the player's turn.
+	Takeback  = "takeback"
+	Resign    = "resign"
+	OfferDraw = "offerDraw"
+	ClaimDraw = "claimDraw"
+	// DrawOfferAnswer tells the player whether the AI accepted their draw
+	// offer; InvalidAction why a player action was refused.
+	DrawOfferAnswer = "drawOfferAnswer"
+	InvalidAction   = "invalidAction"
+	// MovePlayed tells spectators of a web game about each move, whoever
+	// played it; SearchInfo shows them the AI's search while it thinks.
+	MovePlayed = "movePlayed"
+	SearchInfo = "searchInfo"
+)
+
+// ProtocolVersion is the version of the player WebSocket protocol. Clients
+// send it when connecting; version 2 added session tokens and Resync.
+const ProtocolVersion = 2
+
+const (
+	// Resync gives a reconnecting player the whole game: the state with its
+	// history and clocks, the status and, on their turn, their legal moves.
+	Resync = "resync"
+	// ConnectionRejected tells a client why the server closed its connection,
+	// e.g. an unsupported protocol version or a wrong session token.
+	ConnectionRejected = "connectionRejected"
+)
+
+const (
+	// AnalysisInfo streams an analysis's progress after each depth of its
+	// search; AnalysisResult ends the stream with the finished analysis.
+	AnalysisInfo   = "analysisInfo"
+	AnalysisResult = "analysisResult"
 )
 
 type ChessMessage struct {
@@ -40,6 +76,41 @@ type GameStateJSON struct {
 	GameStatus       string                                `json:"gameStatus"`
 	MoveLimit        int32                                 `json:"moveLimit"`
 	TimeLimit        time.Duration                         `json:"timeLimit"`
+	Clocks           map[string]int64                      `json:"clocks,omitempty"`
+	// History lets a reconnecting client rebuild the move list. It is only
+	// sent in a Resync; other clients fetch it from the history endpoint.
+	History *GameHistoryJSON `json:"history,omitempty"`
+}
+
+// GameHistoryJSON is the record of a game: the position it started from and
+// every ply played since.
+type GameHistoryJSON struct {
+	StartFEN   string           `json:"startFen"`
+	Moves      []MoveRecordJSON `json:"moves"`
+	GameStatus string           `json:"gameStatus"`
+}
+
+// MoveRecordJSON is one ply of a game record. FEN is the position after the
+// move and ClockMs the mover's remaining time after it, in timed games.
+type MoveRecordJSON struct {
+	Ply         uint            `json:"ply"`
+	Color       string          `json:"color"`
+	UCI         string          `json:"uci"`
+	SAN         string          `json:"san"`
+	FEN         string          `json:"fen"`
+	PlayedAt    time.Time       `json:"playedAt"`
+	ThinkTimeMs int64           `json:"thinkTimeMs"`
+	ClockMs     *int64          `json:"clockMs,omitempty"`
+	Eval        *EvaluationJSON `json:"eval,omitempty"`
+}
+
+// EvaluationJSON is the AI's search behind a move: its score in centipawns
+// from the mover's side, the depth searched and the principal variation in
+// UCI, starting with the move itself.
+type EvaluationJSON struct {
+	Score int      `json:"score"`
+	Depth int      `json:"depth"`
+	PV    []string `json:"pv"`
 }
 
 type GameStatusJSON struct {
@@ -47,6 +118,94 @@ type GameStatusJSON struct {
 	MovesPlayed      uint   `json:"movesPlayed"`
 	GameStatus       string `json:"gameStatus"`
 	KingInCheck      bool   `json:"kingInCheck"`
+	// Clocks holds each side's remaining time in milliseconds, keyed by color
+	// name, in timed games. The side to move's clock is running.
+	Clocks map[string]int64 `json:"clocks,omitempty"`
+	// ClaimableDraw is the status the side to move can end the game with by
+	// claiming a draw, if any.
+	ClaimableDraw string `json:"claimableDraw,omitempty"`
+}
+
+// GameSettingsJSON are the effective settings of a web game, as its start
+// command chose them. ClockSec 0 is an untimed game.
+type GameSettingsJSON struct {
+	FEN          string `json:"fen"`
+	HumanColor   string `json:"humanColor"`
+	Algorithm    string `json:"algorithm"`
+	ThinkTimeMs  int64  `json:"thinkTimeMs"`
+	Depth        int    `json:"depth"`
+	Skill        int    `json:"skill"`
+	ClockSec     int    `json:"clockSec"`
+	IncrementSec int    `json:"incrementSec"`
+	DelaySec     int    `json:"delaySec"`
+	DelayType    string `json:"delayType"`
+}
+
+type ResyncJSON struct {
+	ProtocolVersion int                 `json:"protocolVersion"`
+	State           *GameStateJSON      `json:"state"`
+	Status          *GameStatusJSON     `json:"status"`
+	AvailableMoves  *AvailableMovesJSON `json:"availableMoves,omitempty"`
+}
+
+type ConnectionRejectedJSON struct {
+	Error           string `json:"error"`
+	ProtocolVersion int    `json:"protocolVersion"`
+}
+
+// SearchInfoJSON is the AI's search after its latest completed depth: the
+// score in centipawns from the AI's side and the principal variation, in UCI
+// and SAN, starting with the move it would play.
+type SearchInfoJSON struct {
+	Color     string   `json:"color"`
+	Depth     int      `json:"depth"`
+	Score     int      `json:"score"`
+	PV        []string `json:"pv"`
+	PVSAN     []string `json:"pvSan"`
+	ElapsedMs int64    `json:"elapsedMs"`
+}
+
+// AnalysisJSON is the engine's analysis of a position: its best move and the
+// lines it found, best first, with the nodes and time the analysis took.
+type AnalysisJSON struct {
+	FEN         string             `json:"fen"`
+	Algorithm   string             `json:"algorithm"`
+	BestMove    string             `json:"bestMove"`
+	BestMoveSAN string             `json:"bestMoveSan"`
+	Depth       int                `json:"depth"`
+	Nodes       uint64             `json:"nodes"`
+	TimeMs      int64              `json:"timeMs"`
+	Lines       []AnalysisLineJSON `json:"lines"`
+}
+
+// AnalysisLineJSON is a line of an analysis, its principal variation in UCI
+// and SAN. The score is from the side to move: ScoreCP in centipawns, or
+// ScoreMate moves to a forced mate, negative when the side to move is mated.
+type AnalysisLineJSON struct {
+	Depth     int      `json:"depth"`
+	ScoreCP   *int     `json:"scoreCp,omitempty"`
+	ScoreMate *int     `json:"scoreMate,omitempty"`
+	PV        []string `json:"pv"`
+	PVSAN     []string `json:"pvSan"`
+}
+
+// AnalysisInfoJSON is an analysis's best line after a depth of its search.
+type AnalysisInfoJSON struct {
+	AnalysisLineJSON
+	Nodes  uint64 `json:"nodes"`
+	TimeMs int64  `json:"timeMs"`
+}
+
+type InvalidMoveJSON struct {
+	Error string `json:"error"`
+}
+
+type InvalidActionJSON struct {
+	Error string `json:"error"`
+}
+
+type DrawOfferAnswerJSON struct {
+	Accepted bool `json:"accepted"`
 }
 
 type PieceJSON struct {
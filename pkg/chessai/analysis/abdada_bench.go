@@ -23,6 +23,8 @@ type ABDADABenchConfig struct {
 	StockfishDepth int
 	ShowRoot       int
 	JSONPath       string
+	// DisableSingular turns off ABDADA singular extensions and multi-cut.
+	DisableSingular bool
 }
 
 type ABDADAMatrixConfig struct {
@@ -35,6 +37,10 @@ type ABDADAMatrixConfig struct {
 	StockfishPath  string
 	StockfishDepth int
 	Modes          string
+	// ProbCutMargin and ProbCutSEEThreshold apply to every mode that
+	// searches with ProbCut (0 margin selects ai.DefaultProbCutMargin).
+	ProbCutMargin       int
+	ProbCutSEEThreshold int
 }
 
 type matrixMode struct {
@@ -46,6 +52,11 @@ type matrixMode struct {
 	DisableLMR      bool
 	DisableFutility bool
 	DisableRazoring bool
+	DisableSingular bool
+	DisableProbCut  bool
+
+	ProbCutMargin       int
+	ProbCutSEEThreshold int
 }
 
 type benchPosition struct {
@@ -96,6 +107,7 @@ type benchReport struct {
 	Runs            int                   `json:"runs"`
 	StockfishPath   string                `json:"stockfishPath,omitempty"`
 	StockfishDepth  int                   `json:"stockfishDepth,omitempty"`
+	DisableSingular bool                  `json:"disableSingular,omitempty"`
 	Positions       []benchReportPosition `json:"positions"`
 	Summary         benchReportSummary    `json:"summary"`
 	ThreadSummaries []benchThreadSummary  `json:"threadSummaries"`
@@ -203,12 +215,13 @@ func RunABDADABench(cfg ABDADABenchConfig) error {
 
 	totals := benchTotals{byThread: map[int]*threadTotals{}}
 	report := benchReport{
-		FENPath:        cfg.FENPath,
-		Depth:          cfg.Depth,
-		ThinkTimeMS:    cfg.ThinkTime.Milliseconds(),
-		Runs:           cfg.Runs,
-		StockfishPath:  cfg.StockfishPath,
-		StockfishDepth: cfg.StockfishDepth,
+		FENPath:         cfg.FENPath,
+		Depth:           cfg.Depth,
+		ThinkTimeMS:     cfg.ThinkTime.Milliseconds(),
+		Runs:            cfg.Runs,
+		StockfishPath:   cfg.StockfishPath,
+		StockfishDepth:  cfg.StockfishDepth,
+		DisableSingular: cfg.DisableSingular,
 	}
 	for posIdx, pos := range positions {
 		totals.positions++
@@ -247,7 +260,7 @@ func RunABDADABench(cfg ABDADABenchConfig) error {
 		for _, threads := range cfg.Threads {
 			results := make([]benchRun, 0, cfg.Runs)
 			for run := 0; run < cfg.Runs; run++ {
-				result, err := runABDADABenchSearch(pos.FEN, threads, cfg.Depth, cfg.ThinkTime)
+				result, err := runABDADABenchSearch(pos.FEN, threads, cfg.Depth, cfg.ThinkTime, cfg.DisableSingular)
 				if err != nil {
 					return fmt.Errorf("%s threads=%d run=%d: %w", pos.Tag, threads, run+1, err)
 				}
@@ -458,6 +471,10 @@ func RunABDADAMatrix(cfg ABDADAMatrixConfig) error {
 	if err != nil {
 		return err
 	}
+	for i := range modes {
+		modes[i].ProbCutMargin = cfg.ProbCutMargin
+		modes[i].ProbCutSEEThreshold = cfg.ProbCutSEEThreshold
+	}
 	var positions []benchPosition
 	if strings.TrimSpace(cfg.FEN) != "" {
 		if _, err := ParseFEN(cfg.FEN); err != nil {
@@ -604,10 +621,20 @@ func parseMatrixModes(s string) ([]matrixMode, error) {
 			modes = append(modes, matrixMode{Name: "abdada-8-no-futility", Algorithm: ai.AlgorithmABDADA, Threads: 8, TT: true, DisableFutility: true})
 		case "abdada8norazor":
 			modes = append(modes, matrixMode{Name: "abdada-8-no-razor", Algorithm: ai.AlgorithmABDADA, Threads: 8, TT: true, DisableRazoring: true})
+		case "abdada1nosingular":
+			modes = append(modes, matrixMode{Name: "abdada-1-no-singular", Algorithm: ai.AlgorithmABDADA, Threads: 1, TT: true, DisableSingular: true})
+		case "abdada8nosingular":
+			modes = append(modes, matrixMode{Name: "abdada-8-no-singular", Algorithm: ai.AlgorithmABDADA, Threads: 8, TT: true, DisableSingular: true})
+		case "abdada1noprobcut":
+			modes = append(modes, matrixMode{Name: "abdada-1-no-probcut", Algorithm: ai.AlgorithmABDADA, Threads: 1, TT: true, DisableProbCut: true})
+		case "abdada8noprobcut":
+			modes = append(modes, matrixMode{Name: "abdada-8-no-probcut", Algorithm: ai.AlgorithmABDADA, Threads: 8, TT: true, DisableProbCut: true})
 		case "negascouttt":
 			modes = append(modes, matrixMode{Name: "negascout-tt", Algorithm: ai.AlgorithmNegaScout, Threads: 1, TT: true})
 		case "negascoutnott":
 			modes = append(modes, matrixMode{Name: "negascout-no-tt", Algorithm: ai.AlgorithmNegaScout, Threads: 1, TT: false})
+		case "negascoutnoprobcut":
+			modes = append(modes, matrixMode{Name: "negascout-no-probcut", Algorithm: ai.AlgorithmNegaScout, Threads: 1, TT: true, DisableProbCut: true})
 		default:
 			return nil, fmt.Errorf("unknown matrix mode %q", part)
 		}
@@ -625,6 +652,8 @@ func safeABDADAMode(name string, threads int, tt bool) matrixMode {
 		DisableLMR:      true,
 		DisableFutility: true,
 		DisableRazoring: true,
+		DisableSingular: true,
+		DisableProbCut:  true,
 	}
 }
 
@@ -648,9 +677,18 @@ func runMatrixSearch(fen string, mode matrixMode, depth int, thinkTime time.Dura
 			DisableLMR:      mode.DisableLMR,
 			DisableFutility: mode.DisableFutility,
 			DisableRazoring: mode.DisableRazoring,
+			DisableSingular: mode.DisableSingular,
+			DisableProbCut:  mode.DisableProbCut,
+
+			ProbCutMargin:       mode.ProbCutMargin,
+			ProbCutSEEThreshold: mode.ProbCutSEEThreshold,
 		}
 	case ai.AlgorithmNegaScout:
-		algorithm = &ai.NegaScout{}
+		algorithm = &ai.NegaScout{
+			DisableProbCut:      mode.DisableProbCut,
+			ProbCutMargin:       mode.ProbCutMargin,
+			ProbCutSEEThreshold: mode.ProbCutSEEThreshold,
+		}
 	default:
 		return benchRun{}, fmt.Errorf("unsupported matrix algorithm %s", mode.Algorithm)
 	}
@@ -800,7 +838,7 @@ func printRootMoveScores(fen string, depth, limit int, sf *StockfishEngine, sfDe
 	return nil
 }
 
-func runABDADABenchSearch(fen string, threads, depth int, thinkTime time.Duration) (benchRun, error) {
+func runABDADABenchSearch(fen string, threads, depth int, thinkTime time.Duration, disableSingular bool) (benchRun, error) {
 	parsed, err := ParseFEN(fen)
 	if err != nil {
 		return benchRun{}, err
@@ -811,7 +849,7 @@ func runABDADABenchSearch(fen string, threads, depth int, thinkTime time.Duratio
 	if maxDepth <= 0 {
 		maxDepth = 64
 	}
-	algorithm := &ai.ABDADA{NumThreads: threads}
+	algorithm := &ai.ABDADA{NumThreads: threads, DisableSingular: disableSingular}
 	player := ai.NewAIPlayer(parsed.Active, algorithm)
 	player.MaxSearchDepth = maxDepth
 	player.MaxThinkTime = thinkTime
@@ -0,0 +1,241 @@
+package analysis
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/api"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
+)
+
+// unlimitedDepth is the search depth of an analysis limited only by time or
+// nodes.
+const unlimitedDepth = 64
+
+// AnalyzeConfig is a position to analyse with the algorithm named Algorithm.
+// The search stops at the first of its limits it reaches: ThinkTime, which
+// must be set, and Depth and Nodes, which are unlimited when 0. MultiPV is
+// the number of lines to show.
+type AnalyzeConfig struct {
+	Position  *ParsedFEN
+	Algorithm string
+	Depth     int
+	ThinkTime time.Duration
+	Nodes     uint64
+	MultiPV   int
+}
+
+// Analyze searches cfg.Position for its best line. With MultiPV above one,
+// the best line's search gets half the limits and the other moves share the
+// rest, each searched from the opponent's side a depth shallower; the best
+// of them follow the best line, and moves not reached in time are left out.
+// onInfo, if not nil, is called with the best line after each depth of its
+// search. Cancelling ctx ends the analysis early.
+func Analyze(ctx context.Context, cfg AnalyzeConfig, onInfo func(api.AnalysisInfoJSON)) *api.AnalysisJSON {
+	start := time.Now()
+	pos, side := cfg.Position, cfg.Position.Active
+	moves := *pos.Board.GetAllMovesUnShuffled(side, pos.Previous)
+	alternatives := cfg.MultiPV - 1
+	if alternatives > len(moves)-1 {
+		alternatives = len(moves) - 1
+	}
+	depth := cfg.Depth
+	if depth <= 0 {
+		depth = unlimitedDepth
+	}
+	thinkTime, nodes := cfg.ThinkTime, cfg.Nodes
+	if alternatives > 0 {
+		thinkTime, nodes = thinkTime/2, nodes/2
+	}
+
+	s := &searches{ctx: ctx}
+	finished := make(chan struct{})
+	defer close(finished)
+	go s.abortOnCancel(finished)
+
+	player := ai.NewAnalysisPlayer(side, ai.NewAlgorithm(cfg.Algorithm))
+	player.MaxSearchDepth, player.MaxThinkTime, player.MaxNodes = depth, thinkTime, nodes
+	if onInfo != nil {
+		player.OnSearchInfo = func(info ai.SearchInfo) {
+			onInfo(api.AnalysisInfoJSON{
+				AnalysisLineJSON: analysisLine(pos, info.Depth, info.Score, info.PV),
+				Nodes:            info.Nodes,
+				TimeMs:           int64(time.Since(start) / time.Millisecond),
+			})
+		}
+	}
+	best, score, line := s.run(player, pos.Board, pos.Previous)
+	lines := []api.AnalysisLineJSON{analysisLine(pos, player.LastSearchDepth, score, line)}
+
+	if alternatives > 0 {
+		reply := ai.NewAnalysisPlayer(side^1, ai.NewAlgorithm(cfg.Algorithm))
+		reply.MaxSearchDepth = player.LastSearchDepth - 1
+		if reply.MaxSearchDepth < 1 {
+			reply.MaxSearchDepth = 1
+		}
+		var others []location.Move
+		for _, m := range moves {
+			if !m.Equals(&best) {
+				others = append(others, m)
+			}
+		}
+		deadline := start.Add(cfg.ThinkTime)
+		var scored []scoredLine
+		for i, m := range others {
+			remaining := time.Until(deadline)
+			if remaining <= 0 || ctx.Err() != nil {
+				break
+			}
+			reply.MaxThinkTime = remaining / time.Duration(len(others)-i)
+			if cfg.Nodes != 0 {
+				reply.MaxNodes = (cfg.Nodes-nodes)/uint64(len(others)) + 1
+			}
+			scored = append(scored, s.alternative(reply, pos, m))
+		}
+		sort.SliceStable(scored, func(i, j int) bool {
+			return scored[i].score > scored[j].score
+		})
+		if len(scored) > alternatives {
+			scored = scored[:alternatives]
+		}
+		for _, alt := range scored {
+			lines = append(lines, analysisLine(pos, alt.depth, alt.score, alt.line))
+		}
+	}
+
+	return &api.AnalysisJSON{
+		FEN:         pos.Normalized,
+		Algorithm:   cfg.Algorithm,
+		BestMove:    board.MoveToUCI(best),
+		BestMoveSAN: pos.Board.SAN(best, side, pos.Previous),
+		Depth:       player.LastSearchDepth,
+		Nodes:       s.nodes,
+		TimeMs:      int64(time.Since(start) / time.Millisecond),
+		Lines:       lines,
+	}
+}
+
+// scoredLine is a line with its score from the side to move.
+type scoredLine struct {
+	score int
+	depth int
+	line  []location.Move
+}
+
+// searches runs an analysis's searches one at a time, adding up their nodes.
+type searches struct {
+	ctx   context.Context
+	nodes uint64
+
+	mu      sync.Mutex
+	current *ai.AIPlayer
+}
+
+// run searches b for p and returns the move found, its score and its line.
+func (s *searches) run(p *ai.AIPlayer, b *board.Board, previousMove *board.LastMove) (location.Move, int, []location.Move) {
+	b = b.Copy()
+	if _, parallel := p.Algorithm.(*ai.ABDADA); parallel {
+		// ABDADA generates moves concurrently, without the board's caches
+		b.CacheGetAllMoves, b.CacheGetAllAttackableMoves = false, false
+	}
+	s.mu.Lock()
+	s.current = p
+	s.mu.Unlock()
+	move := *p.GetBestMove(b, previousMove, nil)
+	s.mu.Lock()
+	s.current = nil
+	s.mu.Unlock()
+
+	s.nodes += p.Metrics.MovesConsidered
+	if !p.HasLastScore || len(p.LastLine) == 0 {
+		return move, 0, []location.Move{move}
+	}
+	return move, p.LastScore, p.LastLine
+}
+
+// alternative scores move, one of the moves of pos, by searching the
+// position after it for the opponent with reply.
+func (s *searches) alternative(reply *ai.AIPlayer, pos *ParsedFEN, move location.Move) scoredLine {
+	after := pos.Board.Copy()
+	m := move
+	previousMove := board.MakeMove(&m, after)
+	opponent := pos.Active ^ 1
+	if len(*after.GetAllMovesUnShuffled(opponent, previousMove)) == 0 {
+		// Checkmate or stalemate
+		alt := scoredLine{depth: 1, line: []location.Move{move}}
+		if after.IsKingInCheck(opponent) {
+			alt.score = ai.WinScore
+		}
+		return alt
+	}
+	_, score, line := s.run(reply, after, previousMove)
+	return scoredLine{
+		score: -score,
+		depth: reply.LastSearchDepth + 1,
+		line:  append([]location.Move{move}, line...),
+	}
+}
+
+// abortOnCancel keeps aborting the search in progress once s.ctx is
+// cancelled, until finished is closed. A search only notices an abort that
+// comes after it has started, hence the repeats.
+func (s *searches) abortOnCancel(finished <-chan struct{}) {
+	select {
+	case <-s.ctx.Done():
+	case <-finished:
+		return
+	}
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		s.mu.Lock()
+		if s.current != nil {
+			s.current.Abort()
+		}
+		s.mu.Unlock()
+		select {
+		case <-ticker.C:
+		case <-finished:
+			return
+		}
+	}
+}
+
+// analysisLine is line, a line of depth with score from the side to move of
+// pos, in the API's form.
+func analysisLine(pos *ParsedFEN, depth, score int, line []location.Move) api.AnalysisLineJSON {
+	san := pos.Board.LineSAN(line, pos.Active, pos.Previous)
+	analysis := api.AnalysisLineJSON{Depth: depth, PV: board.LineUCI(line), PVSAN: san}
+	if score < ai.WinScore && score > ai.LossScore {
+		analysis.ScoreCP = &score
+		return analysis
+	}
+	mate := (matePlies(depth, score, san) + 1) / 2
+	if score < 0 {
+		mate = -mate
+	}
+	analysis.ScoreMate = &mate
+	return analysis
+}
+
+// matePlies is the number of plies to the mate a search to depth scored
+// score. A mate score carries the depth left when the mate was found, but
+// the line shows it exactly when it plays out to the mate.
+func matePlies(depth, score int, san []string) int {
+	if n := len(san); n > 0 && strings.HasSuffix(san[n-1], "#") {
+		return n
+	}
+	left := score - ai.WinScore
+	if score < 0 {
+		left = ai.LossScore - score
+	}
+	if plies := depth - left; plies > 0 {
+		return plies
+	}
+	return 1
+}
@@ -0,0 +1,65 @@
+package analysis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/api"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+)
+
+func TestAnalyzeFindsMateAndAlternatives(t *testing.T) {
+	pos, err := ParseFEN("6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var infos []api.AnalysisInfoJSON
+	result := Analyze(context.Background(), AnalyzeConfig{
+		Position:  pos,
+		Algorithm: game_config.Get().Algorithm,
+		Depth:     3,
+		ThinkTime: 5 * time.Second,
+		MultiPV:   3,
+	}, func(info api.AnalysisInfoJSON) { infos = append(infos, info) })
+
+	if result.BestMove != "d1d8" || result.BestMoveSAN != "Rd8#" {
+		t.Errorf("best move %s (%s), want d1d8 (Rd8#)", result.BestMove, result.BestMoveSAN)
+	}
+	if len(result.Lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(result.Lines))
+	}
+	if mate := result.Lines[0].ScoreMate; mate == nil || *mate != 1 {
+		t.Errorf("best line scored %+v, want mate in 1", result.Lines[0])
+	}
+	for _, line := range result.Lines[1:] {
+		if line.ScoreCP == nil || line.PV[0] == "d1d8" || len(line.PV) != len(line.PVSAN) {
+			t.Errorf("unexpected alternative %+v", line)
+		}
+	}
+	if len(infos) == 0 || result.Nodes == 0 {
+		t.Errorf("got %d progress updates and %d nodes, want some of each", len(infos), result.Nodes)
+	}
+}
+
+func TestAnalyzeStopsWhenCancelled(t *testing.T) {
+	pos, err := ParseFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	start := time.Now()
+	result := Analyze(ctx, AnalyzeConfig{
+		Position:  pos,
+		Algorithm: game_config.Get().Algorithm,
+		ThinkTime: time.Minute,
+		MultiPV:   2,
+	}, nil)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("cancelled analysis took %s", elapsed)
+	}
+	if result.BestMove == "" {
+		t.Error("no best move")
+	}
+}
@@ -0,0 +1,110 @@
+package analysis
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/util"
+)
+
+type EvalSymmetryConfig struct {
+	// FENPath is an ABDADA benchmark-format FEN file (optional).
+	FENPath string
+	// BoardsDir holds evaluation_boards-style text boards (optional).
+	BoardsDir string
+	// FEN checks a single position instead of the files.
+	FEN string
+}
+
+type symmetryPosition struct {
+	Name     string
+	Board    *board.Board
+	WhoMoves color.Color
+}
+
+// RunEvalSymmetry checks every configured position with ai.CheckEvalSymmetry
+// and prints the first asymmetric term per evaluator. It returns an error if
+// any position is asymmetric so it can gate scripts.
+func RunEvalSymmetry(cfg EvalSymmetryConfig) error {
+	positions, err := loadSymmetryPositions(cfg)
+	if err != nil {
+		return err
+	}
+	if len(positions) == 0 {
+		return fmt.Errorf("no positions to check")
+	}
+	evaluators := make([]string, len(ai.SymmetryEvaluators))
+	for i, ev := range ai.SymmetryEvaluators {
+		evaluators[i] = ev.Name
+	}
+	fmt.Printf("Eval symmetry: %d positions, evaluators=%s\n", len(positions), strings.Join(evaluators, ","))
+
+	failed := 0
+	for _, pos := range positions {
+		violations := ai.CheckEvalSymmetry(pos.Board, pos.WhoMoves)
+		if len(violations) == 0 {
+			continue
+		}
+		failed++
+		fmt.Printf("ASYMMETRIC %s\n", pos.Name)
+		for _, v := range violations {
+			fmt.Printf("  %s\n", v)
+		}
+	}
+	fmt.Printf("%d/%d positions symmetric\n", len(positions)-failed, len(positions))
+	if failed > 0 {
+		return fmt.Errorf("%d positions evaluated asymmetrically", failed)
+	}
+	return nil
+}
+
+func loadSymmetryPositions(cfg EvalSymmetryConfig) ([]symmetryPosition, error) {
+	var positions []symmetryPosition
+	if cfg.FEN != "" {
+		parsed, err := ParseFEN(cfg.FEN)
+		if err != nil {
+			return nil, err
+		}
+		return append(positions, symmetryPosition{cfg.FEN, parsed.Board, parsed.Active}), nil
+	}
+	if cfg.FENPath != "" {
+		bench, err := loadBenchPositions(cfg.FENPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, pos := range bench {
+			parsed, err := ParseFEN(pos.FEN)
+			if err != nil {
+				return nil, err
+			}
+			positions = append(positions, symmetryPosition{pos.FEN, parsed.Board, parsed.Active})
+		}
+	}
+	if cfg.BoardsDir != "" {
+		files, err := ioutil.ReadDir(cfg.BoardsDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			name := filepath.Join(cfg.BoardsDir, f.Name())
+			lines, skip := util.LoadBoardFile(name)
+			if skip {
+				continue
+			}
+			whoMoves := color.Black
+			if strings.HasPrefix(lines[0], "White") {
+				whoMoves = color.White
+			}
+			b := &board.Board{}
+			b.ResetDefault()
+			b.LoadBoardFromText(lines[1:])
+			positions = append(positions, symmetryPosition{name, b, whoMoves})
+		}
+	}
+	return positions, nil
+}
@@ -0,0 +1,46 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
+)
+
+type MateSolveConfig struct {
+	FEN string
+	// MaxNodes is the proof-number tree budget (0 selects
+	// ai.DefaultProofNumberMaxNodes).
+	MaxNodes int
+}
+
+// RunMateSolve runs a proof-number search for a forced mate by the side to
+// move and prints the proven line in UCI, or "no mate found".
+func RunMateSolve(cfg MateSolveConfig) error {
+	parsed, err := ParseFEN(cfg.FEN)
+	if err != nil {
+		return err
+	}
+	maxNodes := cfg.MaxNodes
+	if maxNodes <= 0 {
+		maxNodes = ai.DefaultProofNumberMaxNodes
+	}
+	start := time.Now()
+	result := ai.SolveMate(parsed.Board, parsed.Active, parsed.Previous, maxNodes, nil)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if !result.Proven {
+		reason := "node budget exhausted"
+		if result.Disproven {
+			reason = "disproven"
+		}
+		fmt.Printf("no mate found (%s, nodes=%d, time=%s)\n", reason, result.Nodes, elapsed)
+		return nil
+	}
+	line := make([]string, len(result.Line))
+	for i, m := range result.Line {
+		line[i] = MoveToUCI(m)
+	}
+	fmt.Printf("mate in %d: %s (nodes=%d, time=%s)\n", result.MateIn(), strings.Join(line, " "), result.Nodes, elapsed)
+	return nil
+}
@@ -35,3 +35,46 @@ func TestReplaySANMovesHandlesKingsideCastle(t *testing.T) {
 		t.Fatalf("black castle UCI = %s, want e8g8", got)
 	}
 }
+
+func TestBoardSANMatchesReplayedGames(t *testing.T) {
+	for _, moves := range []string{
+		"e4 d5 exd5 Qxd5 Nf3 Qe4+ Be2 Qg6 Nc3 Qxg2 Rg1 Qh3 d4 c5 dxc5 Nf6 Be3 Nbd7 Nb5 Kd8 Rg3 Qf5 Ng5 Ne4 Rf3 Qxg5 Bxg5 Nxg5 Ra3 a5 Qd5 h6 c6 e6 cxb7 Rb8 bxc8=R+ Rxc8",
+		"Nf3 Nf6 g3 g6 Bg2 Bg7 O-O O-O",
+		"e4 e6 e5 d5 exd6",
+		"f3 e5 g4 Qh4#",
+	} {
+		replayed, err := ReplaySANMoves(moves)
+		if err != nil {
+			t.Fatalf("ReplaySANMoves(%q) error = %v", moves, err)
+		}
+		for _, ply := range replayed {
+			position, err := ParseFEN(ply.FENBefore)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, err := matchUCIMove(position.Board, position.Active, position.Previous, ply.UCI)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := position.Board.SAN(m, position.Active, position.Previous); got != ply.SAN {
+				t.Errorf("ply %d SAN = %s, want %s", ply.Ply, got, ply.SAN)
+			}
+		}
+	}
+}
+
+func TestBoardSANDisambiguatesByRank(t *testing.T) {
+	position, err := ParseFEN("4k3/8/8/8/8/R7/8/R3K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for uci, want := range map[string]string{"a1a2": "R1a2", "a3a2": "R3a2", "a3b3": "Rb3", "a1d1": "Rd1"} {
+		m, err := matchUCIMove(position.Board, position.Active, position.Previous, uci)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := position.Board.SAN(m, position.Active, position.Previous); got != want {
+			t.Errorf("%s SAN = %s, want %s", uci, got, want)
+		}
+	}
+}
@@ -223,26 +223,13 @@ func parseUCISquare(square string) (location.Location, error) {
 	return location.NewLocation(row, col), nil
 }
 
+// statusAfterMove is the game's status after a ply, reporting a draw that can
+// be claimed as if it had been.
 func statusAfterMove(b *board.Board, nextSide color.Color, previousMove *board.LastMove) byte {
-	if b.IsInCheckmate(nextSide, previousMove) {
-		if nextSide == color.White {
-			return game.BlackWin
-		}
-		return game.WhiteWin
-	}
-	if b.IsStalemate(nextSide, previousMove) {
-		return game.Stalemate
-	}
-	if b.MovesSinceNoDraw >= 100 {
-		return game.FiftyMoveDraw
-	}
-	if b.CurrentPositionRepeats >= 2 {
-		return game.RepeatedActionThreeTimeDraw
-	}
-	if b.IsInsufficientMaterial() {
-		return game.InsufficientMaterialDraw
+	if status := game.StatusAfterMove(b, nextSide, previousMove); status != game.Active {
+		return status
 	}
-	return game.Active
+	return game.BoardClaimableDraw(b)
 }
 
 func PrintUCIReplayJSON(states []UCIReplayState) error {
@@ -604,3 +604,62 @@ func TestIsInsufficientMaterial_KQvsK(t *testing.T) {
 	})
 	assert.False(t, b.IsInsufficientMaterial())
 }
+
+func TestIsDeadPosition_SameColoredBishops(t *testing.T) {
+	// All three bishops stand on dark squares: (0+1)%2, (5+0)%2 and (7+0)%2 are 1.
+	b := loadInsufficientMaterialBoard([]string{
+		"   |B_B|   |   |B_K|   |   |   ",
+		"   |   |   |   |   |   |   |   ",
+		"   |   |   |   |   |   |   |   ",
+		"   |   |   |   |   |   |   |   ",
+		"   |   |   |   |   |   |   |   ",
+		"W_B|   |   |   |   |   |   |   ",
+		"   |   |   |   |   |   |   |   ",
+		"W_B|   |   |   |W_K|   |   |   ",
+	})
+	assert.False(t, b.IsInsufficientMaterial())
+	assert.True(t, b.IsDeadPosition())
+
+	// A bishop on a light square can help mate.
+	b.SetPiece(location.NewLocation(5, 0), nil)
+	lightBishop := PieceFromType(piece.BishopType)
+	lightBishop.SetColor(color.White)
+	b.SetPiece(location.NewLocation(5, 1), lightBishop)
+	assert.False(t, b.IsDeadPosition())
+}
+
+func TestIsDeadPosition_PawnFortress(t *testing.T) {
+	// Row 0 is White's back rank, so White's pawns move down the page. Each
+	// side's pawns and the squares they attack wall its king in.
+	lines := []string{
+		"   |   |   |   |W_K|   |   |   ",
+		"   |   |   |   |   |   |   |   ",
+		"   |   |   |   |   |   |   |   ",
+		"W_P|   |W_P|   |W_P|   |W_P|   ",
+		"B_P|   |B_P|   |B_P|   |B_P|   ",
+		"   |   |   |   |   |   |   |   ",
+		"   |   |   |   |   |   |   |   ",
+		"   |   |   |   |B_K|   |   |   ",
+	}
+	b := loadInsufficientMaterialBoard(lines)
+	assert.True(t, b.IsDeadPosition())
+
+	// Without the last pair the kings can walk around the chain to the pawns.
+	lines[3] = "W_P|   |W_P|   |W_P|   |   |   "
+	lines[4] = "B_P|   |B_P|   |B_P|   |   |   "
+	b = loadInsufficientMaterialBoard(lines)
+	assert.False(t, b.IsDeadPosition())
+
+	// A pawn with a capture can still move.
+	lines[3] = "W_P|   |W_P|   |W_P|   |W_P|   "
+	lines[4] = "B_P|   |B_P|   |B_P|   |B_P|B_P"
+	b = loadInsufficientMaterialBoard(lines)
+	assert.False(t, b.IsDeadPosition())
+
+	// A black pawn that just moved two squares beside a white pawn can be
+	// taken en passant.
+	lines[3] = "W_P|B_P|W_P|   |W_P|   |W_P|   "
+	lines[4] = "B_P|   |B_P|   |B_P|   |B_P|   "
+	b = loadInsufficientMaterialBoard(lines)
+	assert.False(t, b.IsDeadPosition())
+}
@@ -692,6 +692,136 @@ func (b *Board) IsInsufficientMaterial() bool {
 	return false
 }
 
+// IsDeadPosition returns true when no sequence of legal moves can lead to
+// checkmate. Besides insufficient material it recognizes positions where all
+// pieces but the kings are bishops on squares of one color, and pawn
+// fortresses: kings and pawns only, every pawn blocked by a pawn with nothing
+// to capture, and neither king able to reach an enemy pawn it could take.
+// Positions it cannot prove dead are not.
+func (b *Board) IsDeadPosition() bool {
+	return b.IsInsufficientMaterial() || b.hasOnlySameColoredBishops() || b.isPawnFortress()
+}
+
+// hasOnlySameColoredBishops reports whether every piece but the kings is a
+// bishop and all of them stand on squares of one color.
+func (b *Board) hasOnlySameColoredBishops() bool {
+	squareColor := -1
+	for row := location.CoordinateType(0); row < Height; row++ {
+		for col := location.CoordinateType(0); col < Width; col++ {
+			pt, _, ok := b.GetPieceTypeColor(location.NewLocation(row, col))
+			if !ok || pt == piece.KingType {
+				continue
+			}
+			if pt != piece.BishopType {
+				return false
+			}
+			if squareColor == -1 {
+				squareColor = int(row+col) % 2
+			} else if squareColor != int(row+col)%2 {
+				return false
+			}
+		}
+	}
+	return squareColor != -1
+}
+
+// isPawnFortress reports whether only kings and pawns are left, no pawn can
+// ever move again and no king can ever capture a pawn, so that nobody can be
+// checked, let alone mated.
+func (b *Board) isPawnFortress() bool {
+	var pawns [Height][Width]bool
+	var pawnColor [Height][Width]color.Color
+	var kings [color.NumColors]location.Location
+	// attacked marks the squares each color's pawns attack, which the other
+	// king can never enter while the pawns stand still.
+	var attacked [color.NumColors][Height][Width]bool
+	numPawns := 0
+	for row := location.CoordinateType(0); row < Height; row++ {
+		for col := location.CoordinateType(0); col < Width; col++ {
+			loc := location.NewLocation(row, col)
+			pt, c, ok := b.GetPieceTypeColor(loc)
+			if !ok {
+				continue
+			}
+			switch pt {
+			case piece.KingType:
+				kings[c] = loc
+			case piece.PawnType:
+				pawns[row][col], pawnColor[row][col] = true, c
+				numPawns++
+			default:
+				return false
+			}
+		}
+	}
+	if numPawns == 0 {
+		return false
+	}
+
+	for row := 0; row < Height; row++ {
+		for col := 0; col < Width; col++ {
+			if !pawns[row][col] {
+				continue
+			}
+			c := pawnColor[row][col]
+			ahead := row + 1
+			if c == color.Black {
+				ahead = row - 1
+			}
+			if ahead < 0 || ahead >= Height || !pawns[ahead][col] {
+				return false
+			}
+			for _, diagonal := range []int{col - 1, col + 1} {
+				if diagonal < 0 || diagonal >= Width {
+					continue
+				}
+				if pawns[ahead][diagonal] && pawnColor[ahead][diagonal] != c {
+					return false
+				}
+				// An enemy pawn beside it may have just moved two squares
+				// and be open to en passant.
+				if pawns[row][diagonal] && pawnColor[row][diagonal] != c && !pawns[ahead][diagonal] {
+					return false
+				}
+				attacked[c][ahead][diagonal] = true
+			}
+		}
+	}
+
+	// Flood fill the squares each king can reach without stepping into a pawn's
+	// attack, stopping at any enemy pawn it could capture.
+	for c := color.White; c < color.NumColors; c++ {
+		var seen [Height][Width]bool
+		start := kings[c]
+		queue := [][2]int{{int(start.GetRow()), int(start.GetCol())}}
+		seen[start.GetRow()][start.GetCol()] = true
+		for len(queue) > 0 {
+			square := queue[0]
+			queue = queue[1:]
+			for dr := -1; dr <= 1; dr++ {
+				for dc := -1; dc <= 1; dc++ {
+					row, col := square[0]+dr, square[1]+dc
+					if row < 0 || row >= Height || col < 0 || col >= Width || seen[row][col] {
+						continue
+					}
+					seen[row][col] = true
+					if attacked[c^1][row][col] {
+						continue
+					}
+					if pawns[row][col] {
+						if pawnColor[row][col] != c {
+							return false
+						}
+						continue
+					}
+					queue = append(queue, [2]int{row, col})
+				}
+			}
+		}
+	}
+	return true
+}
+
 /**
  * Checks if the board is reaching a draw based on the previous move (pawn movement, piece capture)
  */
@@ -0,0 +1,72 @@
+package board
+
+import (
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+)
+
+// Mirror returns the color-flipped copy of b: every piece moves to the same
+// file on the opposite rank (row r -> row 7-r) and changes color, and the
+// per-color flags and king locations are swapped to match. A correct,
+// side-to-move-relative evaluation must score Mirror(b) with the other side
+// to move exactly as it scores b.
+//
+// Repetition history is dropped: the hashes in PreviousPositions describe the
+// unmirrored positions and would never match again.
+func (b *Board) Mirror() *Board {
+	m := b.Copy()
+	for row := 0; row < Height; row++ {
+		m.board[row] = 0
+	}
+	for row := location.CoordinateType(0); row < Height; row++ {
+		for col := location.CoordinateType(0); col < Width; col++ {
+			data := b.getPieceData(location.NewLocation(row, col))
+			if data == 0 {
+				continue
+			}
+			to := location.NewLocation(Height-1-row, col)
+			m.board[Height-1-row] |= uint32(data^0x1) << getBitOffset(to)
+		}
+	}
+	m.flags = b.flags>>NumFlagBits | b.flags<<NumFlagBits
+	for c := color.Color(0); c < color.NumColors; c++ {
+		row, col := b.KingLocations[c].Get()
+		m.KingLocations[c^1] = location.NewLocation(Height-1-row, col)
+	}
+	m.PreviousPositions = nil
+	m.PreviousPositionsSeen = 0
+	m.CurrentPositionRepeats = 0
+	return m
+}
+
+// FlipFiles returns the copy of b reflected across the d/e file boundary
+// (col c -> col 7-c) with colors unchanged. Kings end up on the wrong file for
+// castling, so castling-related flags are only swapped left/right and should
+// not be relied on; positions where this matters are excluded from file-flip
+// checks by the callers.
+func (b *Board) FlipFiles() *Board {
+	m := b.Copy()
+	for row := 0; row < Height; row++ {
+		m.board[row] = 0
+	}
+	for row := location.CoordinateType(0); row < Height; row++ {
+		for col := location.CoordinateType(0); col < Width; col++ {
+			data := b.getPieceData(location.NewLocation(row, col))
+			if data == 0 {
+				continue
+			}
+			to := location.NewLocation(row, Width-1-col)
+			m.board[row] |= uint32(data) << getBitOffset(to)
+		}
+	}
+	for c := color.Color(0); c < color.NumColors; c++ {
+		m.SetFlag(FlagLeftRookMoved, c, b.GetFlag(FlagRightRookMoved, c))
+		m.SetFlag(FlagRightRookMoved, c, b.GetFlag(FlagLeftRookMoved, c))
+		row, col := b.KingLocations[c].Get()
+		m.KingLocations[c] = location.NewLocation(row, Width-1-col)
+	}
+	m.PreviousPositions = nil
+	m.PreviousPositionsSeen = 0
+	m.CurrentPositionRepeats = 0
+	return m
+}
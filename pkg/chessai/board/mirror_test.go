@@ -0,0 +1,54 @@
+package board
+
+import (
+	"testing"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/piece"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoard_MirrorStartingPosition(t *testing.T) {
+	b := Board{}
+	b.ResetDefault()
+	// The starting position is its own color mirror.
+	assert.True(t, b.Equals(b.Mirror()))
+}
+
+func TestBoard_MirrorSwapsColorsAndRanks(t *testing.T) {
+	b := Board{}
+	b.ResetDefault()
+	MakeMove(&location.Move{Start: location.NewLocation(1, 3), End: location.NewLocation(3, 3)}, &b)
+	b.SetFlag(FlagKingMoved, color.White, true)
+
+	m := b.Mirror()
+	pt, c, ok := m.GetPieceTypeColor(location.NewLocation(4, 3))
+	assert.True(t, ok)
+	assert.Equal(t, piece.PawnType, pt)
+	assert.Equal(t, color.Black, c)
+	assert.True(t, m.IsEmpty(location.NewLocation(6, 3)))
+	assert.True(t, m.GetFlag(FlagKingMoved, color.Black))
+	assert.False(t, m.GetFlag(FlagKingMoved, color.White))
+	assert.Equal(t, location.NewLocation(7, 3), m.KingLocations[color.Black])
+	assert.Equal(t, location.NewLocation(0, 3), m.KingLocations[color.White])
+
+	assert.True(t, b.Equals(m.Mirror()))
+}
+
+func TestBoard_FlipFiles(t *testing.T) {
+	b := Board{}
+	b.ResetDefault()
+	b.SetFlag(FlagLeftRookMoved, color.White, true)
+
+	f := b.FlipFiles()
+	pt, c, ok := f.GetPieceTypeColor(location.NewLocation(0, 4))
+	assert.True(t, ok)
+	assert.Equal(t, piece.KingType, pt)
+	assert.Equal(t, color.White, c)
+	assert.Equal(t, location.NewLocation(0, 4), f.KingLocations[color.White])
+	assert.True(t, f.GetFlag(FlagRightRookMoved, color.White))
+	assert.False(t, f.GetFlag(FlagLeftRookMoved, color.White))
+
+	assert.True(t, b.Equals(f.FlipFiles()))
+}
@@ -0,0 +1,241 @@
+package board
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/piece"
+)
+
+// The engine's coordinate system is mirrored along the file axis relative to
+// standard chess notation:
+//   engine col 0  → file h   (engine col 7 → file a)
+//   engine row 0  → rank 1   (engine row 7 → rank 8)
+//
+// So: file = 'a' + (7 - col),  rank = row + 1
+
+var pieceChar = map[byte]byte{
+	piece.PawnType:   piece.PawnChar,
+	piece.KnightType: piece.KnightChar,
+	piece.BishopType: piece.BishopChar,
+	piece.RookType:   piece.RookChar,
+	piece.QueenType:  piece.QueenChar,
+	piece.KingType:   piece.KingChar,
+}
+
+// SquareName returns the square l in algebraic notation, e.g. "e4".
+func SquareName(l location.Location) string {
+	return string([]byte{fileName(l), rankName(l)})
+}
+
+func fileName(l location.Location) byte {
+	return 'a' + byte(7-l.GetCol())
+}
+
+func rankName(l location.Location) byte {
+	return '1' + byte(l.GetRow())
+}
+
+// MoveToUCI converts a move to UCI notation (e.g. "e2e4" or "e7e8q").
+func MoveToUCI(m location.Move) string {
+	uci := SquareName(m.Start) + SquareName(m.End)
+	if hasPromo, promoType := m.End.GetPawnPromotion(); hasPromo {
+		uci += string([]byte{pieceChar[promoType] + ('a' - 'A')})
+	}
+	return uci
+}
+
+// FEN converts the board to a standard FEN string. lastMove is used to derive
+// the en passant target square (may be nil). activeColor is the side to move
+// next. fullMove is the fullmove number (1-based).
+func (b *Board) FEN(activeColor color.Color, lastMove *LastMove, fullMove int) string {
+	var sb strings.Builder
+
+	// 1. Piece placement — FEN iterates rank 8..1 (engine row 7..0),
+	//    each rank from file a..h (engine col 7..0).
+	for row := 7; row >= 0; row-- {
+		empty := 0
+		for col := 7; col >= 0; col-- {
+			l := location.NewLocation(location.CoordinateType(row), location.CoordinateType(col))
+			p := b.GetPiece(l)
+			if p == nil {
+				empty++
+			} else {
+				if empty > 0 {
+					sb.WriteByte(byte('0' + empty))
+					empty = 0
+				}
+				ch := pieceChar[p.GetPieceType()]
+				if p.GetColor() == color.Black {
+					ch += 'a' - 'A' // lowercase for black
+				}
+				sb.WriteByte(ch)
+			}
+		}
+		if empty > 0 {
+			sb.WriteByte(byte('0' + empty))
+		}
+		if row > 0 {
+			sb.WriteByte('/')
+		}
+	}
+
+	// 2. Active color
+	if activeColor == color.White {
+		sb.WriteString(" w ")
+	} else {
+		sb.WriteString(" b ")
+	}
+
+	// 3. Castling rights.
+	// Left = h-file direction (kingside), Right = a-file direction (queenside).
+	castling := ""
+	if !b.GetFlag(FlagKingMoved, color.White) && !b.GetFlag(FlagCastled, color.White) {
+		if !b.GetFlag(FlagLeftRookMoved, color.White) {
+			castling += "K"
+		}
+		if !b.GetFlag(FlagRightRookMoved, color.White) {
+			castling += "Q"
+		}
+	}
+	if !b.GetFlag(FlagKingMoved, color.Black) && !b.GetFlag(FlagCastled, color.Black) {
+		if !b.GetFlag(FlagLeftRookMoved, color.Black) {
+			castling += "k"
+		}
+		if !b.GetFlag(FlagRightRookMoved, color.Black) {
+			castling += "q"
+		}
+	}
+	if castling == "" {
+		castling = "-"
+	}
+	sb.WriteString(castling)
+
+	// 4. En passant target square.
+	sb.WriteString(" " + enPassantSquare(lastMove))
+
+	// 5. Halfmove clock and fullmove number.
+	sb.WriteString(fmt.Sprintf(" %d %d", b.MovesSinceNoDraw, fullMove))
+
+	return sb.String()
+}
+
+// enPassantSquare returns the FEN en passant target square from the last move,
+// or "-" if not applicable.
+func enPassantSquare(lm *LastMove) string {
+	if lm == nil {
+		return "-"
+	}
+	p := *lm.Piece
+	if p == nil || p.GetPieceType() != piece.PawnType {
+		return "-"
+	}
+	startRow := int(lm.Move.Start.GetRow())
+	endRow := int(lm.Move.End.GetRow())
+
+	// Two-square pawn advance
+	diff := endRow - startRow
+	if diff != 2 && diff != -2 {
+		return "-"
+	}
+	target := location.NewLocation(location.CoordinateType((startRow+endRow)/2), lm.Move.End.GetCol())
+	return SquareName(target)
+}
+
+// SAN returns m, a legal move of side's, in standard algebraic notation
+// (e.g. "Nbd7", "exd6", "e8=Q+" or "O-O#"). It must be called before m is
+// made on b.
+func (b *Board) SAN(m location.Move, side color.Color, previousMove *LastMove) string {
+	moved := b.GetPiece(m.Start)
+	if moved == nil {
+		return MoveToUCI(m)
+	}
+	pieceType := moved.GetPieceType()
+	colDiff := int(m.End.GetCol()) - int(m.Start.GetCol())
+
+	var san string
+	switch {
+	case pieceType == piece.KingType && (colDiff == 2 || colDiff == -2):
+		// The h-file is column 0, so kingside castling moves the king down
+		if colDiff < 0 {
+			san = "O-O"
+		} else {
+			san = "O-O-O"
+		}
+	case pieceType == piece.PawnType:
+		if colDiff != 0 {
+			// Captures, including en passant, name the pawn's file
+			san = string([]byte{fileName(m.Start), 'x'})
+		}
+		san += SquareName(m.End)
+		if hasPromo, promoType := m.End.GetPawnPromotion(); hasPromo {
+			san += "=" + string([]byte{pieceChar[promoType]})
+		}
+	default:
+		san = string([]byte{pieceChar[pieceType]}) + b.sanDisambiguation(m, pieceType, side, previousMove)
+		if b.GetPiece(m.End) != nil {
+			san += "x"
+		}
+		san += SquareName(m.End)
+	}
+
+	after := b.Copy()
+	lastMove := MakeMove(&m, after)
+	if after.IsInCheckmate(side^1, lastMove) {
+		san += "#"
+	} else if after.IsKingInCheck(side ^ 1) {
+		san += "+"
+	}
+	return san
+}
+
+// sanDisambiguation returns the file, rank or square of m's start that tells
+// it apart from moves of side's other pieces of pieceType to the same square.
+func (b *Board) sanDisambiguation(m location.Move, pieceType byte, side color.Color, previousMove *LastMove) string {
+	ambiguous, sameFile, sameRank := false, false, false
+	for _, other := range *b.GetAllMovesUnShuffled(side, previousMove) {
+		if !other.End.Equals(m.End) || other.Start.Equals(m.Start) {
+			continue
+		}
+		if p := b.GetPiece(other.Start); p == nil || p.GetPieceType() != pieceType {
+			continue
+		}
+		ambiguous = true
+		sameFile = sameFile || other.Start.GetCol() == m.Start.GetCol()
+		sameRank = sameRank || other.Start.GetRow() == m.Start.GetRow()
+	}
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return string([]byte{fileName(m.Start)})
+	case !sameRank:
+		return string([]byte{rankName(m.Start)})
+	}
+	return SquareName(m.Start)
+}
+
+// LineUCI returns the moves of line in UCI notation.
+func LineUCI(line []location.Move) []string {
+	uci := make([]string, len(line))
+	for i, m := range line {
+		uci[i] = MoveToUCI(m)
+	}
+	return uci
+}
+
+// LineSAN returns line, a sequence of legal moves starting with side's, in
+// SAN. b is left unchanged.
+func (b *Board) LineSAN(line []location.Move, side color.Color, previousMove *LastMove) []string {
+	b = b.Copy()
+	san := make([]string, len(line))
+	for i := range line {
+		m := line[i]
+		san[i] = b.SAN(m, side, previousMove)
+		previousMove = MakeMove(&m, b)
+		side ^= 1
+	}
+	return san
+}
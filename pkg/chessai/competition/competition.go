@@ -47,6 +47,7 @@ func (c *Competition) RunCompetition() {
 		// randomize color of players each game
 		c.randomizePlayers()
 		g := game.NewGame(c.players[c.whiteIndex], c.players[c.blackIndex])
+		g.AIClaimsDraws = true
 		if !config.Get().LogDebug {
 			c.disablePrinting(g)
 		}
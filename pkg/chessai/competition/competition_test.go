@@ -6,9 +6,14 @@ import (
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/game"
 	"github.com/stretchr/testify/assert"
 	"math/rand"
+	"os"
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	os.Exit(config.RunWithTempLogs(m))
+}
+
 func TestCompetition_RecordOutcome(t *testing.T) {
 	comp := NewCompetition()
 	comp.whiteIndex = 0
@@ -43,6 +43,7 @@ func RunTournament(gamesPerMatchup int, thinkTime time.Duration, spectatorCh cha
 		{name: "NegaScout", algorithm: &ai.NegaScout{}, elo: startingElo},
 		{name: "Jamboree", algorithm: &ai.Jamboree{}, elo: startingElo},
 		{name: "LazySMP", algorithm: &ai.LazySMP{}, elo: startingElo},
+		{name: "MCTS", algorithm: &ai.MCTS{}, elo: startingElo},
 		{name: "Random", algorithm: &ai.Random{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}, elo: startingElo},
 	}
 
@@ -198,6 +199,7 @@ func playGame(white, black *tournamentPlayer, thinkTime time.Duration, spectator
 
 	g := game.NewGame(wp, bp)
 	g.PrintInfo = false
+	g.AIClaimsDraws = true
 
 	if spectatorCh != nil {
 		// Send full board once at game start so spectators see the initial position.
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"testing"
 )
 
 type Configuration struct {
@@ -36,6 +37,9 @@ type Configuration struct {
 	// passed pawns + space + initiative) instead of the engine's native eval. If both
 	// MaterialOnlyEval and this are set, MaterialOnlyEval takes precedence.
 	StockfishClassicEval bool
+	// LogDir is the directory the debug and performance logs are written to,
+	// the working directory if empty.
+	LogDir string
 }
 
 const FilePath = "conf.json"
@@ -91,3 +95,21 @@ func Get() *Configuration {
 	}
 	return cfg
 }
+
+// LogPath is the path of the log file name in LogDir.
+func LogPath(name string) string {
+	return filepath.Join(Get().LogDir, name)
+}
+
+// RunWithTempLogs runs the tests of m with the logs written to a new
+// temporary directory, so that test runs leave none behind, and returns m's
+// exit code for TestMain.
+func RunWithTempLogs(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "chessai-logs")
+	if err != nil {
+		log.Panic("cannot create log directory ", err)
+	}
+	defer os.RemoveAll(dir)
+	Get().LogDir = dir
+	return m.Run()
+}
@@ -0,0 +1,147 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Vadman97/GolangChessAI/pkg/api"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/player"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
+	"log"
+)
+
+// PlayerAction is something a human player does other than moving. The game
+// handles it on the player's turn, so an action requested while the AI
+// thinks waits for the AI's move.
+type PlayerAction byte
+
+const (
+	// Takeback undoes the player's last move and the reply to it.
+	Takeback = PlayerAction(iota)
+	Resign
+	// OfferDraw asks the AI for a draw, which it accepts when its last search
+	// scored the position at most DecisionPolicy.DrawAcceptScore.
+	OfferDraw
+	// ClaimDraw claims a threefold repetition or fifty-move draw.
+	ClaimDraw
+)
+
+// position is the state of the game before a move, kept so that moves can be
+// taken back.
+type position struct {
+	board        *board.Board
+	previousMove *board.LastMove
+	turn         color.Color
+	fullMove     int
+}
+
+// RequestAction queues action for the game loop, from any goroutine. It
+// returns an error when the game is over or an action is already waiting to
+// be handled.
+func (g *Game) RequestAction(action PlayerAction) error {
+	if g.isOver() {
+		return errors.New("the game is over")
+	}
+	select {
+	case g.actions <- action:
+		return nil
+	default:
+		return errors.New("an action is already waiting to be handled")
+	}
+}
+
+// RejectAction tells the player why their action was refused.
+func (g *Game) RejectAction(reason string) {
+	log.Printf("Rejected player action: %s", reason)
+	g.SocketBroadcast <- api.CreateChessMessage(api.InvalidAction, api.InvalidActionJSON{Error: reason})
+}
+
+// handleAction carries out action for the human playing c. Resigning, a draw
+// claim and an accepted draw offer end the game.
+func (g *Game) handleAction(c color.Color, action PlayerAction) {
+	switch action {
+	case Takeback:
+		if err := g.takeBack(c); err != nil {
+			g.RejectAction(err.Error())
+			return
+		}
+		g.GamePrinter <- fmt.Sprintf("%s took back their last move\n", color.Names[c])
+		g.Publish()
+		availableMovesJSON := api.CreateAvailableMovesJSON(g.CurrentBoard.GetAllAvailableMoves(c))
+		g.SocketBroadcast <- api.CreateChessMessage(api.GameState, g.GetJSON())
+		g.SocketBroadcast <- api.CreateChessMessage(api.GameStatus, g.GetStatusJSON())
+		g.SocketBroadcast <- api.CreateChessMessage(api.AvailablePlayerMoves, availableMovesJSON)
+	case Resign:
+		if c == color.White {
+			g.GameStatus = WhiteResigned
+		} else {
+			g.GameStatus = BlackResigned
+		}
+	case OfferDraw:
+		accepted := g.acceptsDraw(c ^ 1)
+		g.SocketBroadcast <- api.CreateChessMessage(api.DrawOfferAnswer, api.DrawOfferAnswerJSON{Accepted: accepted})
+		if accepted {
+			g.GameStatus = DrawAgreed
+		}
+	case ClaimDraw:
+		claim := g.ClaimableDraw()
+		if claim == Active {
+			g.RejectAction("there is no draw to claim")
+			return
+		}
+		g.GameStatus = claim
+	default:
+		g.RejectAction(fmt.Sprintf("unknown action %d", action))
+	}
+}
+
+// acceptsDraw decides a draw offer to the AI playing c from its last search
+// score. Human players and a book move decline.
+func (g *Game) acceptsDraw(c color.Color) bool {
+	aiPlayer, isAI := g.Players[c].(*ai.AIPlayer)
+	if !isAI || !aiPlayer.HasLastScore {
+		return false
+	}
+	return aiPlayer.LastScore <= game_config.Get().DecisionPolicy.DrawAcceptScore
+}
+
+// recordPosition saves the state before the next move for takeBack.
+func (g *Game) recordPosition() {
+	g.positions = append(g.positions, position{
+		board:        g.CurrentBoard.Copy(),
+		previousMove: g.PreviousMove,
+		turn:         g.CurrentTurnColor,
+		fullMove:     g.FullMove,
+	})
+}
+
+// takeBack restores the position before c's last move, undoing that move and
+// the reply to it.
+func (g *Game) takeBack(c color.Color) error {
+	n := len(g.positions)
+	if n < 2 || g.positions[n-2].turn != c {
+		return errors.New("there is no move to take back")
+	}
+	restored := g.positions[n-2]
+	g.positions = g.positions[:n-2]
+	g.CurrentBoard = restored.board
+	g.PreviousMove = restored.previousMove
+	g.CurrentTurnColor = restored.turn
+	g.FullMove = restored.fullMove
+	g.MovesPlayed -= 2
+	g.History = g.History[:len(g.History)-2]
+
+	for side := color.White; side < color.NumColors; side++ {
+		switch p := g.Players[side].(type) {
+		case *player.HumanPlayer:
+			p.TurnCount--
+		case *ai.AIPlayer:
+			p.TurnCount--
+			// The last score belongs to a position no longer on the board
+			p.HasLastScore = false
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package game
+
+import (
+	"fmt"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	"time"
+)
+
+// DelayType is how a clock's delay spares the player's time.
+type DelayType byte
+
+const (
+	NoDelay = DelayType(iota)
+	// SimpleDelay starts the clock only after the delay has passed.
+	SimpleDelay
+	// BronsteinDelay gives back the time a move took, up to the delay.
+	BronsteinDelay
+)
+
+// DelayTypeNames are the names game_conf.json and the web API use.
+var DelayTypeNames = map[string]DelayType{
+	"":          NoDelay,
+	"none":      NoDelay,
+	"simple":    SimpleDelay,
+	"bronstein": BronsteinDelay,
+}
+
+// Clock is one side's chess clock. It runs while the side thinks and gains
+// Increment after each move made in time.
+type Clock struct {
+	Remaining time.Duration
+	Increment time.Duration
+	Delay     time.Duration
+	DelayType DelayType
+	// started is when the running move began, zero while stopped.
+	started time.Time
+}
+
+// NewClock returns a stopped clock with initial time on it.
+func NewClock(initial, increment, delay time.Duration, delayType DelayType) *Clock {
+	if delayType == NoDelay {
+		delay = 0
+	}
+	return &Clock{Remaining: initial, Increment: increment, Delay: delay, DelayType: delayType}
+}
+
+// NewClockFromConfig returns the clock cfg describes, or nil for untimed
+// games.
+func NewClockFromConfig(cfg *game_config.Clock) (*Clock, error) {
+	delayType, known := DelayTypeNames[cfg.DelayType]
+	if !known {
+		return nil, fmt.Errorf("unknown delay type %q, expected simple or bronstein", cfg.DelayType)
+	}
+	if cfg.InitialSec <= 0 {
+		return nil, nil
+	}
+	return NewClock(time.Duration(cfg.InitialSec)*time.Second, time.Duration(cfg.IncrementSec)*time.Second,
+		time.Duration(cfg.DelaySec)*time.Second, delayType), nil
+}
+
+// start runs the clock from now.
+func (c *Clock) start(now time.Time) {
+	c.started = now
+}
+
+// stop charges the time since start and adds the increment. It reports
+// whether the flag fell, in which case the clock shows no time and gets no
+// increment.
+func (c *Clock) stop(now time.Time) (flagged bool) {
+	if c.started.IsZero() {
+		return false
+	}
+	spent := now.Sub(c.started)
+	c.Remaining = c.RemainingAt(now)
+	c.started = time.Time{}
+	if c.Remaining <= 0 {
+		c.Remaining = 0
+		return true
+	}
+	if c.DelayType == BronsteinDelay {
+		if spent > c.Delay {
+			spent = c.Delay
+		}
+		c.Remaining += spent
+	}
+	c.Remaining += c.Increment
+	return false
+}
+
+// RemainingAt returns the time left at now, before any Bronstein delay or
+// increment is given back for the running move.
+func (c *Clock) RemainingAt(now time.Time) time.Duration {
+	if c.started.IsZero() {
+		return c.Remaining
+	}
+	spent := now.Sub(c.started)
+	if c.DelayType == SimpleDelay {
+		if spent -= c.Delay; spent < 0 {
+			spent = 0
+		}
+	}
+	return c.Remaining - spent
+}
+
+// flagFalls returns when the running clock runs out.
+func (c *Clock) flagFalls() time.Time {
+	flag := c.started.Add(c.Remaining)
+	if c.DelayType == SimpleDelay {
+		flag = flag.Add(c.Delay)
+	}
+	return flag
+}
+
+// SetClocks gives both sides a copy of clock before the game starts. A nil
+// clock makes the game untimed.
+func (g *Game) SetClocks(clock *Clock) {
+	for c := color.White; c < color.NumColors; c++ {
+		if clock == nil {
+			g.Clocks[c] = nil
+		} else {
+			copied := *clock
+			g.Clocks[c] = &copied
+		}
+	}
+}
+
+// IsTimed reports whether the game is played on the clock.
+func (g *Game) IsTimed() bool {
+	return g.Clocks[color.White] != nil
+}
+
+// timeout is the status of c losing on time.
+func timeout(c color.Color) byte {
+	if c == color.White {
+		return WhiteTimeout
+	}
+	return BlackTimeout
+}
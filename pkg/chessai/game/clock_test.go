@@ -0,0 +1,92 @@
+package game
+
+import (
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/player"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestClockDelays(t *testing.T) {
+	start := time.Now()
+	for _, test := range []struct {
+		name      string
+		delayType DelayType
+		spent     time.Duration
+		remaining time.Duration
+	}{
+		// 60s, 2s increment, 3s delay
+		{"increment only", NoDelay, 10 * time.Second, 52 * time.Second},
+		{"simple delay", SimpleDelay, 10 * time.Second, 55 * time.Second},
+		{"simple delay not used up", SimpleDelay, 1 * time.Second, 62 * time.Second},
+		{"bronstein delay", BronsteinDelay, 10 * time.Second, 55 * time.Second},
+		{"bronstein delay not used up", BronsteinDelay, 1 * time.Second, 62 * time.Second},
+	} {
+		clock := NewClock(time.Minute, 2*time.Second, 3*time.Second, test.delayType)
+		clock.start(start)
+		assert.False(t, clock.stop(start.Add(test.spent)), test.name)
+		assert.Equal(t, test.remaining, clock.Remaining, test.name)
+	}
+
+	clock := NewClock(time.Second, 2*time.Second, 0, NoDelay)
+	clock.start(start)
+	assert.Equal(t, start.Add(time.Second), clock.flagFalls())
+	assert.True(t, clock.stop(start.Add(2*time.Second)), "the flag fell")
+	assert.Equal(t, time.Duration(0), clock.Remaining, "no increment after the flag fell")
+
+	clock = NewClock(time.Second, 0, 3*time.Second, SimpleDelay)
+	clock.start(start)
+	assert.Equal(t, start.Add(4*time.Second), clock.flagFalls(), "the clock starts after the delay")
+	assert.Equal(t, time.Second, clock.RemainingAt(start.Add(2*time.Second)))
+}
+
+func TestHumanLosesOnTime(t *testing.T) {
+	g := NewGame(player.NewHumanPlayer(color.White), ai.NewAIPlayer(color.Black, &ai.Random{}))
+	g.SetClocks(NewClock(50*time.Millisecond, 0, 0, NoDelay))
+	assert.True(t, g.IsTimed())
+	assert.False(t, g.Clocks[color.White] == g.Clocks[color.Black], "each side has its own clock")
+
+	played := make(chan bool)
+	go func() { played <- g.PlayTurn() }()
+	select {
+	case active := <-played:
+		assert.False(t, active)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the flag did not fall")
+	}
+	assert.Equal(t, WhiteTimeout, g.GameStatus)
+	assert.True(t, g.GetGameOutcome().Win[color.Black])
+	assert.Equal(t, int64(0), g.GetStatusJSON().Clocks["White"])
+	assert.Equal(t, int64(50), g.GetStatusJSON().Clocks["Black"])
+}
+
+func TestSnapshotShowsTheRunningClock(t *testing.T) {
+	g := NewGame(player.NewHumanPlayer(color.White), ai.NewAIPlayer(color.Black, &ai.Random{}))
+	defer g.Stop()
+	g.SetClocks(NewClock(time.Minute, 0, 0, NoDelay))
+	assert.Nil(t, g.Snapshot(), "nothing is published before the game is shared")
+
+	g.Clocks[color.White].start(time.Now().Add(-10 * time.Second))
+	g.Publish()
+	snapshot := g.Snapshot()
+	assert.InDelta(t, 50000, snapshot.State.Clocks["White"], 1000)
+	assert.Equal(t, int64(60000), snapshot.Status.Clocks["Black"])
+	if assert.NotNil(t, snapshot.AvailableMoves, "it is the human's turn") {
+		moves := 0
+		for _, pieceMoves := range snapshot.AvailableMoves.AvailableMoves {
+			moves += len(pieceMoves)
+		}
+		assert.Equal(t, 20, moves)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, g.Snapshot().Status.Clocks["White"] < snapshot.Status.Clocks["White"], "the clock runs after publishing")
+
+	g.PlayTurnMove(parseTestUCIMove("e2e4"))
+	snapshot = g.Snapshot()
+	assert.Len(t, snapshot.History.Moves, 1)
+	assert.Equal(t, "Black", snapshot.State.CurrentTurnColor)
+	assert.Nil(t, snapshot.AvailableMoves, "it is the AI's turn")
+}
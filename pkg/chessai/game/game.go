@@ -16,6 +16,8 @@ import (
 	"log"
 	"math"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -40,6 +42,27 @@ type Game struct {
 	SocketBroadcast    chan api.ChessMessage
 	GamePrinter        chan string
 	quit               chan bool
+	actions            chan PlayerAction
+	// Clocks are the sides' chess clocks, nil for untimed games, which end
+	// after TimeLimit instead. See SetClocks.
+	Clocks map[color.Color]*Clock
+	// AIClaimsDraws lets AI players claim a threefold repetition or fifty-move
+	// draw on their turn when, as for a draw offer, they are not better. The
+	// Lichess bot leaves it off and claims through Lichess with its move.
+	AIClaimsDraws bool
+	// History records every move played, oldest first, and FullMove is the
+	// fullmove number of the position on the board, as in FEN.
+	History  []MoveRecord
+	FullMove int
+	// positions holds the state before each move played, oldest first.
+	positions []position
+	// over is closed once the game is over, which stops memoryThread and
+	// printThread and refuses further actions. They never read GameStatus,
+	// which only the goroutine playing the game may touch.
+	over     chan struct{}
+	overOnce *sync.Once
+	// snapshot holds the *Snapshot last published.
+	snapshot atomic.Value
 }
 
 type Outcome struct {
@@ -48,11 +71,11 @@ type Outcome struct {
 }
 
 func (g *Game) GetGameOutcome() (outcome Outcome) {
-	if g.GameStatus == WhiteWin {
+	if g.GameStatus == WhiteWin || g.GameStatus == BlackResigned || g.GameStatus == BlackTimeout {
 		outcome.Win[color.White] = true
-	} else if g.GameStatus == BlackWin {
+	} else if g.GameStatus == BlackWin || g.GameStatus == WhiteResigned || g.GameStatus == WhiteTimeout {
 		outcome.Win[color.Black] = true
-	} else if g.GameStatus == Stalemate || g.GameStatus == FiftyMoveDraw || g.GameStatus == RepeatedActionThreeTimeDraw || g.GameStatus == InsufficientMaterialDraw {
+	} else if IsDraw(g.GameStatus) {
 		outcome.Tie = true
 	}
 	return
@@ -68,7 +91,7 @@ func (g *Game) PlayTurn() bool {
 		return false
 	}
 
-	if g.MovesPlayed%2 == 0 && g.GetTotalPlayTime() > g.TimeLimit {
+	if !g.IsTimed() && g.MovesPlayed%2 == 0 && g.GetTotalPlayTime() > g.TimeLimit {
 		g.GamePrinter <- fmt.Sprintf("Aborting - out of time\n")
 		g.GameStatus = Aborted
 	} else {
@@ -77,49 +100,53 @@ func (g *Game) PlayTurn() bool {
 		quitTimeUpdates := make(chan bool)
 		// print think time for slow players, regardless of what's going on
 		go g.periodicUpdates(quitTimeUpdates, start)
+		clock := g.Clocks[g.CurrentTurnColor]
+		if clock != nil {
+			clock.start(start)
+		}
+		g.Publish()
 
 		var move *location.Move
 		switch p := g.Players[g.CurrentTurnColor].(type) {
 		case *player.HumanPlayer:
-			move = p.WaitForMove()
+			var quit bool
+			if move, quit = g.waitForHumanMove(p, clock); quit {
+				close(quitTimeUpdates)
+				g.Stop()
+				g.Publish()
+				return false
+			}
 		case *ai.AIPlayer:
+			if claim := g.ClaimableDraw(); claim != Active && g.AIClaimsDraws && g.acceptsDraw(g.CurrentTurnColor) {
+				g.GameStatus = claim
+				break
+			}
+			if clock != nil {
+				// Budget the AI's own clock, as the Lichess bot does; a delay
+				// saves time much like an increment.
+				p.MaxThinkTime = ai.ThinkTimeForClock(clock.Remaining, clock.Increment+clock.Delay, p.TurnCount)
+			}
 			move = p.GetBestMove(g.CurrentBoard, g.PreviousMove, g.PerformanceLogger)
 		}
 
-		g.PreviousMove = g.Players[g.CurrentTurnColor].MakeMove(g.CurrentBoard, move)
-
-		// quit time updates (never prints if quick player)
-		close(quitTimeUpdates)
-		g.UpdateTime(start)
-		g.CurrentTurnColor ^= 1
-		g.MovesPlayed++
-
-		// check that the next player is not in checkmate
-		// priority goes to win, then stalemate, then fifty move draw
-		if g.CurrentBoard.IsInCheckmate(g.CurrentTurnColor, g.PreviousMove) {
-			if g.CurrentTurnColor == color.White {
-				g.GameStatus = BlackWin
-			} else {
-				g.GameStatus = WhiteWin
-			}
-		} else if g.CurrentBoard.IsStalemate(g.CurrentTurnColor, g.PreviousMove) {
-			g.GameStatus = Stalemate
-		} else if g.CurrentBoard.MovesSinceNoDraw >= 100 {
-			// 50 Move Rule (50 moves per color)
-			g.GameStatus = FiftyMoveDraw
-		} else if g.CurrentBoard.CurrentPositionRepeats >= 2 {
-			// 3-position repetition
-			g.GameStatus = RepeatedActionThreeTimeDraw
-		} else if g.CurrentBoard.IsInsufficientMaterial() {
-			g.GameStatus = InsufficientMaterialDraw
+		if move != nil && clock != nil && clock.stop(time.Now()) {
+			// The move came after the flag fell
+			g.GameStatus = timeout(g.CurrentTurnColor)
+			move = nil
 		}
-
-		if g.GameStatus == Active {
-			g.GamePrinter <- fmt.Sprintf("Move #%d by %s\n", g.MovesPlayed, color.Names[g.CurrentTurnColor^1])
-		} else {
+		if move == nil {
+			// The player resigned, claimed or agreed to a draw, or ran out of
+			// time
+			if clock != nil {
+				clock.stop(time.Now())
+			}
+			close(quitTimeUpdates)
 			g.GamePrinter <- fmt.Sprintf("Game Over! Result is: %s\n", StatusStrings[g.GameStatus])
+		} else {
+			g.playMove(move, quitTimeUpdates, start)
 		}
 	}
+	g.Publish()
 	g.GamePrinter <- fmt.Sprintln(g)
 	if g.GameStatus != Active {
 		var aiPlayers []*ai.AIPlayer
@@ -130,6 +157,7 @@ func (g *Game) PlayTurn() bool {
 		}
 
 		g.PerformanceLogger.CompletePerformanceLog(aiPlayers)
+		g.end()
 		g.printThread()
 	}
 	// perform player cleanup
@@ -141,47 +169,194 @@ func (g *Game) PlayTurn() bool {
 	return g.GameStatus == Active
 }
 
-// Stop marks the game as no longer active so its background goroutines
-// (memoryThread, printThread) fall out of their `for g.GameStatus == Active`
-// loops and exit. Without this, abandoning a game (e.g. between Lichess games,
-// where the game often ends server-side before we detect a terminal status
-// locally) leaks both goroutines — and each keeps a reference to the Game, its
+// playMove plays the move of the side to move and updates the game status.
+func (g *Game) playMove(move *location.Move, quitTimeUpdates chan bool, start time.Time) {
+	_, isAI := g.Players[g.CurrentTurnColor].(*ai.AIPlayer)
+	g.makeMove(move, time.Since(start), isAI)
+
+	// quit time updates (never prints if quick player)
+	close(quitTimeUpdates)
+	g.UpdateTime(start)
+	g.CurrentTurnColor ^= 1
+	g.MovesPlayed++
+
+	g.GameStatus = StatusAfterMove(g.CurrentBoard, g.CurrentTurnColor, g.PreviousMove)
+
+	if g.GameStatus == Active {
+		g.GamePrinter <- fmt.Sprintf("Move #%d by %s\n", g.MovesPlayed, color.Names[g.CurrentTurnColor^1])
+	} else {
+		g.GamePrinter <- fmt.Sprintf("Game Over! Result is: %s\n", StatusStrings[g.GameStatus])
+	}
+}
+
+// waitForHumanMove waits for a legal move by p, handling the actions p
+// requests meanwhile. It returns a nil move when an action or p's running
+// clock ended the game, and quit when the game was quit.
+func (g *Game) waitForHumanMove(p *player.HumanPlayer, clock *Clock) (move *location.Move, quit bool) {
+	var flag <-chan time.Time
+	if clock != nil {
+		timer := time.NewTimer(time.Until(clock.flagFalls()))
+		defer timer.Stop()
+		flag = timer.C
+	}
+	for {
+		select {
+		case move = <-p.Move:
+			if g.IsLegalMove(move) {
+				return move, false
+			}
+			g.RejectMove(fmt.Sprintf("%s is not a legal move", move.UCIString()))
+		case action := <-g.actions:
+			g.handleAction(p.PlayerColor, action)
+			if g.GameStatus != Active {
+				return nil, false
+			}
+		case <-flag:
+			g.GameStatus = timeout(p.PlayerColor)
+			return nil, false
+		case <-g.quit:
+			return nil, true
+		}
+	}
+}
+
+// Stop marks the game as no longer active and ends its background goroutines
+// (memoryThread, printThread). Without this, abandoning a game (e.g. between
+// Lichess games, where the game often ends server-side before we detect a
+// terminal status locally) leaks both goroutines — and each keeps a reference to the Game, its
 // players, and their transposition/evaluation caches, so memory is never freed
 // across back-to-back games. Safe to call multiple times.
 func (g *Game) Stop() {
 	if g.GameStatus == Active {
 		g.GameStatus = Aborted
 	}
+	g.end()
+}
+
+// end stops memoryThread and printThread. Safe to call multiple times.
+func (g *Game) end() {
+	g.overOnce.Do(func() { close(g.over) })
+}
+
+// isOver reports whether the game has ended or been quit.
+func (g *Game) isOver() bool {
+	select {
+	case <-g.over:
+		return true
+	case <-g.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsLegalMove reports whether move is legal for the side to move, including
+// the promotion piece.
+func (g *Game) IsLegalMove(move *location.Move) bool {
+	for _, legal := range *g.CurrentBoard.GetAllMovesUnShuffled(g.CurrentTurnColor, g.PreviousMove) {
+		if legal.Equals(move) {
+			return true
+		}
+	}
+	return false
+}
+
+// RejectMove tells the player why their move was refused and resends the
+// game state so that their board shows the position again.
+func (g *Game) RejectMove(reason string) {
+	log.Printf("Rejected player move: %s", reason)
+	g.SocketBroadcast <- api.CreateChessMessage(api.InvalidMove, api.InvalidMoveJSON{Error: reason})
+	g.SocketBroadcast <- api.CreateChessMessage(api.GameState, g.GetJSON())
+}
+
+// Quit makes Loop return, even while it waits for a human move, and Loop
+// aborts the game on its way out. Quit only signals the loop, so it may be
+// called from any goroutine, but at most once.
+func (g *Game) Quit() {
+	close(g.quit)
 }
 
 // PlayTurnMove applies an externally provided move (e.g. from a lichess opponent)
-// and updates game state, without consulting the current player's AI.
+// and updates game state, without consulting the current player's AI. Its
+// think time in History is the time since the previous move.
 func (g *Game) PlayTurnMove(move *location.Move) {
+	g.playTurnMove(move, false)
+}
+
+// PlayAdoptedMove is PlayTurnMove for a move found by a search the current
+// AI player has taken over (see ai.AIPlayer.AdoptSearch), such as a ponder
+// hit's; History records that search with the move.
+func (g *Game) PlayAdoptedMove(move *location.Move) {
+	g.playTurnMove(move, true)
+}
+
+func (g *Game) playTurnMove(move *location.Move, searched bool) {
 	if g.GameStatus != Active {
 		return
 	}
-	g.PreviousMove = g.Players[g.CurrentTurnColor].MakeMove(g.CurrentBoard, move)
+	var thinkTime time.Duration
+	if n := len(g.History); n > 0 {
+		thinkTime = time.Since(g.History[n-1].PlayedAt)
+	}
+	g.makeMove(move, thinkTime, searched)
 	g.CurrentTurnColor ^= 1
 	g.MovesPlayed++
+	g.GameStatus = StatusAfterMove(g.CurrentBoard, g.CurrentTurnColor, g.PreviousMove)
+	g.Publish()
+}
 
-	if g.CurrentBoard.IsInCheckmate(g.CurrentTurnColor, g.PreviousMove) {
-		if g.CurrentTurnColor == color.White {
-			g.GameStatus = BlackWin
-		} else {
-			g.GameStatus = WhiteWin
+// StatusAfterMove returns the status of the game once a move has left b with
+// nextSide to move: checkmate and stalemate, then the draws FIDE rules make
+// automatic — fivefold repetition, the seventy-five-move rule and dead
+// positions. Threefold repetition and the fifty-move rule only end the game
+// when claimed; see ClaimableDraw.
+func StatusAfterMove(b *board.Board, nextSide color.Color, previousMove *board.LastMove) byte {
+	if b.IsInCheckmate(nextSide, previousMove) {
+		if nextSide == color.White {
+			return BlackWin
 		}
-	} else if g.CurrentBoard.IsStalemate(g.CurrentTurnColor, g.PreviousMove) {
-		g.GameStatus = Stalemate
-	} else if g.CurrentBoard.MovesSinceNoDraw >= 100 {
-		g.GameStatus = FiftyMoveDraw
-	} else if g.CurrentBoard.CurrentPositionRepeats >= 2 {
-		g.GameStatus = RepeatedActionThreeTimeDraw
-	} else if g.CurrentBoard.IsInsufficientMaterial() {
-		g.GameStatus = InsufficientMaterialDraw
+		return WhiteWin
+	}
+	if b.IsStalemate(nextSide, previousMove) {
+		return Stalemate
+	}
+	if b.CurrentPositionRepeats >= 4 {
+		return FivefoldRepetitionDraw
 	}
+	if b.MovesSinceNoDraw >= 150 {
+		// 75 moves per color
+		return SeventyFiveMoveDraw
+	}
+	if b.IsInsufficientMaterial() {
+		return InsufficientMaterialDraw
+	}
+	if b.IsDeadPosition() {
+		return DeadPositionDraw
+	}
+	return Active
+}
+
+// ClaimableDraw returns the draw the side to move can claim: FiftyMoveDraw
+// after 50 moves per color without a capture or pawn move, or
+// RepeatedActionThreeTimeDraw when the position has occurred three times. It
+// returns Active when there is no draw to claim.
+func (g *Game) ClaimableDraw() byte {
+	return BoardClaimableDraw(g.CurrentBoard)
+}
+
+// BoardClaimableDraw is ClaimableDraw for the position on b.
+func BoardClaimableDraw(b *board.Board) byte {
+	if b.MovesSinceNoDraw >= 100 {
+		return FiftyMoveDraw
+	}
+	if b.CurrentPositionRepeats >= 2 {
+		return RepeatedActionThreeTimeDraw
+	}
+	return Active
 }
 
 func (g *Game) Loop(client *websocket.Conn) {
+	g.Publish()
 	g.SocketBroadcast <- api.CreateChessMessage(api.GameState, g.GetJSON())
 
 	var gameActive = true
@@ -202,10 +377,13 @@ func (g *Game) Loop(client *websocket.Conn) {
 
 		select {
 		case <-g.quit:
-			break
+			g.Stop()
+			g.Publish()
+			return
 		default:
 			log.Printf("Turn %d", i)
 			CurrentTurnColor := g.CurrentTurnColor
+			movesPlayed := g.MovesPlayed
 
 			// Send Pre-Move Information
 			if CurrentTurnColor == humanColor {
@@ -215,13 +393,19 @@ func (g *Game) Loop(client *websocket.Conn) {
 
 			gameActive = g.PlayTurn()
 
-			// Send Post-Move Information
-			if CurrentTurnColor != humanColor {
+			// Send Post-Move Information, unless the AI ended the game instead
+			if CurrentTurnColor != humanColor && g.MovesPlayed != movesPlayed {
 				lastMoveJSON := api.CreateMoveJSON(g.PreviousMove)
 				g.SocketBroadcast <- api.CreateChessMessage(api.AIMove, lastMoveJSON)
 			}
 
-			if g.MovesPlayed > 20 && game_config.Get().AIScaleThinkTimeWithHuman {
+			// Spectators see every move, and late joiners the position after it
+			if g.MovesPlayed != movesPlayed {
+				g.SocketBroadcast <- api.CreateChessMessage(api.MovePlayed, api.CreateMoveJSON(g.PreviousMove))
+				g.SocketBroadcast <- api.CreateChessMessage(api.SpectatorSync, g.GetJSON())
+			}
+
+			if !g.IsTimed() && g.MovesPlayed > 20 && game_config.Get().AIScaleThinkTimeWithHuman {
 				humanThinkSec := math.Round(g.AverageMoveTime[humanColor])
 				humanThinkTime := time.Duration(humanThinkSec) * time.Second
 				// only allow AI to go up to certain think time
@@ -321,6 +505,7 @@ func (g *Game) GetJSON() *api.GameStateJSON {
 		GameStatus:       StatusStrings[g.GameStatus],
 		MoveLimit:        g.MoveLimit,
 		TimeLimit:        g.TimeLimit,
+		Clocks:           g.clocksJSON(),
 	}
 
 	// Set Human Color (if there is one)
@@ -354,16 +539,43 @@ func (g *Game) GetJSON() *api.GameStateJSON {
 }
 
 func (g *Game) GetStatusJSON() *api.GameStatusJSON {
-	return &api.GameStatusJSON{
+	statusJSON := &api.GameStatusJSON{
 		CurrentTurnColor: color.Names[g.CurrentTurnColor],
 		MovesPlayed:      g.MovesPlayed,
 		GameStatus:       StatusStrings[g.GameStatus],
 		KingInCheck:      g.CurrentBoard.IsKingInCheck(g.CurrentTurnColor),
+		Clocks:           g.clocksJSON(),
+	}
+	if claim := g.ClaimableDraw(); claim != Active && g.GameStatus == Active {
+		statusJSON.ClaimableDraw = StatusStrings[claim]
+	}
+	return statusJSON
+}
+
+// clocksJSON returns each side's remaining time in milliseconds, or nil for
+// untimed games.
+func (g *Game) clocksJSON() map[string]int64 {
+	if !g.IsTimed() {
+		return nil
+	}
+	return clockTimesJSON(g.Clocks, time.Now())
+}
+
+// clockTimesJSON returns the remaining time in milliseconds on clocks at now.
+func clockTimesJSON(clocks map[color.Color]*Clock, now time.Time) map[string]int64 {
+	times := make(map[string]int64)
+	for c := color.White; c < color.NumColors; c++ {
+		remaining := clocks[c].RemainingAt(now)
+		if remaining < 0 {
+			remaining = 0
+		}
+		times[color.Names[c]] = int64(remaining / time.Millisecond)
 	}
+	return times
 }
 
 func (g *Game) memoryThread() {
-	for g.GameStatus == Active {
+	for !g.isOver() {
 		if util.GetMemoryUsed() > g.CacheMemoryLimit {
 			g.GamePrinter <- fmt.Sprintf("Clearing caches\n")
 			g.ClearCaches(false)
@@ -376,7 +588,7 @@ func (g *Game) memoryThread() {
 }
 
 func (g *Game) printThread() {
-	for g.GameStatus == Active {
+	for !g.isOver() {
 		util.PrintPrinter(g.GamePrinter, g.PrintInfo)
 	}
 	util.PrintPrinter(g.GamePrinter, g.PrintInfo)
@@ -419,16 +631,21 @@ func NewGame(whitePlayer, blackPlayer player.Player) *Game {
 			color.Black: 0,
 		},
 		MovesPlayed:       0,
+		FullMove:          1,
 		PreviousMove:      nil,
 		GameStatus:        Active,
 		CacheMemoryLimit:  config.Get().MemoryLimit,
 		MoveLimit:         math.MaxInt32,
 		TimeLimit:         math.MaxInt64,
+		Clocks:            map[byte]*Clock{},
 		PerformanceLogger: performanceLogger,
 		PrintInfo:         true,
 		SocketBroadcast:   make(chan api.ChessMessage, 10),
 		GamePrinter:       make(chan string, 100000),
 		quit:              make(chan bool),
+		over:              make(chan struct{}),
+		overOnce:          &sync.Once{},
+		actions:           make(chan PlayerAction, 1),
 	}
 	g.CurrentBoard.ResetDefault()
 	go g.memoryThread()
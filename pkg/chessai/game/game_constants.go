@@ -9,18 +9,36 @@ const (
 	RepeatedActionThreeTimeDraw = byte(iota)
 	InsufficientMaterialDraw    = byte(iota)
 	Aborted                     = byte(iota)
+	WhiteResigned               = byte(iota)
+	BlackResigned               = byte(iota)
+	DrawAgreed                  = byte(iota)
+	WhiteTimeout                = byte(iota)
+	BlackTimeout                = byte(iota)
+	FivefoldRepetitionDraw      = byte(iota)
+	SeventyFiveMoveDraw         = byte(iota)
+	DeadPositionDraw            = byte(iota)
 )
 
-// IsClaimableDraw reports whether a status is a draw the side to move can claim
-// while still having legal moves available (threefold repetition / fifty-move
-// rule). Lichess does not end the game automatically on these — a bot that simply
-// stops moving when it detects one locally will flag and lose. Unlike checkmate /
-// stalemate (no legal move) or insufficient material (auto-drawn by the server),
-// these require the bot to keep playing and claim the draw via its move.
+// IsClaimableDraw reports whether a status is a draw that a player has to claim
+// (threefold repetition / fifty-move rule): the game goes on until they do, and
+// fivefold repetition or the seventy-five-move rule ends it automatically. See
+// Game.ClaimableDraw. Lichess does not end the game on these either — a bot
+// that simply stops moving when it detects one locally will flag and lose, so
+// it keeps playing and claims the draw via its move.
 func IsClaimableDraw(status byte) bool {
 	return status == RepeatedActionThreeTimeDraw || status == FiftyMoveDraw
 }
 
+// IsDraw reports whether a status is a drawn game.
+func IsDraw(status byte) bool {
+	switch status {
+	case Stalemate, FiftyMoveDraw, RepeatedActionThreeTimeDraw, InsufficientMaterialDraw, DrawAgreed,
+		FivefoldRepetitionDraw, SeventyFiveMoveDraw, DeadPositionDraw:
+		return true
+	}
+	return false
+}
+
 var StatusStrings = [...]string{
 	"Active",
 	"White Win",
@@ -30,4 +48,12 @@ var StatusStrings = [...]string{
 	"Repeated Action Three Times Draw",
 	"Insufficient Material Draw",
 	"Aborted",
+	"White Resigned",
+	"Black Resigned",
+	"Draw Agreed",
+	"White Timeout",
+	"Black Timeout",
+	"Fivefold Repetition Draw",
+	"Seventy-Five Move Draw",
+	"Dead Position Draw",
 }
@@ -2,9 +2,11 @@ package game
 
 import (
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/config"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
 	"github.com/stretchr/testify/assert"
+	"os"
 	"runtime"
 	"strings"
 	"testing"
@@ -13,6 +15,10 @@ import (
 
 // TODO(Vadim) test game.go
 
+func TestMain(m *testing.M) {
+	os.Exit(config.RunWithTempLogs(m))
+}
+
 func TestNewGame(t *testing.T) {
 	p1 := ai.NewAIPlayer(color.Black, &ai.MiniMax{})
 	p1.MaxSearchDepth = 100
@@ -28,10 +34,10 @@ func TestNewGame(t *testing.T) {
 }
 
 // TestGameStopTerminatesBackgroundGoroutines guards against the cross-game memory
-// leak: each NewGame spawns memoryThread + printThread that loop while the game is
-// Active. Abandoning a game (as the Lichess server does between back-to-back games)
+// leak: each NewGame spawns memoryThread + printThread that loop until the game is
+// over. Abandoning a game (as the Lichess server does between back-to-back games)
 // must let those goroutines exit, or they pin the Game and its players' caches in
-// memory forever. Stop() flips the status so they terminate.
+// memory forever. Stop() ends them.
 func TestGameStopTerminatesBackgroundGoroutines(t *testing.T) {
 	baseline := runtime.NumGoroutine()
 	const n = 25
@@ -79,6 +85,85 @@ func TestGameDoesNotDrawOnCumulativeDifferentRepetitions(t *testing.T) {
 	assert.Equal(t, 1, g.CurrentBoard.CurrentPositionRepeats)
 }
 
+func TestThreefoldIsClaimedAndFivefoldEndsTheGame(t *testing.T) {
+	shuffle := strings.Split("g1f3 g8f6 f3g1 f6g8", " ")
+	newShuffleGame := func(cycles int) *Game {
+		g := NewGame(
+			ai.NewAIPlayer(color.White, &ai.Random{}),
+			ai.NewAIPlayer(color.Black, &ai.Random{}),
+		)
+		for i := 0; i < cycles; i++ {
+			for _, move := range shuffle {
+				g.PlayTurnMove(parseTestUCIMove(move))
+			}
+		}
+		return g
+	}
+
+	// The starting position occurs for the third time
+	g := newShuffleGame(2)
+	assert.Equal(t, Active, g.GameStatus, "a threefold repetition does not end the game")
+	assert.Equal(t, RepeatedActionThreeTimeDraw, g.ClaimableDraw())
+	assert.Equal(t, StatusStrings[RepeatedActionThreeTimeDraw], g.GetStatusJSON().ClaimableDraw)
+	g.handleAction(color.White, ClaimDraw)
+	assert.Equal(t, RepeatedActionThreeTimeDraw, g.GameStatus)
+	assert.True(t, g.GetGameOutcome().Tie)
+	g.Stop()
+
+	// The AI claims when its policy would accept a draw offer
+	g = newShuffleGame(2)
+	g.AIClaimsDraws = true
+	white := g.Players[color.White].(*ai.AIPlayer)
+	white.HasLastScore, white.LastScore = true, 0
+	assert.False(t, g.PlayTurn())
+	assert.Equal(t, RepeatedActionThreeTimeDraw, g.GameStatus)
+	g.Stop()
+
+	// and the fifth time ends the game without a claim
+	g = newShuffleGame(4)
+	assert.Equal(t, FivefoldRepetitionDraw, g.GameStatus)
+	g.Stop()
+}
+
+func TestHistoryRecordsEveryPly(t *testing.T) {
+	black := ai.NewAIPlayer(color.Black, &ai.Random{})
+	// Search rather than play a book move or an opening preference
+	black.Opening, black.TurnCount = ai.OpeningNone, 2
+	g := NewGame(ai.NewAIPlayer(color.White, &ai.Random{}), black)
+	defer g.Stop()
+
+	g.PlayTurnMove(parseTestUCIMove("e2e4"))
+	assert.True(t, g.PlayTurn())
+	assert.Len(t, g.History, 2)
+
+	first := g.History[0]
+	assert.Equal(t, uint(1), first.Ply)
+	assert.Equal(t, color.White, first.Color)
+	assert.Equal(t, "e2e4", first.UCI)
+	assert.Equal(t, "e4", first.SAN)
+	assert.Equal(t, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", first.FEN)
+	assert.Nil(t, first.Eval, "an external move has no search behind it")
+
+	second := g.History[1]
+	assert.Equal(t, color.Black, second.Color)
+	assert.Equal(t, "2", strings.Fields(second.FEN)[5], "the fullmove number goes up after Black moves")
+	if assert.NotNil(t, second.Eval) {
+		assert.True(t, second.Eval.PV[0].Equals(&second.Move), "the principal variation starts with the move")
+	}
+
+	historyJSON := g.GetHistoryJSON()
+	assert.Equal(t, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", historyJSON.StartFEN)
+	assert.Len(t, historyJSON.Moves, 2)
+	assert.Equal(t, "Black", historyJSON.Moves[1].Color)
+	assert.Equal(t, historyJSON.Moves[1].UCI, historyJSON.Moves[1].Eval.PV[0])
+	assert.Nil(t, historyJSON.Moves[1].ClockMs, "untimed games record no clock")
+	assert.Nil(t, g.GetJSON().History, "state updates leave the history out")
+
+	assert.NoError(t, g.takeBack(color.White))
+	assert.Empty(t, g.History)
+	assert.Equal(t, 1, g.FullMove)
+}
+
 func parseTestUCIMove(uci string) *location.Move {
 	sCol := 7 - (uci[0] - 'a')
 	sRow := uci[1] - '0' - 1
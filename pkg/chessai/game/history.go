@@ -0,0 +1,108 @@
+package game
+
+import (
+	"github.com/Vadman97/GolangChessAI/pkg/api"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
+	"time"
+)
+
+// MoveRecord is one ply of the game record.
+type MoveRecord struct {
+	Ply   uint
+	Color color.Color
+	Move  location.Move
+	UCI   string
+	SAN   string
+	// FEN is the position after the move.
+	FEN       string
+	PlayedAt  time.Time
+	ThinkTime time.Duration
+	// Clock is the mover's remaining time after the move; HasClock is false
+	// in untimed games.
+	Clock    time.Duration
+	HasClock bool
+	// Eval is the AI's search behind the move, nil for human and book moves.
+	Eval *Evaluation
+}
+
+// Evaluation is an AI search result: the score from the mover's side, the
+// depth searched and the principal variation, starting with the move itself.
+type Evaluation struct {
+	Score int
+	Depth int
+	PV    []location.Move
+}
+
+// makeMove makes move for the side to move and records it in History. A
+// searched AI move also records the search.
+func (g *Game) makeMove(move *location.Move, thinkTime time.Duration, searched bool) {
+	c := g.CurrentTurnColor
+	record := MoveRecord{
+		Ply:       g.MovesPlayed + 1,
+		Color:     c,
+		Move:      *move,
+		UCI:       board.MoveToUCI(*move),
+		SAN:       g.CurrentBoard.SAN(*move, c, g.PreviousMove),
+		ThinkTime: thinkTime,
+	}
+	if aiPlayer, isAI := g.Players[c].(*ai.AIPlayer); isAI && searched && aiPlayer.HasLastScore {
+		record.Eval = &Evaluation{
+			Score: aiPlayer.LastScore,
+			Depth: aiPlayer.LastSearchDepth,
+			PV:    aiPlayer.LastLine,
+		}
+	}
+
+	g.recordPosition()
+	g.PreviousMove = g.Players[c].MakeMove(g.CurrentBoard, move)
+	if c == color.Black {
+		g.FullMove++
+	}
+	record.FEN = g.CurrentBoard.FEN(c^1, g.PreviousMove, g.FullMove)
+	record.PlayedAt = time.Now()
+	if clock := g.Clocks[c]; clock != nil {
+		record.Clock, record.HasClock = clock.Remaining, true
+	}
+	g.History = append(g.History, record)
+}
+
+// StartFEN returns the position the game started from.
+func (g *Game) StartFEN() string {
+	if len(g.positions) == 0 {
+		return g.CurrentBoard.FEN(g.CurrentTurnColor, g.PreviousMove, g.FullMove)
+	}
+	start := g.positions[0]
+	return start.board.FEN(start.turn, start.previousMove, start.fullMove)
+}
+
+// GetHistoryJSON returns the game record.
+func (g *Game) GetHistoryJSON() *api.GameHistoryJSON {
+	historyJSON := &api.GameHistoryJSON{
+		StartFEN:   g.StartFEN(),
+		Moves:      make([]api.MoveRecordJSON, len(g.History)),
+		GameStatus: StatusStrings[g.GameStatus],
+	}
+	for i, record := range g.History {
+		recordJSON := api.MoveRecordJSON{
+			Ply:         record.Ply,
+			Color:       color.Names[record.Color],
+			UCI:         record.UCI,
+			SAN:         record.SAN,
+			FEN:         record.FEN,
+			PlayedAt:    record.PlayedAt,
+			ThinkTimeMs: int64(record.ThinkTime / time.Millisecond),
+		}
+		if record.HasClock {
+			clockMs := int64(record.Clock / time.Millisecond)
+			recordJSON.ClockMs = &clockMs
+		}
+		if record.Eval != nil {
+			recordJSON.Eval = &api.EvaluationJSON{Score: record.Eval.Score, Depth: record.Eval.Depth, PV: board.LineUCI(record.Eval.PV)}
+		}
+		historyJSON.Moves[i] = recordJSON
+	}
+	return historyJSON
+}
@@ -0,0 +1,64 @@
+package game
+
+import (
+	"github.com/Vadman97/GolangChessAI/pkg/api"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/player"
+	"time"
+)
+
+// Snapshot is a game as the goroutine playing it last published it, for
+// other goroutines to read while it plays on.
+type Snapshot struct {
+	State   *api.GameStateJSON
+	Status  *api.GameStatusJSON
+	History *api.GameHistoryJSON
+	// AvailableMoves are the human's legal moves on their turn, else nil.
+	AvailableMoves *api.AvailableMovesJSON
+	// clocks are copies of the game's clocks, so that State and Status show
+	// the running clock as it is when read.
+	clocks map[color.Color]*Clock
+}
+
+// Publish makes the game's current state what Snapshot returns. The game
+// publishes itself as it is played; call Publish before sharing a game that
+// has not started.
+func (g *Game) Publish() {
+	s := &Snapshot{
+		State:   g.GetJSON(),
+		Status:  g.GetStatusJSON(),
+		History: g.GetHistoryJSON(),
+	}
+	if _, isHuman := g.Players[g.CurrentTurnColor].(*player.HumanPlayer); isHuman && g.GameStatus == Active {
+		availableMoves := api.CreateAvailableMovesJSON(g.CurrentBoard.GetAllAvailableMoves(g.CurrentTurnColor))
+		s.AvailableMoves = &availableMoves
+	}
+	if g.IsTimed() {
+		s.clocks = make(map[color.Color]*Clock)
+		for c, clock := range g.Clocks {
+			copied := *clock
+			s.clocks[c] = &copied
+		}
+	}
+	g.snapshot.Store(s)
+}
+
+// Snapshot returns the game as last published, with the clocks as they are
+// now, or nil if it was never published. It may be called from any
+// goroutine, and the snapshot must not be modified.
+func (g *Game) Snapshot() *Snapshot {
+	s, _ := g.snapshot.Load().(*Snapshot)
+	if s == nil || s.clocks == nil {
+		return s
+	}
+	state, status := *s.State, *s.Status
+	state.Clocks = clockTimesJSON(s.clocks, time.Now())
+	status.Clocks = state.Clocks
+	return &Snapshot{
+		State:          &state,
+		Status:         &status,
+		History:        s.History,
+		AvailableMoves: s.AvailableMoves,
+		clocks:         s.clocks,
+	}
+}
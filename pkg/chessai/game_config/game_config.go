@@ -15,6 +15,267 @@ type GameConfiguration struct {
 	AIMaxSearchDepth          int
 	AIMaxThinkTimeMs          time.Duration
 	AIScaleThinkTimeWithHuman bool
+	// AIMateSolverNodes enables the proof-number mate helper on the AI's root
+	// move with this node budget per solve; 0 (the default) disables it.
+	AIMateSolverNodes int
+	// MoveOverheadMinMs is the least time reserved per Lichess move for the
+	// network; the bot reserves its measured lag when that is higher.
+	MoveOverheadMinMs int
+	// ShutdownGraceSec is how long the Lichess bot keeps playing its game
+	// after SIGTERM or SIGINT before resigning it.
+	ShutdownGraceSec int
+	// ChallengePolicy decides which incoming Lichess challenges the bot
+	// accepts. Fields omitted from game_conf.json keep their defaults.
+	ChallengePolicy *ChallengePolicy
+	// DecisionPolicy decides draw offers, resignation and takebacks in Lichess
+	// games. Fields omitted from game_conf.json keep their defaults.
+	DecisionPolicy *DecisionPolicy
+	// Matchmaking challenges other online bots while the bot is idle. Fields
+	// omitted from game_conf.json keep their defaults.
+	Matchmaking *Matchmaking
+	// Chat greets opponents and answers chat commands in Lichess games.
+	// Fields omitted from game_conf.json keep their defaults.
+	Chat *Chat
+	// Tournament selects the event lichess-tournament mode plays in. Fields
+	// omitted from game_conf.json keep their defaults.
+	Tournament *Tournament
+	// WebGames limits the games the web server hosts at once. Fields omitted
+	// from game_conf.json keep their defaults.
+	WebGames *WebGames
+	// Clock is the default time control of web games. Fields omitted from
+	// game_conf.json keep their defaults.
+	Clock *Clock
+	// Analysis limits the web server's position analysis. Fields omitted
+	// from game_conf.json keep their defaults.
+	Analysis *Analysis
+}
+
+// ChallengePolicy filters incoming Lichess challenges. Zero bounds are
+// unbounded; user names are matched case-insensitively.
+type ChallengePolicy struct {
+	// Clock limits, in seconds. Only real-time (clock) games are ever accepted.
+	MinInitialSec   int
+	MaxInitialSec   int
+	MinIncrementSec int
+	MaxIncrementSec int
+	// Variants lists accepted Lichess variant keys, e.g. "standard".
+	Variants     []string
+	AcceptRated  bool
+	AcceptCasual bool
+	// Opponent rating bounds for the challenge's speed.
+	MinRating    int
+	MaxRating    int
+	AcceptBots   bool
+	AcceptHumans bool
+	// AllowUsers, when non-empty, is the only set of users who may challenge.
+	AllowUsers []string
+	BlockUsers []string
+	// MaxQueue is how many challenges are held while a game is in progress;
+	// they are accepted in order once it ends. Further challenges are declined.
+	MaxQueue int
+}
+
+// DefaultChallengePolicy accepts rated standard clock games from anyone and
+// holds one challenge while busy.
+func DefaultChallengePolicy() *ChallengePolicy {
+	return &ChallengePolicy{
+		Variants:     []string{"standard"},
+		AcceptRated:  true,
+		AcceptBots:   true,
+		AcceptHumans: true,
+		MaxQueue:     1,
+	}
+}
+
+// DecisionPolicy controls the bot's non-move decisions. Scores are centipawns
+// from the bot's side, taken from its last search. Web games use
+// DrawAcceptScore alone to answer the player's draw offers.
+type DecisionPolicy struct {
+	// An opponent's draw offer is accepted when the score is at most
+	// DrawAcceptScore, unless the bot is up DrawRefuseMaterial or more in
+	// material. With less than DrawAcceptLowClockSec on its clock and less time
+	// than the opponent, the bot also accepts up to DrawAcceptLowClockScore.
+	DrawAcceptScore         int
+	DrawRefuseMaterial      int
+	DrawAcceptLowClockSec   int
+	DrawAcceptLowClockScore int
+	// The bot offers a draw once the score has stayed within ±DrawOfferScore
+	// for DrawOfferMoves consecutive moves, from move DrawOfferMinMove on, with
+	// at most DrawOfferMaxPieces pieces left. DrawOfferMoves 0 disables offers.
+	DrawOfferScore     int
+	DrawOfferMoves     int
+	DrawOfferMinMove   int
+	DrawOfferMaxPieces int
+	// The bot resigns after ResignMoves consecutive moves scored at or below
+	// ResignScore. ResignMoves 0 disables resignation.
+	ResignScore int
+	ResignMoves int
+	// AcceptTakebacks grants opponent takeback requests; they are declined
+	// otherwise.
+	AcceptTakebacks bool
+}
+
+// DefaultDecisionPolicy accepts draws when not better, offers them in long
+// dead-equal endgames, resigns after five moves at -10 pawns or worse, and
+// declines takebacks.
+func DefaultDecisionPolicy() *DecisionPolicy {
+	return &DecisionPolicy{
+		DrawAcceptScore:         0,
+		DrawRefuseMaterial:      200,
+		DrawAcceptLowClockSec:   10,
+		DrawAcceptLowClockScore: 100,
+		DrawOfferScore:          15,
+		DrawOfferMoves:          6,
+		DrawOfferMinMove:        40,
+		DrawOfferMaxPieces:      10,
+		ResignScore:             -1000,
+		ResignMoves:             5,
+	}
+}
+
+// TimeControl is a Lichess clock: initial time and increment, in seconds.
+type TimeControl struct {
+	InitialSec   int
+	IncrementSec int
+}
+
+// Matchmaking configures outgoing challenges to online bots. After IdleSec
+// without a game or challenge, the bot challenges an online bot rated within
+// ±RatingWindow of its own rating for the time control, which it picks in
+// turn from TimeControls. An opponent is not challenged again within
+// CooldownMin of the last challenge or game, unanswered challenges are
+// cancelled after ChallengeTimeoutSec, and at most one challenge is sent per
+// MinChallengeIntervalSec. Per-opponent results are kept in ResultsFile.
+type Matchmaking struct {
+	Enabled                 bool
+	IdleSec                 int
+	TimeControls            []TimeControl
+	Rated                   bool
+	RatingWindow            int
+	CooldownMin             int
+	ChallengeTimeoutSec     int
+	MinChallengeIntervalSec int
+	ResultsFile             string
+}
+
+// DefaultMatchmaking is disabled; when enabled it looks for rated 3+2 and
+// 5+3 games against bots within 300 points after a minute idle.
+func DefaultMatchmaking() *Matchmaking {
+	return &Matchmaking{
+		IdleSec:                 60,
+		TimeControls:            []TimeControl{{InitialSec: 180, IncrementSec: 2}, {InitialSec: 300, IncrementSec: 3}},
+		Rated:                   true,
+		RatingWindow:            300,
+		CooldownMin:             60,
+		ChallengeTimeoutSec:     30,
+		MinChallengeIntervalSec: 30,
+		ResultsFile:             "matchmaking_results.json",
+	}
+}
+
+// Chat configures the Lichess game chat. When Enabled, the bot posts Greeting
+// when a game starts and Goodbye when it ends (an empty message is not sent),
+// and answers !eval, !name, !hardware, !stats and !help in the player and
+// spectator rooms. Each room gets at most one answer per MinIntervalSec.
+type Chat struct {
+	Enabled        bool
+	Greeting       string
+	Goodbye        string
+	MinIntervalSec int
+}
+
+// DefaultChat is disabled; when enabled it greets, says goodbye and answers
+// one command per room every 5 seconds.
+func DefaultChat() *Chat {
+	return &Chat{
+		Greeting:       "Hi, I'm GolangChessAI. Good luck! Type !help for commands.",
+		Goodbye:        "Thanks for the game!",
+		MinIntervalSec: 5,
+	}
+}
+
+// Tournament configures lichess-tournament mode. The bot joins the arena
+// ArenaID or the Swiss SwissID; with neither, it joins the next arena open
+// to bots whose clock ChallengePolicy accepts. It plays until the event
+// finishes, rejoining an arena whenever Lichess has paused it, and checks the
+// event every PollSec. In arenas it berserks when Berserk is set, the initial
+// clock is at least BerserkMinInitialSec and the increment at most
+// BerserkMaxIncrementSec: berserk halves the clock and drops the increment.
+type Tournament struct {
+	ArenaID                string
+	SwissID                string
+	Berserk                bool
+	BerserkMinInitialSec   int
+	BerserkMaxIncrementSec int
+	PollSec                int
+}
+
+// DefaultTournament joins the next bot arena and never berserks; when
+// berserk is enabled it does so from 3+0 up to 2 seconds increment.
+func DefaultTournament() *Tournament {
+	return &Tournament{
+		BerserkMinInitialSec:   180,
+		BerserkMaxIncrementSec: 2,
+		PollSec:                10,
+	}
+}
+
+// WebGames configures the games the web server hosts. At most MaxGames are
+// in progress at once; 0 allows one per two CPU cores, as every AI search
+// uses all cores and games slow each other down. A game nobody is connected
+// to is ended after IdleTimeoutSec. A player who disconnects mid-game and
+// does not reconnect within ReconnectGraceSec forfeits; 0 never forfeits.
+type WebGames struct {
+	MaxGames          int
+	IdleTimeoutSec    int
+	ReconnectGraceSec int
+}
+
+// DefaultWebGames sizes the cap by CPU, forfeits a player gone for a minute
+// and ends games left idle for 3 minutes.
+func DefaultWebGames() *WebGames {
+	return &WebGames{IdleTimeoutSec: 180, ReconnectGraceSec: 60}
+}
+
+// Clock is a chess clock: InitialSec per side, IncrementSec added after each
+// move and DelaySec of each move spared the DelayType way, "simple" or
+// "bronstein". InitialSec 0 plays untimed, limited by SecondsToPlay instead.
+type Clock struct {
+	InitialSec   int
+	IncrementSec int
+	DelaySec     int
+	DelayType    string
+}
+
+// DefaultClock is untimed.
+func DefaultClock() *Clock {
+	return &Clock{}
+}
+
+// Analysis configures the position analysis the web server runs on request.
+// At most MaxConcurrent analyses search at once, as each competes for the
+// CPUs with the games; up to MaxQueued more wait their turn and further
+// requests are refused. An analysis searches for DefaultThinkTimeMs unless
+// the request sets its own limits, and never longer than MaxThinkTimeMs. It
+// shows at most MaxMultiPV lines.
+type Analysis struct {
+	MaxConcurrent      int
+	MaxQueued          int
+	DefaultThinkTimeMs int
+	MaxThinkTimeMs     int
+	MaxMultiPV         int
+}
+
+// DefaultAnalysis runs one analysis at a time with 8 waiting, each for a
+// second by default and 10 seconds at most, and shows up to 5 lines.
+func DefaultAnalysis() *Analysis {
+	return &Analysis{
+		MaxConcurrent:      1,
+		MaxQueued:          8,
+		DefaultThinkTimeMs: 1000,
+		MaxThinkTimeMs:     10000,
+		MaxMultiPV:         5,
+	}
 }
 
 const FilePath = "game_conf.json"
@@ -60,7 +321,19 @@ func Get() *GameConfiguration {
 		}
 		defer func() { _ = file.Close() }()
 		decoder := json.NewDecoder(file)
-		configuration := GameConfiguration{}
+		// Decoding into the default policies keeps defaults for omitted fields.
+		configuration := GameConfiguration{
+			MoveOverheadMinMs: 100,
+			ShutdownGraceSec:  180,
+			ChallengePolicy:   DefaultChallengePolicy(),
+			DecisionPolicy:    DefaultDecisionPolicy(),
+			Matchmaking:       DefaultMatchmaking(),
+			Chat:              DefaultChat(),
+			Tournament:        DefaultTournament(),
+			WebGames:          DefaultWebGames(),
+			Clock:             DefaultClock(),
+			Analysis:          DefaultAnalysis(),
+		}
 		err := decoder.Decode(&configuration)
 		if err != nil {
 			log.Panic("configuration parsing failed ", err)
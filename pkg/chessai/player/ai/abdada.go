@@ -46,6 +46,15 @@ const (
 	// 150cp margin, a discarded move can also never be a "close second" that
 	// verification would need an exact score for.
 	rootScoutMargin = 200
+
+	// Singular extensions: when the TT move of a deep node scores well above
+	// every alternative searched at half depth with the TT move excluded, the
+	// node has a single good reply and the TT move is extended by one ply. If
+	// the alternatives instead also clear the singular bound and that bound is
+	// already >= beta, several moves fail high and the node is cut (multi-cut).
+	singularMinDepth     = 6
+	singularTTDepthSlack = 3 // TT entry may be this many plies shallower than the node
+	singularMarginPerPly = 3 // singular bound is ttScore - margin*depth (cp)
 )
 
 // squareIdx converts a location to a flat [0,63] index for history/killer tables.
@@ -81,7 +90,16 @@ type ABDADA struct {
 	DisableLMR         bool
 	DisableFutility    bool
 	DisableRazoring    bool
-	heuristicMu        sync.RWMutex
+	DisableSingular    bool
+	DisableProbCut     bool
+
+	// ProbCutMargin is added to beta for the ProbCut bound (0 selects
+	// DefaultProbCutMargin); only captures with SEE above ProbCutSEEThreshold
+	// are tried.
+	ProbCutMargin       int
+	ProbCutSEEThreshold int
+
+	heuristicMu sync.RWMutex
 	// killers[depth%maxKillerDepth][0..1]: last two quiet moves causing beta cutoffs at this depth.
 	killers [maxKillerDepth][2]location.Move
 	// history[from][to]: accumulated depth^2 bonuses for quiet moves that caused cutoffs.
@@ -274,6 +292,10 @@ func (ab *ABDADA) ABDADA(root *board.Board, depth, alpha, beta int, exclusivePro
 	const ghiGuardEnabled = false
 	allowTTCutoffs := !ghiGuardEnabled || (!inCheck && !parentInCheck)
 
+	// Peek for a singular-extension candidate before ttRead: ttRead claims
+	// shallower entries for this node by resetting them to Unset.
+	singular, singularOK := ab.singularCandidate(root, currentPlayer, depth, ply)
+
 	searchAlpha, searchBeta := alpha, beta
 	ttAnswer := ab.ttRead(root, currentPlayer, uint16(depth), alpha, beta, exclusiveProbe, allowTTCutoffs)
 	movesArr := root.GetAllMoves(currentPlayer, previousMove)
@@ -331,6 +353,28 @@ func (ab *ABDADA) ABDADA(root *board.Board, depth, alpha, beta int, exclusivePro
 		}
 	}
 
+	// ProbCut (see probcut.go), at null-window nodes only. Skipped at the root
+	// for the same reason as null move pruning, and when in check, where
+	// evasions must all be searched.
+	if !ab.DisableProbCut && !inCheck && depth >= probCutMinDepth && ply > 0 && beta-alpha == 1 {
+		if pcBeta, ok := probCutBeta(beta, ab.ProbCutMargin); ok {
+			for _, move := range probCutCaptures(root, *movesArr, currentPlayer, ab.ProbCutSEEThreshold) {
+				if ab.player.isAborted() || ab.isKilled() {
+					break
+				}
+				child, pm := ab.player.applyMove(root, &move)
+				if -ab.player.Quiesce(child, -pcBeta, -pcBeta+1, currentPlayer^1, pm) < pcBeta {
+					continue
+				}
+				value := ab.ABDADA(child, depth-probCutReduction, -pcBeta, -pcBeta+1, false, currentPlayer^1, pm, true, ply+1, extensions, false)
+				if -value.Score >= pcBeta && !ab.player.isAborted() && !ab.isKilled() {
+					atomic.AddUint64(&ab.player.Metrics.MovesPrunedAB, uint64(len(*movesArr)))
+					return ScoredMove{Score: beta, Move: move, PathDependentDraw: value.PathDependentDraw}
+				}
+			}
+		}
+	}
+
 	// Futility pruning + razoring: compute static eval once for frontier nodes.
 	// Futility: skip quiet moves where standPat + margin can't reach alpha.
 	// Razoring: if standPat + margin < alpha even before any moves, drop to qsearch.
@@ -356,6 +400,23 @@ func (ab *ABDADA) ABDADA(root *board.Board, depth, alpha, beta int, exclusivePro
 	killerPair := ab.killerPair(ply)
 	orderedMoves := orderMoves(*movesArr, ttAnswer.bestMove, killerPair, ab, root, previousMove)
 
+	// Singular extension / multi-cut (see singularMinDepth). Skipped for
+	// single-reply nodes, which the one-reply extension already covers.
+	singularExtend := false
+	if singularOK && len(orderedMoves) > 1 &&
+		singular.Move.Start.Equals(ttAnswer.bestMove.Start) && singular.Move.End.Equals(ttAnswer.bestMove.End) {
+		singularBeta := singular.Score - singularMarginPerPly*depth
+		excluded := ab.singularSearch(root, orderedMoves, singular.Move, depth, singularBeta, currentPlayer, ply, extensions, inCheck)
+		if !ab.player.isAborted() && !ab.isKilled() {
+			if excluded.Score < singularBeta {
+				singularExtend = extensions > 0
+			} else if singularBeta >= beta {
+				atomic.AddUint64(&ab.player.Metrics.MovesPrunedAB, uint64(len(orderedMoves)))
+				return ScoredMove{Score: singularBeta, Move: singular.Move, PathDependentDraw: excluded.PathDependentDraw}
+			}
+		}
+	}
+
 	iteration := 0
 	allDone := false
 	for iteration < 2 && alpha < beta && !allDone {
@@ -455,7 +516,12 @@ func (ab *ABDADA) ABDADA(root *board.Board, depth, alpha, beta int, exclusivePro
 						value = lmr
 					}
 				} else {
-					value = ab.ABDADA(child, depth-1, -beta, -util.MaxScore(alpha, best.Score), exclusiveProbe, currentPlayer^1, pm, true, ply+1, extensions, inCheck)
+					childDepth, childExtensions := depth-1, extensions
+					if singularExtend && isTTMove {
+						childDepth++
+						childExtensions--
+					}
+					value = ab.ABDADA(child, childDepth, -beta, -util.MaxScore(alpha, best.Score), exclusiveProbe, currentPlayer^1, pm, true, ply+1, childExtensions, inCheck)
 					value.Score = -value.Score
 					value.Move = move
 				}
@@ -493,6 +559,62 @@ func (ab *ABDADA) ABDADA(root *board.Board, depth, alpha, beta int, exclusivePro
 	return best
 }
 
+// singularCandidate returns the TT move and its score when the TT entry for
+// root is a fresh, non-mate lower bound or exact score from a search at most
+// singularTTDepthSlack plies shallower than depth.
+func (ab *ABDADA) singularCandidate(root *board.Board, currentPlayer color.Color, depth, ply int) (ScoredMove, bool) {
+	if ab.DisableSingular || !ab.player.TranspositionTableEnabled || ply == 0 || depth < singularMinDepth {
+		return ScoredMove{}, false
+	}
+	h := root.Hash()
+	e, ok := ab.player.transpositionTable.Read(&h, currentPlayer)
+	if !ok {
+		return ScoredMove{}, false
+	}
+	entry := e.(*transposition_table.TranspositionTableEntryABDADA)
+	entry.Lock.Lock()
+	defer entry.Lock.Unlock()
+	if entry.Generation < atomic.LoadUint32(&ab.player.ttGeneration) ||
+		(entry.EntryType != transposition_table.LowerBound && entry.EntryType != transposition_table.TrueScore) ||
+		int(entry.Depth)+singularTTDepthSlack < depth ||
+		entry.BestMove.Start.Equals(entry.BestMove.End) {
+		return ScoredMove{}, false
+	}
+	score := DenormalizeMateScore(entry.Score, depth)
+	if score >= WinScore || score <= LossScore {
+		return ScoredMove{}, false
+	}
+	return ScoredMove{Move: entry.BestMove, Score: score}, true
+}
+
+// singularSearch is the excluded-move verification search: every move but
+// excluded is searched at half depth against the null window just below
+// singularBeta. It stops at the first move reaching singularBeta and never
+// touches root's own TT entry, which belongs to the full search.
+func (ab *ABDADA) singularSearch(root *board.Board, moves []location.Move, excluded location.Move, depth, singularBeta int, currentPlayer color.Color, ply, extensions int, inCheck bool) ScoredMove {
+	best := ScoredMove{Score: NegInf}
+	for _, move := range moves {
+		if move.Start.Equals(excluded.Start) && move.End.Equals(excluded.End) {
+			continue
+		}
+		if ab.player.isAborted() || ab.isKilled() {
+			break
+		}
+		child, pm := ab.player.applyMove(root, &move)
+		value := ab.ABDADA(child, (depth-1)/2, -singularBeta, -(singularBeta - 1), false, currentPlayer^1, pm, true, ply+1, extensions, inCheck)
+		value.Score = -value.Score
+		value.Move = move
+		best.PathDependentDraw = best.PathDependentDraw || value.PathDependentDraw
+		if value.Score > best.Score {
+			best.Score, best.Move = value.Score, value.Move
+			if best.Score >= singularBeta {
+				break
+			}
+		}
+	}
+	return best
+}
+
 func (ab *ABDADA) getBestMove(b *board.Board, depth, alpha, beta int, previousMove *board.LastMove) ScoredMove {
 	ab.player.setAbort(false)
 	originalAlpha := alpha
@@ -898,6 +1020,11 @@ func (ab *ABDADA) newWorkerAB() *ABDADA {
 		DisableLMR:      ab.DisableLMR,
 		DisableFutility: ab.DisableFutility,
 		DisableRazoring: ab.DisableRazoring,
+		DisableSingular: ab.DisableSingular,
+		DisableProbCut:  ab.DisableProbCut,
+
+		ProbCutMargin:       ab.ProbCutMargin,
+		ProbCutSEEThreshold: ab.ProbCutSEEThreshold,
 	}
 	w.player = ab.player.NewRootWorkerPlayer()
 	w.abortPlayer = ab.player
@@ -1059,24 +1186,20 @@ func (ab *ABDADA) iterativeABDADA(b *board.Board, previousMove *board.LastMove)
 	// won't finish before the hard abort and we'd just throw away the partial.
 	// We only push past the soft target — up to the hard ceiling — when the
 	// position is unstable (best move changing or score dropping), where the
-	// extra depth is most likely to change the move we play.
-	hardLimit := ab.player.MaxThinkTime
-	var softStable, softUnstable time.Duration
-	if hardLimit > 0 {
-		softStable = hardLimit / 2        // 50% rule: quiet, settled positions
-		softUnstable = hardLimit * 9 / 10 // extend toward the hard ceiling when unstable
-	}
+	// extra depth is most likely to change the move we play. The bounds are
+	// re-read every iteration because a ponder hit (see PonderHit) replaces
+	// them mid-search.
 	unstable := false
 
 	for ab.currentSearchDepth = iterativeIncrement; ab.currentSearchDepth <= ab.player.MaxSearchDepth; ab.currentSearchDepth += iterativeIncrement {
 		// Soft-bound check: decide whether to begin THIS iteration. Always run at
 		// least the first iteration so we never return a zero-move.
-		if hardLimit > 0 && ab.currentSearchDepth > iterativeIncrement {
-			limit := softStable
+		if elapsed, hardLimit := ab.player.thinkClock(start); hardLimit > 0 && ab.currentSearchDepth > iterativeIncrement {
+			limit := hardLimit / 2 // 50% rule: quiet, settled positions
 			if unstable {
-				limit = softUnstable
+				limit = hardLimit * 9 / 10 // extend toward the hard ceiling when unstable
 			}
-			if elapsed := time.Since(start); elapsed >= limit {
+			if elapsed >= limit {
 				ab.player.printer <- fmt.Sprintf("%s soft stop after depth %d (elapsed %s >= %s, unstable=%v)\n",
 					ab.GetName(), ab.player.LastSearchDepth, elapsed, limit, unstable)
 				break
@@ -1102,6 +1225,7 @@ func (ab *ABDADA) iterativeABDADA(b *board.Board, previousMove *board.LastMove)
 			// Feed the next iteration's soft-bound decision: was this depth stable?
 			unstable = searchUnstable(prevForStability, best)
 			ab.player.printer <- fmt.Sprintf("Best D:%d M:%s score:%d\n", ab.player.LastSearchDepth, best.Move, best.Score)
+			ab.player.reportSearchInfo(b, previousMove, &best, start)
 		} else {
 			// Use the partial result if we haven't found any valid move yet.
 			// Without this, a timeout on the very first IDA iteration leaves
@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/transposition_table"
+)
+
+// rxd4 is Rd1xd4 in engine coordinates (col 0 is the h-file).
+var rxd4 = location.Move{
+	Start: location.NewLocation(0, 4),
+	End:   location.NewLocation(3, 4),
+}
+
+func newSingularTestAB(t *testing.T) (*ABDADA, *board.Board) {
+	t.Helper()
+	// White wins the queen with Rxd4; every other move leaves White a queen
+	// for a rook down.
+	b := boardFromFENPlacement(t, "4k3/8/8/8/3q4/8/8/3RK3")
+	p := NewAIPlayer(color.White, &ABDADA{})
+	p.TranspositionTableEnabled = true
+	p.PrintInfo = false
+	p.Debug = false
+	return &ABDADA{player: p, NumThreads: 1}, b
+}
+
+func storeSingularTTEntry(ab *ABDADA, b *board.Board, depth uint16, entryType byte, score int) {
+	h := b.Hash()
+	ab.player.transpositionTable.Store(&h, color.White, &transposition_table.TranspositionTableEntryABDADA{
+		Depth:     depth,
+		EntryType: entryType,
+		Score:     score,
+		BestMove:  rxd4,
+	})
+}
+
+func TestABDADASingularCandidateRequiresUsableTTEntry(t *testing.T) {
+	ab, b := newSingularTestAB(t)
+	storeSingularTTEntry(ab, b, singularMinDepth-singularTTDepthSlack, transposition_table.LowerBound, 500)
+
+	got, ok := ab.singularCandidate(b, color.White, singularMinDepth, 1)
+	if !ok {
+		t.Fatal("expected a singular candidate from a lower-bound TT entry")
+	}
+	if !got.Move.Start.Equals(rxd4.Start) || !got.Move.End.Equals(rxd4.End) || got.Score != 500 {
+		t.Fatalf("unexpected candidate %v score %d", got.Move, got.Score)
+	}
+
+	if _, ok := ab.singularCandidate(b, color.White, singularMinDepth, 0); ok {
+		t.Fatal("root nodes must not be singular candidates")
+	}
+	if _, ok := ab.singularCandidate(b, color.White, singularMinDepth+1, 1); ok {
+		t.Fatal("TT entry too shallow for the node depth")
+	}
+	ab.DisableSingular = true
+	if _, ok := ab.singularCandidate(b, color.White, singularMinDepth, 1); ok {
+		t.Fatal("DisableSingular must suppress singular candidates")
+	}
+	ab.DisableSingular = false
+
+	storeSingularTTEntry(ab, b, singularMinDepth, transposition_table.UpperBound, 500)
+	if _, ok := ab.singularCandidate(b, color.White, singularMinDepth, 1); ok {
+		t.Fatal("upper-bound TT entries must not be singular candidates")
+	}
+	storeSingularTTEntry(ab, b, singularMinDepth, transposition_table.TrueScore, WinScore)
+	if _, ok := ab.singularCandidate(b, color.White, singularMinDepth, 1); ok {
+		t.Fatal("mate scores must not be singular candidates")
+	}
+}
+
+func TestABDADASingularSearchExcludesTTMove(t *testing.T) {
+	ab, b := newSingularTestAB(t)
+	moves := orderMoves(*b.GetAllMoves(color.White, nil), rxd4, [2]location.Move{}, ab, b, nil)
+	if !isMoveInList(rxd4, &moves) {
+		t.Fatal("test setup expected Rxd4 to be legal")
+	}
+
+	// Without Rxd4 White cannot avoid being a queen for a rook down, so the
+	// capture is singular.
+	got := ab.singularSearch(b, moves, rxd4, singularMinDepth, 0, color.White, 1, maxExtensions, false)
+	if got.Score >= 0 {
+		t.Fatalf("expected every non-excluded move to fail low, got %v score %d", got.Move, got.Score)
+	}
+	if got.Move.Start.Equals(rxd4.Start) && got.Move.End.Equals(rxd4.End) {
+		t.Fatal("singular search must not search the excluded move")
+	}
+
+	// Excluding some other move leaves Rxd4 available to clear the bound.
+	other := moves[len(moves)-1]
+	got = ab.singularSearch(b, moves, other, singularMinDepth, 0, color.White, 1, maxExtensions, false)
+	if got.Score < 0 {
+		t.Fatalf("expected Rxd4 to reach the singular bound, got %v score %d", got.Move, got.Score)
+	}
+}
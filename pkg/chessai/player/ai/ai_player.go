@@ -6,11 +6,13 @@ import (
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/config"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/transposition_table"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/util"
 	"log"
 	"math/rand"
 	"os"
 	"runtime"
+	"sort"
 	"sync/atomic"
 	"time"
 )
@@ -111,6 +113,29 @@ type AIPlayer struct {
 	TurnCount                 int
 	Opening                   int
 	Metrics                   *Metrics
+	// MateSolverNodes enables the proof-number root helper (see
+	// provenMateOverride) with this node budget per solve; 0 disables it.
+	MateSolverNodes int
+	// MaxNodes, if set, stops a search that has considered this many moves,
+	// like MaxThinkTime. It takes effect only in think-time limited searches.
+	MaxNodes uint64
+	// SkillLevel weakens the AI when set with SetSkillLevel; 0 plays at full
+	// strength.
+	SkillLevel int
+	// LastScore is the score of the last searched move from this player's
+	// side; HasLastScore is false when the move came from a book.
+	LastScore    int
+	HasLastScore bool
+	// LastLine is the principal variation of the last searched move, starting
+	// with the move itself, and LastSearchTime is how long that search took.
+	LastLine       []location.Move
+	LastSearchTime time.Duration
+	// OnSearchInfo, if set, is called with the search's progress each time
+	// it completes an iterative-deepening depth. It runs on the searching
+	// goroutine, so it must not block.
+	OnSearchInfo func(SearchInfo)
+	// analysis players always search, see NewAnalysisPlayer.
+	analysis bool
 
 	Debug              bool
 	PrintInfo          bool
@@ -121,8 +146,20 @@ type AIPlayer struct {
 	// ttGeneration is incremented on ponder miss so stale ponder entries
 	// are demoted to move-ordering-only and cannot cause alpha/beta cutoffs.
 	ttGeneration uint32
+	// ponderState is ponderNone, or ponderRunning while a search started with
+	// StartPondering runs on the opponent's time, and ponderHitState once
+	// PonderHit has given it ponderHitLimit from ponderHitAt (UnixNano).
+	ponderState    uint32
+	ponderHitAt    int64
+	ponderHitLimit int64
 }
 
+const (
+	ponderNone = uint32(iota)
+	ponderRunning
+	ponderHitState
+)
+
 func NewAIPlayer(c color.Color, algorithm Algorithm) *AIPlayer {
 	p := &AIPlayer{
 		Algorithm:                 algorithm,
@@ -155,6 +192,11 @@ func newAlgorithmLike(algorithm Algorithm) Algorithm {
 			DisableLMR:      a.DisableLMR,
 			DisableFutility: a.DisableFutility,
 			DisableRazoring: a.DisableRazoring,
+			DisableSingular: a.DisableSingular,
+			DisableProbCut:  a.DisableProbCut,
+
+			ProbCutMargin:       a.ProbCutMargin,
+			ProbCutSEEThreshold: a.ProbCutSEEThreshold,
 		}
 	case *LazySMP:
 		return &LazySMP{}
@@ -165,16 +207,51 @@ func newAlgorithmLike(algorithm Algorithm) Algorithm {
 	case *MTDf:
 		return &MTDf{}
 	case *NegaScout:
-		return &NegaScout{}
+		return &NegaScout{
+			DisableProbCut:      a.DisableProbCut,
+			ProbCutMargin:       a.ProbCutMargin,
+			ProbCutSEEThreshold: a.ProbCutSEEThreshold,
+		}
 	case *Jamboree:
 		return &Jamboree{}
 	case *Random:
 		return &Random{}
+	case *ProofNumber:
+		pn := &ProofNumber{MaxNodes: a.MaxNodes}
+		if a.Fallback != nil {
+			pn.Fallback = newAlgorithmLike(a.Fallback)
+		}
+		return pn
+	case *MCTS:
+		return &MCTS{
+			NumThreads:    a.NumThreads,
+			Exploration:   a.Exploration,
+			DisablePriors: a.DisablePriors,
+			MaxIterations: a.MaxIterations,
+			MaxTreeNodes:  a.MaxTreeNodes,
+		}
 	default:
 		return NameToAlgorithm[algorithm.GetName()]
 	}
 }
 
+// NewAnalysisPlayer creates a player that analyses positions rather than
+// playing a game: it has no opening book or preferences, so it always
+// searches, and it prints nothing.
+func NewAnalysisPlayer(c color.Color, algorithm Algorithm) *AIPlayer {
+	return &AIPlayer{
+		Algorithm:                 algorithm,
+		TranspositionTableEnabled: config.Get().TranspositionTableEnabled,
+		PlayerColor:               c,
+		Opening:                   OpeningNone,
+		Metrics:                   &Metrics{},
+		analysis:                  true,
+		evaluationMap:             util.NewConcurrentBoardMap(),
+		transpositionTable:        util.NewConcurrentBoardMap(),
+		printer:                   make(chan string, 4096),
+	}
+}
+
 // NewRootWorkerPlayer creates an isolated per-thread search player for the
 // parallel root loop. Unlike NewPonderPlayer it reuses the parent's printer
 // channel (allocating a fresh 1M-slot channel per root move dominated malloc
@@ -253,6 +330,7 @@ func betterMove(maximizingP bool, currentBest *ScoredMove, candidate *ScoredMove
 }
 
 func (p *AIPlayer) GetBestMove(b *board.Board, previousMove *board.LastMove, logger *PerformanceLogger) *location.Move {
+	p.HasLastScore, p.LastLine = false, nil
 	if p.Opening != OpeningNone && p.TurnCount < len(OpeningMoves[p.PlayerColor][p.Opening]) {
 		bookMove := OpeningMoves[p.PlayerColor][p.Opening][p.TurnCount]
 		// The book is a fixed move list indexed by turn count — it does NOT react to
@@ -288,6 +366,9 @@ func (p *AIPlayer) GetBestMove(b *board.Board, previousMove *board.LastMove, log
 	if move := p.earlyOpeningPreference(b, previousMove); move != nil {
 		return move
 	}
+	if move := p.skillBlunder(b, previousMove); move != nil {
+		return move
+	}
 	{
 		thinking := make(chan bool)
 		go p.printThread(thinking)
@@ -295,6 +376,7 @@ func (p *AIPlayer) GetBestMove(b *board.Board, previousMove *board.LastMove, log
 		p.setAbort(false)
 		// reset metrics for each move
 		p.Metrics = &Metrics{}
+		start := time.Now()
 		// Bound cache growth: the eval map adds ~50-100K entries per move and
 		// never shrinks, pinning RSS at the 2GiB soft memory limit within one
 		// long game — at the cap the GC runs continuously and search speed
@@ -306,12 +388,15 @@ func (p *AIPlayer) GetBestMove(b *board.Board, previousMove *board.LastMove, log
 		if p.Algorithm != nil {
 			scoredMove := p.Algorithm.GetBestMove(p, b, previousMove)
 			scoredMove = p.avoidImmediateMateMove(b, previousMove, scoredMove)
+			scoredMove = p.provenMateOverride(b, previousMove, scoredMove, start)
 			if p.Debug {
 				p.printMoveDebug(b, scoredMove)
 			}
 			if logger != nil {
 				logger.MarkPerformance(b, scoredMove, p)
 			}
+			p.LastScore, p.HasLastScore = scoredMove.Score, true
+			p.LastLine, p.LastSearchTime = p.principalVariation(b, previousMove, scoredMove), time.Since(start)
 			if scoredMove.Move.Start.Equals(scoredMove.Move.End) {
 				log.Printf("%s resigns, no best move available. Picking random.\n", p)
 				return &(&Random{
@@ -325,8 +410,106 @@ func (p *AIPlayer) GetBestMove(b *board.Board, previousMove *board.LastMove, log
 	}
 }
 
+// SearchInfo is a search's progress after a completed depth: its best move's
+// score from the searching player's side and its principal variation,
+// starting with the move.
+type SearchInfo struct {
+	Depth   int
+	Score   int
+	PV      []location.Move
+	Nodes   uint64
+	Elapsed time.Duration
+}
+
+// reportSearchInfo passes best, the result of the depth just completed, to
+// OnSearchInfo.
+func (p *AIPlayer) reportSearchInfo(b *board.Board, previousMove *board.LastMove, best *ScoredMove, start time.Time) {
+	if p.OnSearchInfo == nil || best.Move.Start.Equals(best.Move.End) {
+		return
+	}
+	p.OnSearchInfo(SearchInfo{
+		Depth:   p.LastSearchDepth,
+		Score:   best.Score,
+		PV:      p.principalVariation(b, previousMove, best),
+		Nodes:   atomic.LoadUint64(&p.Metrics.MovesConsidered),
+		Elapsed: time.Since(start),
+	})
+}
+
+// maxPrincipalVariation caps the length of LastLine.
+const maxPrincipalVariation = 16
+
+// principalVariation returns m's line in playing order. MoveSequence is built
+// from the leaf up; when it is missing or no longer starts with the chosen
+// move (a root override replaced it), the line restarts from the move. Lines
+// the search did not record are continued from the transposition table.
+func (p *AIPlayer) principalVariation(b *board.Board, previousMove *board.LastMove, m *ScoredMove) []location.Move {
+	line := []location.Move{m.Move}
+	if n := len(m.MoveSequence); n > 0 && m.MoveSequence[n-1].Start.Equals(m.Move.Start) && m.MoveSequence[n-1].End.Equals(m.Move.End) {
+		line = make([]location.Move, n)
+		for i, move := range m.MoveSequence {
+			line[n-1-i] = move
+		}
+	}
+	if len(line) > maxPrincipalVariation {
+		line = line[:maxPrincipalVariation]
+	}
+	pvBoard, side := b.Copy(), p.PlayerColor
+	for i := range line {
+		previousMove = board.MakeMove(&line[i], pvBoard)
+		side ^= 1
+	}
+	for len(line) < maxPrincipalVariation {
+		move, ok := p.tableMove(pvBoard, side)
+		if !ok || !isLegalMove(pvBoard, side, previousMove, move) {
+			break
+		}
+		line = append(line, move)
+		previousMove = board.MakeMove(&move, pvBoard)
+		side ^= 1
+	}
+	return line
+}
+
+// tableMove is the best move the transposition table holds for b with side to
+// move.
+func (p *AIPlayer) tableMove(b *board.Board, side color.Color) (location.Move, bool) {
+	h := b.Hash()
+	e, ok := p.transpositionTable.Read(&h, side)
+	if !ok {
+		return location.Move{}, false
+	}
+	var move location.Move
+	switch entry := e.(type) {
+	case *transposition_table.TranspositionTableEntryABDADA:
+		entry.Lock.Lock()
+		move = entry.BestMove
+		entry.Lock.Unlock()
+	case *transposition_table.TranspositionTableEntryJamboree:
+		entry.Lock.Lock()
+		move = entry.BestMove
+		entry.Lock.Unlock()
+	case *transposition_table.TranspositionTableEntryNegaScout:
+		move = entry.BestMove
+	case *transposition_table.TranspositionTableEntryABMemory:
+		move = entry.BestMove
+	default:
+		return location.Move{}, false
+	}
+	return move, !move.Start.Equals(move.End)
+}
+
+func isLegalMove(b *board.Board, side color.Color, previousMove *board.LastMove, move location.Move) bool {
+	for _, m := range *b.GetAllMoves(side, previousMove) {
+		if m.Start.Equals(move.Start) && m.End.Equals(move.End) {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *AIPlayer) earlyOpeningPreference(b *board.Board, previousMove *board.LastMove) *location.Move {
-	if p.TurnCount >= 2 || !looksLikeOpeningPosition(b) {
+	if p.analysis || p.TurnCount >= 2 || !looksLikeOpeningPosition(b) {
 		return nil
 	}
 	preferences := openingPreferenceMoves(p.PlayerColor, p.TurnCount)
@@ -445,6 +628,64 @@ func (p *AIPlayer) avoidImmediateMateMove(b *board.Board, previousMove *board.La
 	return &bestSafe
 }
 
+// provenMateOverride is the deeper sibling of avoidImmediateMateMove, run when
+// MateSolverNodes is set. A proof-number solve from the root replaces a
+// non-mate search result with a proven mate, and a chosen move after which the
+// opponent has a proven mate is swapped for the best-evaluated move where the
+// solver finds none. Solves are bounded by the node budget and by what is left
+// of the think time of the search that started at start; the search's abort
+// flag is usually already set by the time this runs, so it cannot be used.
+func (p *AIPlayer) provenMateOverride(b *board.Board, previousMove *board.LastMove, scoredMove *ScoredMove, start time.Time) *ScoredMove {
+	if p.MateSolverNodes <= 0 || scoredMove == nil || scoredMove.Move.Start.Equals(scoredMove.Move.End) {
+		return scoredMove
+	}
+	outOfTime := func() bool {
+		elapsed, limit := p.thinkClock(start)
+		return limit > 0 && elapsed >= limit
+	}
+	if outOfTime() {
+		return scoredMove
+	}
+	if _, solved := p.Algorithm.(*ProofNumber); !solved && scoredMove.Score < WinScore {
+		if result := SolveMate(b, p.PlayerColor, previousMove, p.MateSolverNodes, outOfTime); result.Proven {
+			p.printer <- fmt.Sprintf("proven mate override: %s -> %s (mate in %d)\n", scoredMove.Move, result.Line[0], result.MateIn())
+			return &ScoredMove{Move: result.Line[0], MoveSequence: result.Line, Score: WinScore}
+		}
+	}
+	if !p.moveAllowsProvenMate(b, previousMove, scoredMove.Move, outOfTime) {
+		return scoredMove
+	}
+	moves := b.GetAllMoves(p.PlayerColor, previousMove)
+	candidates := make([]ScoredMove, 0, len(*moves))
+	for _, move := range *moves {
+		if move.Equals(&scoredMove.Move) {
+			continue
+		}
+		child := b.Copy()
+		board.MakeMove(&move, child)
+		candidates = append(candidates, ScoredMove{Move: move, Score: p.EvaluateBoard(child, p.PlayerColor).TotalScore})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	for _, candidate := range candidates {
+		allowsMate := p.moveAllowsProvenMate(b, previousMove, candidate.Move, outOfTime)
+		if outOfTime() {
+			// An unfinished solve proves nothing about the candidate
+			break
+		}
+		if !allowsMate {
+			p.printer <- fmt.Sprintf("proven mate safety override: %s -> %s\n", scoredMove.Move, candidate.Move)
+			return &candidate
+		}
+	}
+	return scoredMove
+}
+
+func (p *AIPlayer) moveAllowsProvenMate(b *board.Board, previousMove *board.LastMove, move location.Move, abort func() bool) bool {
+	child := b.Copy()
+	last := board.MakeMove(&move, child)
+	return SolveMate(child, p.PlayerColor^1, last, p.MateSolverNodes, abort).Proven
+}
+
 func moveAllowsMateInOne(b *board.Board, previousMove *board.LastMove, side color.Color, move location.Move) bool {
 	child := b.Copy()
 	last := board.MakeMove(&move, child)
@@ -472,7 +713,7 @@ func (p AIPlayer) String() string {
 }
 
 func (p *AIPlayer) printMoveDebug(b *board.Board, m *ScoredMove) {
-	LogFile := config.Get().DebugLogFileName
+	LogFile := config.LogPath(config.Get().DebugLogFileName)
 	file, err := os.OpenFile(LogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		log.Fatal("Cannot open file", err)
@@ -547,6 +788,42 @@ func (p *AIPlayer) printThread(stop chan bool) {
 	}
 }
 
+// StartPondering makes the next search a ponder search: it runs on the
+// opponent's time, bounded only by MaxThinkTime as a safety cap, until
+// PonderHit gives it a real time limit.
+func (p *AIPlayer) StartPondering() {
+	atomic.StoreUint32(&p.ponderState, ponderRunning)
+}
+
+// PonderHit turns a running ponder search into the search for our move: it
+// goes on from where it is, and now has limit (as MaxThinkTime would) counted
+// from this call.
+func (p *AIPlayer) PonderHit(limit time.Duration) {
+	atomic.StoreInt64(&p.ponderHitLimit, int64(limit))
+	atomic.StoreInt64(&p.ponderHitAt, time.Now().UnixNano())
+	atomic.StoreUint32(&p.ponderState, ponderHitState)
+}
+
+// thinkClock is the time spent on a search that started at start and its
+// limit. After a ponder hit both count from the hit.
+func (p *AIPlayer) thinkClock(start time.Time) (elapsed, limit time.Duration) {
+	if atomic.LoadUint32(&p.ponderState) == ponderHitState {
+		hitAt := time.Unix(0, atomic.LoadInt64(&p.ponderHitAt))
+		return time.Since(hitAt), time.Duration(atomic.LoadInt64(&p.ponderHitLimit))
+	}
+	return time.Since(start), p.MaxThinkTime
+}
+
+// AdoptSearch takes over the results of a search by ponder, a ponder player
+// of p, as if p had searched itself.
+func (p *AIPlayer) AdoptSearch(ponder *AIPlayer) {
+	p.LastScore, p.HasLastScore = ponder.LastScore, ponder.HasLastScore
+	p.LastLine, p.LastSearchTime = ponder.LastLine, ponder.LastSearchTime
+	p.LastSearchDepth = ponder.LastSearchDepth
+	p.Metrics = ponder.Metrics
+	p.Opening = ponder.Opening
+}
+
 func (p *AIPlayer) trackThinkTime(stop, done chan bool, start time.Time) {
 	if p.MaxThinkTime != 0 {
 		for {
@@ -555,10 +832,13 @@ func (p *AIPlayer) trackThinkTime(stop, done chan bool, start time.Time) {
 				done <- true
 				return
 			default:
-				thinkTime := time.Now().Sub(start)
-				if thinkTime > p.MaxThinkTime {
+				thinkTime, limit := p.thinkClock(start)
+				if thinkTime > limit {
 					p.setAbort(true)
 					p.printer <- fmt.Sprintf("requesting AI hard abort, out of time!\n")
+				} else if p.MaxNodes != 0 && atomic.LoadUint64(&p.Metrics.MovesConsidered) >= p.MaxNodes {
+					p.setAbort(true)
+					p.printer <- fmt.Sprintf("requesting AI hard abort, out of nodes!\n")
 				}
 			}
 			time.Sleep(1 * time.Millisecond)
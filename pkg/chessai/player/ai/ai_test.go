@@ -12,6 +12,7 @@ import (
 	"io/ioutil"
 	"log"
 	"math/rand"
+	"os"
 	"path"
 	"runtime"
 	"strconv"
@@ -20,6 +21,10 @@ import (
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	os.Exit(config.RunWithTempLogs(m))
+}
+
 var algorithmsToTest = [...]string{
 	AlgorithmMiniMax,
 	AlgorithmMTDf,
@@ -11,6 +11,8 @@ const (
 	AlgorithmRandom              = "Random"
 	AlgorithmJamboree            = "Jamboree"
 	AlgorithmLazySMP             = "LazySMP"
+	AlgorithmProofNumber         = "ProofNumber"
+	AlgorithmMCTS                = "MCTS"
 )
 
 type Algorithm interface {
@@ -27,6 +29,8 @@ var NameToAlgorithm = map[string]Algorithm{
 	AlgorithmRandom:              &Random{},
 	AlgorithmJamboree:            &Jamboree{},
 	AlgorithmLazySMP:             &LazySMP{},
+	AlgorithmProofNumber:         &ProofNumber{},
+	AlgorithmMCTS:                &MCTS{},
 }
 
 // NewAlgorithm returns a fresh, unshared instance of the named algorithm. The
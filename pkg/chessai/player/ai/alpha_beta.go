@@ -144,6 +144,7 @@ func (ab *AlphaBetaWithMemory) iterativeAlphaBeta(b *board.Board, previousMove *
 			best = newBest
 			ab.player.LastSearchDepth = ab.currentSearchDepth
 			ab.player.printer <- fmt.Sprintf("Best D:%d M:%s\n", ab.player.LastSearchDepth, best.Move)
+			ab.player.reportSearchInfo(b, previousMove, best, start)
 		} else {
 			ab.player.LastSearchDepth = ab.currentSearchDepth - iterativeIncrement
 			ab.player.printer <- fmt.Sprintf("%s hard abort! evaluated to depth %d\n", ab.GetName(), ab.player.LastSearchDepth)
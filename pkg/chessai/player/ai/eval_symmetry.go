@@ -0,0 +1,305 @@
+package ai
+
+import (
+	"fmt"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/piece"
+)
+
+// Evaluation symmetry checking.
+//
+// Both hand-written evaluators must be color-blind: mirroring a position
+// (board.Mirror swaps colors and flips ranks) and handing the move to the other
+// side must not change the side-to-move-relative score. A color-handling bug in
+// a single term (a rank computed from the wrong back rank, a table indexed
+// without flipping) silently favors one side, so each evaluator exposes its
+// terms individually and the checker reports the first one that breaks.
+//
+// File flips (board.FlipFiles) are weaker: some tables deliberately favor one
+// wing, so only terms marked fileSymmetric are required to survive them.
+
+// symmetryTerm is one per-color component of an evaluator; score is positive
+// when it favors c.
+type symmetryTerm struct {
+	name          string
+	fileSymmetric bool
+	score         func(b *board.Board, c color.Color) int
+}
+
+// termScore is a term's score for each color on one board.
+type termScore struct {
+	name           string
+	colorSymmetric bool
+	fileSymmetric  bool
+	score          [color.NumColors]int
+}
+
+// SymmetryEvaluator is an evaluator that the symmetry checker knows how to
+// decompose into terms.
+type SymmetryEvaluator struct {
+	Name  string
+	total func(b *board.Board, whoMoves color.Color) int
+	// terms scores b term by term, always in the same order.
+	terms func(b *board.Board) []termScore
+}
+
+// SymmetryViolation describes the first term of an evaluator that scored a
+// position and its transform differently.
+type SymmetryViolation struct {
+	Evaluator string
+	// Check is "color" for board.Mirror or "file" for board.FlipFiles.
+	Check string
+	Term  string
+	// Color is the side the term was scored for on the original board
+	// (meaningless for the total, which is scored for the side to move).
+	Color       color.Color
+	Original    int
+	Transformed int
+}
+
+func (v SymmetryViolation) String() string {
+	return fmt.Sprintf("%s %s-flip asymmetry in %s (%s): original=%d transformed=%d",
+		v.Evaluator, v.Check, v.Term, color.Names[v.Color], v.Original, v.Transformed)
+}
+
+// SymmetryEvaluators lists the evaluators covered by CheckEvalSymmetry, in the
+// order they are reported.
+var SymmetryEvaluators = []SymmetryEvaluator{
+	{
+		Name: "classic",
+		total: func(b *board.Board, whoMoves color.Color) int {
+			eval := NewEvaluation()
+			evaluateClassic(b, whoMoves, eval, nil)
+			return eval.TotalScore
+		},
+		terms: classicTermsOf,
+	},
+	{
+		Name:  "stockfish-classic",
+		total: evaluateStockfishClassicScore,
+		terms: scoreTerms(sfSymmetryTerms),
+	},
+}
+
+// CheckEvalSymmetry evaluates b with every SymmetryEvaluator and returns at
+// most one violation per evaluator and check: the first asymmetric term, or
+// the total if every term agrees but the combined score does not.
+func CheckEvalSymmetry(b *board.Board, whoMoves color.Color) []SymmetryViolation {
+	var violations []SymmetryViolation
+	mirrored := b.Mirror()
+	flipped := b.FlipFiles()
+	for _, ev := range SymmetryEvaluators {
+		if v, ok := ev.checkColor(b, mirrored, whoMoves); !ok {
+			violations = append(violations, v)
+		}
+		if v, ok := ev.checkFile(b, flipped); !ok {
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+func (ev *SymmetryEvaluator) checkColor(b, mirrored *board.Board, whoMoves color.Color) (SymmetryViolation, bool) {
+	origTerms, mirrTerms := ev.terms(b), ev.terms(mirrored)
+	// The total is compared without the terms known to break color symmetry.
+	orig, mirr := ev.total(b, whoMoves), ev.total(mirrored, whoMoves^1)
+	for i, term := range origTerms {
+		if !term.colorSymmetric {
+			orig -= term.score[whoMoves] - term.score[whoMoves^1]
+			mirr -= mirrTerms[i].score[whoMoves^1] - mirrTerms[i].score[whoMoves]
+			continue
+		}
+		for c := color.Color(0); c < color.NumColors; c++ {
+			orig, mirr := term.score[c], mirrTerms[i].score[c^1]
+			if orig != mirr {
+				return SymmetryViolation{ev.Name, "color", term.name, c, orig, mirr}, false
+			}
+		}
+	}
+	if orig != mirr {
+		return SymmetryViolation{ev.Name, "color", "total", whoMoves, orig, mirr}, false
+	}
+	return SymmetryViolation{}, true
+}
+
+func (ev *SymmetryEvaluator) checkFile(b, flipped *board.Board) (SymmetryViolation, bool) {
+	origTerms, flipTerms := ev.terms(b), ev.terms(flipped)
+	for i, term := range origTerms {
+		if !term.fileSymmetric {
+			continue
+		}
+		for c := color.Color(0); c < color.NumColors; c++ {
+			orig, flip := term.score[c], flipTerms[i].score[c]
+			if orig != flip {
+				return SymmetryViolation{ev.Name, "file", term.name, c, orig, flip}, false
+			}
+		}
+	}
+	return SymmetryViolation{}, true
+}
+
+// scoreTerms scores b with each of terms for both colors.
+func scoreTerms(terms []symmetryTerm) func(b *board.Board) []termScore {
+	return func(b *board.Board) []termScore {
+		scores := make([]termScore, len(terms))
+		for i, term := range terms {
+			scores[i] = termScore{name: term.name, colorSymmetric: true, fileSymmetric: term.fileSymmetric}
+			for c := color.Color(0); c < color.NumColors; c++ {
+				scores[i].score[c] = term.score(b, c)
+			}
+		}
+		return scores
+	}
+}
+
+// classicTerm is one of the terms evaluateClassic adds up.
+type classicTerm int
+
+const (
+	termMaterial classicTerm = iota
+	termPieceAdvance
+	termPST
+	termPassedPawn
+	termBackwardPawn
+	termKnightOutpost
+	termRookFile
+	termRookOnSeventh
+	termRookPasserActivity
+	termKnightBlockade
+	termConnectedPassers
+	termCastling
+	termInCheck
+	termKingSafety
+	termKingAttackZone
+	termDoubledPawns
+	termIsolatedPawns
+	termPawnAdvance
+	termMobility
+	termBishopPair
+	termMopUp
+	termKingPasserSupport
+	termKingPasserDefense
+	numClassicTerms
+)
+
+// classicTerms names each classicTerm and tells whether it must survive a
+// color and a file flip. The mop-up term measures the losing king's distance
+// from d4 instead of the board's center, so it scores a king on White's back
+// rank one step nearer the center than one on Black's; it is left alone
+// because correcting it changes how the engine plays.
+var classicTerms = [numClassicTerms]struct {
+	name           string
+	colorSymmetric bool
+	fileSymmetric  bool
+}{
+	termMaterial:           {"material", true, true},
+	termPieceAdvance:       {"piece-advance", true, true},
+	termPST:                {"pst", true, false},
+	termPassedPawn:         {"passed-pawn", true, true},
+	termBackwardPawn:       {"backward-pawn", true, true},
+	termKnightOutpost:      {"knight-outpost", true, true},
+	termRookFile:           {"rook-file", true, true},
+	termRookOnSeventh:      {"rook-on-seventh", true, true},
+	termRookPasserActivity: {"rook-passer-activity", true, true},
+	termKnightBlockade:     {"knight-blockade", true, true},
+	termConnectedPassers:   {"connected-passers", true, true},
+	termCastling:           {"castling", true, true},
+	termInCheck:            {"in-check", true, true},
+	termKingSafety:         {"king-safety", true, true},
+	termKingAttackZone:     {"king-attack-zone", true, true},
+	termDoubledPawns:       {"doubled-pawns", true, true},
+	termIsolatedPawns:      {"isolated-pawns", true, true},
+	termPawnAdvance:        {"pawn-advance", true, true},
+	termMobility:           {"mobility", true, true},
+	termBishopPair:         {"bishop-pair", true, true},
+	termMopUp:              {"mop-up", false, false},
+	termKingPasserSupport:  {"king-passer-support", true, true},
+	termKingPasserDefense:  {"king-passer-defense", true, true},
+}
+
+// classicTermScores is each classicTerm's score for each color, positive when
+// it favors that color.
+type classicTermScores [numClassicTerms][color.NumColors]int
+
+// add adds score to term's score for c. It does nothing on a nil t, so that
+// evaluateClassic only pays for the breakdown when asked for it.
+func (t *classicTermScores) add(term classicTerm, c color.Color, score int) {
+	if t != nil {
+		t[term][c] += score
+	}
+}
+
+// classicTermsOf scores b with evaluateClassic, term by term.
+func classicTermsOf(b *board.Board) []termScore {
+	var scores classicTermScores
+	evaluateClassic(b, color.White, NewEvaluation(), &scores)
+	terms := make([]termScore, numClassicTerms)
+	for term := range terms {
+		info := classicTerms[term]
+		terms[term] = termScore{info.name, info.colorSymmetric, info.fileSymmetric, scores[term]}
+	}
+	return terms
+}
+
+// sfSymmetryTerms breaks evaluateStockfishClassicScore into the pieces of
+// colorScore, each tapered by the position's phase.
+var sfSymmetryTerms = []symmetryTerm{
+	{"psqt", false, func(b *board.Board, c color.Color) int {
+		e := newSFEval(b)
+		var s sfScore
+		for _, p := range e.pieces {
+			if p.c == c {
+				s = s.add(sfPSQT(p.pt, sfRelRank(c, p.row), 7-p.col))
+			}
+		}
+		return e.taper(s)
+	}},
+	{"mobility", true, func(b *board.Board, c color.Color) int {
+		e := newSFEval(b)
+		var s sfScore
+		for _, p := range e.pieces {
+			if p.c == c {
+				s = s.add(sfMobility(p.pt, sfPopcnt(p.attacks.IntersectBitBoards(e.mobilityArea[c]))))
+			}
+		}
+		return e.taper(s)
+	}},
+	{"passed-pawn", true, func(b *board.Board, c color.Color) int {
+		e := newSFEval(b)
+		forward := 1
+		if c == color.Black {
+			forward = -1
+		}
+		var s sfScore
+		for _, p := range e.pieces {
+			if p.c == c && p.pt == piece.PawnType &&
+				isPassedPawn(b, location.CoordinateType(p.row), location.CoordinateType(p.col), c) {
+				s = s.add(e.passedPawn(c, p.row, p.col, sfRelRank(c, p.row), forward))
+			}
+		}
+		return e.taper(s)
+	}},
+	{"threats", true, func(b *board.Board, c color.Color) int {
+		e := newSFEval(b)
+		return e.taper(e.threats(c))
+	}},
+	{"space", true, func(b *board.Board, c color.Color) int {
+		e := newSFEval(b)
+		return e.taper(e.space(c))
+	}},
+	{"queenless-king-activity", true, func(b *board.Board, c color.Color) int {
+		e := newSFEval(b)
+		return e.taper(e.queenlessKingActivity(c))
+	}},
+	{"king-danger", true, func(b *board.Board, c color.Color) int {
+		e := newSFEval(b)
+		return e.taper(e.kingDanger(c))
+	}},
+	{"color-score", false, func(b *board.Board, c color.Color) int {
+		e := newSFEval(b)
+		return e.taper(e.colorScore(c))
+	}},
+}
@@ -0,0 +1,131 @@
+package ai
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/util"
+)
+
+const symmetryFENFile = "../../../../testdata/abdada_fens.txt"
+
+// TestEvalSymmetryBenchmarkFENs guards both evaluators against color-handling
+// bugs on every position in the ABDADA benchmark file.
+func TestEvalSymmetryBenchmarkFENs(t *testing.T) {
+	f, err := os.Open(symmetryFENFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	checked := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.SplitN(scanner.Text(), "#", 2)[0])
+		if line == "" {
+			continue
+		}
+		fen := strings.TrimSpace(strings.Split(line, "|")[0])
+		fields := strings.Fields(fen)
+		b := boardFromFENPlacement(t, fields[0])
+		whoMoves := color.White
+		if fields[1] == "b" {
+			whoMoves = color.Black
+		}
+		for _, v := range CheckEvalSymmetry(b, whoMoves) {
+			t.Errorf("%s: %s", fen, v)
+		}
+		checked++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if checked == 0 {
+		t.Fatalf("no FENs read from %s", symmetryFENFile)
+	}
+}
+
+func TestEvalSymmetryEvaluationBoards(t *testing.T) {
+	files, err := ioutil.ReadDir(boardsDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		lines, skip := util.LoadBoardFile(path.Join(boardsDirectory, file.Name()))
+		if skip {
+			continue
+		}
+		whoMoves := color.Black
+		if strings.HasPrefix(lines[0], "White") {
+			whoMoves = color.White
+		}
+		b := &board.Board{}
+		b.LoadBoardFromText(lines[1:])
+		for _, v := range CheckEvalSymmetry(b, whoMoves) {
+			t.Errorf("%s: %s", file.Name(), v)
+		}
+	}
+}
+
+// TestClassicTermsAddUpToTotal checks that the breakdown the checker uses
+// covers everything evaluateClassic scores.
+func TestClassicTermsAddUpToTotal(t *testing.T) {
+	files, err := ioutil.ReadDir(boardsDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		lines, skip := util.LoadBoardFile(path.Join(boardsDirectory, file.Name()))
+		if skip {
+			continue
+		}
+		b := &board.Board{}
+		b.LoadBoardFromText(lines[1:])
+		for whoMoves := color.Color(0); whoMoves < color.NumColors; whoMoves++ {
+			var scores classicTermScores
+			eval := NewEvaluation()
+			evaluateClassic(b, whoMoves, eval, &scores)
+			sum := 0
+			for _, score := range scores {
+				sum += score[whoMoves] - score[whoMoves^1]
+			}
+			if sum != eval.TotalScore {
+				t.Errorf("%s: terms add up to %d for %s, total is %d",
+					file.Name(), sum, color.Names[whoMoves], eval.TotalScore)
+			}
+		}
+	}
+}
+
+// TestEvalSymmetryReportsFirstTerm checks the checker itself: a term that
+// ignores color must be reported by name before the total.
+func TestEvalSymmetryReportsFirstTerm(t *testing.T) {
+	b := &board.Board{}
+	b.ResetDefault()
+	board.MakeMove(&location.Move{
+		Start: location.NewLocation(1, 3), // e2
+		End:   location.NewLocation(3, 3), // e4
+	}, b)
+
+	lopsided := SymmetryEvaluator{
+		Name:  "lopsided",
+		total: evaluateStockfishClassicScore,
+		terms: scoreTerms([]symmetryTerm{{"white-rows", true, func(b *board.Board, c color.Color) int {
+			// Deliberately uses White's row numbering for both colors.
+			return int(b.KingLocations[c].GetRow())
+		}}}),
+	}
+	v, ok := lopsided.checkColor(b, b.Mirror(), color.Black)
+	if ok {
+		t.Fatal("expected a color asymmetry")
+	}
+	if v.Term != "white-rows" {
+		t.Fatalf("expected white-rows to be reported first, got %s", v.Term)
+	}
+}
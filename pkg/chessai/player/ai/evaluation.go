@@ -706,350 +706,401 @@ func EvaluateBoardNoCache(b *board.Board, whoMoves color.Color) *Evaluation {
 		// Stockfish-classical-style hand-crafted evaluation (see evaluation_sf.go).
 		eval.TotalScore = evaluateStockfishClassicScore(b, whoMoves)
 	} else {
-		// First pass: count pieces so endgamePhase can be computed before PST scoring.
-		for row := location.CoordinateType(0); row < board.Height; row++ {
-			for col := location.CoordinateType(0); col < board.Width; col++ {
-				if pt, pc, ok := b.GetPieceTypeColor(location.NewLocation(row, col)); ok {
-					eval.PieceCounts[pc][pt]++
-				}
+		evaluateClassic(b, whoMoves, eval, nil)
+	}
+	return eval
+}
+
+// evaluateClassic fills eval with the engine's native hand-crafted evaluation,
+// side-to-move relative. Terminal positions are handled by the caller. If terms
+// is not nil, each term's score for each color is added to it.
+func evaluateClassic(b *board.Board, whoMoves color.Color, eval *Evaluation, terms *classicTermScores) {
+	// First pass: count pieces so endgamePhase can be computed before PST scoring.
+	for row := location.CoordinateType(0); row < board.Height; row++ {
+		for col := location.CoordinateType(0); col < board.Width; col++ {
+			if pt, pc, ok := b.GetPieceTypeColor(location.NewLocation(row, col)); ok {
+				eval.PieceCounts[pc][pt]++
 			}
 		}
-		phase := endgamePhase(eval.PieceCounts)
+	}
+	phase := endgamePhase(eval.PieceCounts)
 
-		pstScores := [color.NumColors]int{}
-		// combinedAttacks accumulates the pseudo-legal attack BitBoards for each color.
-		// Used for the king attack zone penalty without an extra board scan.
-		var combinedAttacks [color.NumColors]board.BitBoard
-		// passedPawnCols tracks which columns have a passed pawn for each color.
-		// Used to detect connected passed pawns (adjacent-file passers).
-		var passedPawnCols [color.NumColors][board.Width]bool
-		for row := location.CoordinateType(0); row < board.Height; row++ {
-			for col := location.CoordinateType(0); col < board.Width; col++ {
-				if gamePiece := b.GetPiece(location.NewLocation(row, col)); gamePiece != nil {
-					c := gamePiece.GetColor()
-					pt := gamePiece.GetPieceType()
+	pstScores := [color.NumColors]int{}
+	// combinedAttacks accumulates the pseudo-legal attack BitBoards for each color.
+	// Used for the king attack zone penalty without an extra board scan.
+	var combinedAttacks [color.NumColors]board.BitBoard
+	// passedPawnCols tracks which columns have a passed pawn for each color.
+	// Used to detect connected passed pawns (adjacent-file passers).
+	var passedPawnCols [color.NumColors][board.Width]bool
+	for row := location.CoordinateType(0); row < board.Height; row++ {
+		for col := location.CoordinateType(0); col < board.Width; col++ {
+			if gamePiece := b.GetPiece(location.NewLocation(row, col)); gamePiece != nil {
+				c := gamePiece.GetColor()
+				pt := gamePiece.GetPieceType()
 
-					// Use pseudo-legal (attackable) squares for mobility — avoids willMoveLeaveKingInCheck
-					// per candidate, which would copy the board for every move of every piece.
-					// Pseudo-legal mobility is a standard eval heuristic and allows searching much deeper.
-					attackableMoves := gamePiece.GetAttackableMoves(b)
-					numPseudoLegal := int(hamming.CountBitsUint64(uint64(attackableMoves)))
-					eval.NumMoves[c] += uint16(numPseudoLegal)
-					combinedAttacks[c] = combinedAttacks[c].CombineBitBoards(attackableMoves)
+				// Use pseudo-legal (attackable) squares for mobility — avoids willMoveLeaveKingInCheck
+				// per candidate, which would copy the board for every move of every piece.
+				// Pseudo-legal mobility is a standard eval heuristic and allows searching much deeper.
+				attackableMoves := gamePiece.GetAttackableMoves(b)
+				numPseudoLegal := int(hamming.CountBitsUint64(uint64(attackableMoves)))
+				eval.NumMoves[c] += uint16(numPseudoLegal)
+				combinedAttacks[c] = combinedAttacks[c].CombineBitBoards(attackableMoves)
 
-					pstScores[c] += pstBonus(pt, c, row, col, phase)
+				pst := pstBonus(pt, c, row, col, phase)
+				pstScores[c] += pst
+				terms.add(termPST, c, pst)
 
-					if pt == piece.PawnType {
-						eval.PawnColumns[c][col]++
-						eval.PawnRows[c][row]++
-						if row != board.StartRow[c]["Pawn"] {
-							eval.PieceAdvanced[c][pt]++
-						}
-						// Passed pawn bonus: awarded per pawn, based on rank from own back rank.
-						if isPassedPawn(b, row, col, c) {
-							rank := int(row)
-							if c == color.Black {
-								rank = 7 - int(row)
-							}
-							pstScores[c] += passedPawnBonus[rank]
-							passedPawnCols[c][col] = true
-						}
-						// Backward pawn: no friendly pawn on adjacent files that is BEHIND this one.
-						// "Behind" = closer to own back rank.
-						pstScores[c] += backwardPawnPenalty(b, row, col, c)
-					} else if pt == piece.KnightType {
-						if row != board.StartRow[c]["Piece"] {
-							eval.PieceAdvanced[c][pt]++
-						}
+				if pt == piece.PawnType {
+					eval.PawnColumns[c][col]++
+					eval.PawnRows[c][row]++
+					if row != board.StartRow[c]["Pawn"] {
+						eval.PieceAdvanced[c][pt]++
+					}
+					// Passed pawn bonus: awarded per pawn, based on rank from own back rank.
+					if isPassedPawn(b, row, col, c) {
 						rank := int(row)
 						if c == color.Black {
 							rank = 7 - int(row)
 						}
-						if rank >= 3 && rank <= 5 && isKnightOutpost(b, row, col, c) {
-							pstScores[c] += KnightOutpostBonus
-						}
-					} else if pt != piece.KingType {
-						if row != board.StartRow[c]["Piece"] {
-							eval.PieceAdvanced[c][pt]++
-						}
+						pstScores[c] += passedPawnBonus[rank]
+						terms.add(termPassedPawn, c, passedPawnBonus[rank])
+						passedPawnCols[c][col] = true
+					}
+					// Backward pawn: no friendly pawn on adjacent files that is BEHIND this one.
+					// "Behind" = closer to own back rank.
+					backward := backwardPawnPenalty(b, row, col, c)
+					pstScores[c] += backward
+					terms.add(termBackwardPawn, c, backward)
+				} else if pt == piece.KnightType {
+					if row != board.StartRow[c]["Piece"] {
+						eval.PieceAdvanced[c][pt]++
+					}
+					rank := int(row)
+					if c == color.Black {
+						rank = 7 - int(row)
+					}
+					if rank >= 3 && rank <= 5 && isKnightOutpost(b, row, col, c) {
+						pstScores[c] += KnightOutpostBonus
+						terms.add(termKnightOutpost, c, KnightOutpostBonus)
+					}
+				} else if pt != piece.KingType {
+					if row != board.StartRow[c]["Piece"] {
+						eval.PieceAdvanced[c][pt]++
 					}
 				}
 			}
 		}
-		// Rook open/semi-open file bonus: second pass after PawnColumns is complete.
-		for row := location.CoordinateType(0); row < board.Height; row++ {
-			for col := location.CoordinateType(0); col < board.Width; col++ {
-				pt, c, ok := b.GetPieceTypeColor(location.NewLocation(row, col))
-				if !ok || pt != piece.RookType {
+	}
+	// Rook open/semi-open file bonus: second pass after PawnColumns is complete.
+	for row := location.CoordinateType(0); row < board.Height; row++ {
+		for col := location.CoordinateType(0); col < board.Width; col++ {
+			pt, c, ok := b.GetPieceTypeColor(location.NewLocation(row, col))
+			if !ok || pt != piece.RookType {
+				continue
+			}
+			friendlyPawns := eval.PawnColumns[c][col] > 0
+			enemyPawns := eval.PawnColumns[c^1][col] > 0
+			if !friendlyPawns && !enemyPawns {
+				pstScores[c] += RookOpenFileBonus
+				terms.add(termRookFile, c, RookOpenFileBonus)
+			} else if !friendlyPawns {
+				pstScores[c] += RookSemiOpenFileBonus
+				terms.add(termRookFile, c, RookSemiOpenFileBonus)
+			}
+			// Rook on 7th rank: penetration into the enemy's pawn zone.
+			// White's 7th = row 6 (rank 7); Black's 7th = row 1 (rank 2).
+			seventhRank := location.CoordinateType(6)
+			if c == color.Black {
+				seventhRank = 1
+			}
+			if row == seventhRank {
+				// Taper: full bonus in middlegame, half in endgame.
+				seventh := RookOnSeventhBonus * phase / 256
+				pstScores[c] += seventh
+				terms.add(termRookOnSeventh, c, seventh)
+			}
+			activity := rookPasserActivityBonus(b, row, col, c)
+			pstScores[c] += activity
+			terms.add(termRookPasserActivity, c, activity)
+		}
+	}
+
+	// Knight passer blockade: bonus for a knight that attacks the square
+	// directly in front of an enemy passed pawn. Discourages the engine from
+	// ignoring defensive knight maneuvers in K+PP vs K+N-type endings.
+	for row := location.CoordinateType(0); row < board.Height; row++ {
+		for col := location.CoordinateType(0); col < board.Width; col++ {
+			pt, c, ok := b.GetPieceTypeColor(location.NewLocation(row, col))
+			if !ok || pt != piece.KnightType {
+				continue
+			}
+			enemy := c ^ 1
+			// enemyForward: direction enemy pawns advance (+1 for White, -1 for Black).
+			enemyForward := 1
+			if enemy == color.Black {
+				enemyForward = -1
+			}
+			for _, d := range knightMoveDeltas {
+				tr := int(row) + d[0]
+				tc := int(col) + d[1]
+				if tr < 0 || tr >= board.Height || tc < 0 || tc >= board.Width {
 					continue
 				}
-				friendlyPawns := eval.PawnColumns[c][col] > 0
-				enemyPawns := eval.PawnColumns[c^1][col] > 0
-				if !friendlyPawns && !enemyPawns {
-					pstScores[c] += RookOpenFileBonus
-				} else if !friendlyPawns {
-					pstScores[c] += RookSemiOpenFileBonus
-				}
-				// Rook on 7th rank: penetration into the enemy's pawn zone.
-				// White's 7th = row 6 (rank 7); Black's 7th = row 1 (rank 2).
-				seventhRank := location.CoordinateType(6)
-				if c == color.Black {
-					seventhRank = 1
+				// The enemy pawn that would be blocked sits one step behind targetRow.
+				pr := tr - enemyForward
+				if pr < 0 || pr >= board.Height {
+					continue
 				}
-				if row == seventhRank {
-					// Taper: full bonus in middlegame, half in endgame.
-					pstScores[c] += RookOnSeventhBonus * phase / 256
+				ept, epc, epok := b.GetPieceTypeColor(location.NewLocation(location.CoordinateType(pr), location.CoordinateType(tc)))
+				if !epok || epc != enemy || ept != piece.PawnType {
+					continue
 				}
-				pstScores[c] += rookPasserActivityBonus(b, row, col, c)
-			}
-		}
-
-		// Knight passer blockade: bonus for a knight that attacks the square
-		// directly in front of an enemy passed pawn. Discourages the engine from
-		// ignoring defensive knight maneuvers in K+PP vs K+N-type endings.
-		for row := location.CoordinateType(0); row < board.Height; row++ {
-			for col := location.CoordinateType(0); col < board.Width; col++ {
-				pt, c, ok := b.GetPieceTypeColor(location.NewLocation(row, col))
-				if !ok || pt != piece.KnightType {
+				pawnRow := location.CoordinateType(pr)
+				pawnCol := location.CoordinateType(tc)
+				if !isPassedPawn(b, pawnRow, pawnCol, enemy) {
 					continue
 				}
-				enemy := c ^ 1
-				// enemyForward: direction enemy pawns advance (+1 for White, -1 for Black).
-				enemyForward := 1
+				// Rank of the enemy pawn from its own back rank (0=start, 6=one step from promo).
+				rank := int(pawnRow)
 				if enemy == color.Black {
-					enemyForward = -1
+					rank = 7 - int(pawnRow)
 				}
-				for _, d := range knightMoveDeltas {
-					tr := int(row) + d[0]
-					tc := int(col) + d[1]
-					if tr < 0 || tr >= board.Height || tc < 0 || tc >= board.Width {
-						continue
-					}
-					// The enemy pawn that would be blocked sits one step behind targetRow.
-					pr := tr - enemyForward
-					if pr < 0 || pr >= board.Height {
-						continue
-					}
-					ept, epc, epok := b.GetPieceTypeColor(location.NewLocation(location.CoordinateType(pr), location.CoordinateType(tc)))
-					if !epok || epc != enemy || ept != piece.PawnType {
-						continue
-					}
-					pawnRow := location.CoordinateType(pr)
-					pawnCol := location.CoordinateType(tc)
-					if !isPassedPawn(b, pawnRow, pawnCol, enemy) {
-						continue
-					}
-					// Rank of the enemy pawn from its own back rank (0=start, 6=one step from promo).
-					rank := int(pawnRow)
-					if enemy == color.Black {
-						rank = 7 - int(pawnRow)
-					}
-					if rank >= 3 {
-						pstScores[c] += KnightPasserBlockadeBonus * rank / 6
-					}
+				if rank >= 3 {
+					blockade := KnightPasserBlockadeBonus * rank / 6
+					pstScores[c] += blockade
+					terms.add(termKnightBlockade, c, blockade)
 				}
 			}
 		}
+	}
 
-		// Connected passed pawn bonus: apply after all pieces have been scanned
-		// so passedPawnCols is fully populated.
-		for pColor := byte(0); pColor < color.NumColors; pColor++ {
-			for col := 1; col < board.Width; col++ {
-				if passedPawnCols[pColor][col] && passedPawnCols[pColor][col-1] {
-					// Both col and col-1 have a passed pawn — they're connected.
-					pstScores[pColor] += ConnectedPasserBonus * 2 // once per pawn
-				}
+	// Connected passed pawn bonus: apply after all pieces have been scanned
+	// so passedPawnCols is fully populated.
+	for pColor := byte(0); pColor < color.NumColors; pColor++ {
+		for col := 1; col < board.Width; col++ {
+			if passedPawnCols[pColor][col] && passedPawnCols[pColor][col-1] {
+				// Both col and col-1 have a passed pawn — they're connected.
+				pstScores[pColor] += ConnectedPasserBonus * 2 // once per pawn
+				terms.add(termConnectedPassers, pColor, ConnectedPasserBonus*2)
 			}
 		}
+	}
 
-		for pColor := byte(0); pColor < color.NumColors; pColor++ {
-			score := 0
-			for pieceType, value := range PieceValue {
-				score += PawnValueWeight * value * int(eval.PieceCounts[pColor][pieceType])
-				score += PieceAdvanceWeight * int(eval.PieceAdvanced[pColor][pieceType])
+	for pColor := byte(0); pColor < color.NumColors; pColor++ {
+		score := 0
+		for pieceType, value := range PieceValue {
+			material := PawnValueWeight * value * int(eval.PieceCounts[pColor][pieceType])
+			advance := PieceAdvanceWeight * int(eval.PieceAdvanced[pColor][pieceType])
+			score += material + advance
+			terms.add(termMaterial, pColor, material)
+			terms.add(termPieceAdvance, pColor, advance)
+		}
+		score += pstScores[pColor]
+		if b.GetFlag(board.FlagCastled, pColor) {
+			score += KingCastledWeight
+			terms.add(termCastling, pColor, KingCastledWeight)
+		} else {
+			// has not castled
+			if b.GetFlag(board.FlagKingMoved, pColor) {
+				score += KingDisplacedWeight
+				terms.add(termCastling, pColor, KingDisplacedWeight)
 			}
-			score += pstScores[pColor]
-			if b.GetFlag(board.FlagCastled, pColor) {
-				score += KingCastledWeight
-			} else {
-				// has not castled
-				if b.GetFlag(board.FlagKingMoved, pColor) {
-					score += KingDisplacedWeight
-				}
-				if b.GetFlag(board.FlagLeftRookMoved, pColor) || b.GetFlag(board.FlagRightRookMoved, pColor) {
-					score += RookDisplacedWeight
-				}
-				// King-in-center urgency: as more pieces are developed, the penalty for
-				// delaying castling grows. 5 cp per developed minor/major piece (max ~40 cp).
-				// Only applies in middlegame (phase > 128) to avoid distorting endgame evals.
-				if phase > 128 {
-					developed := int(eval.PieceAdvanced[pColor][piece.KnightType]) +
-						int(eval.PieceAdvanced[pColor][piece.BishopType]) +
-						int(eval.PieceAdvanced[pColor][piece.RookType])
-					score -= developed * 5
-				}
+			if b.GetFlag(board.FlagLeftRookMoved, pColor) || b.GetFlag(board.FlagRightRookMoved, pColor) {
+				score += RookDisplacedWeight
+				terms.add(termCastling, pColor, RookDisplacedWeight)
 			}
-			if b.IsKingInCheck(pColor) {
-				score += KingCheckedWeight
+			// King-in-center urgency: as more pieces are developed, the penalty for
+			// delaying castling grows. 5 cp per developed minor/major piece (max ~40 cp).
+			// Only applies in middlegame (phase > 128) to avoid distorting endgame evals.
+			if phase > 128 {
+				developed := int(eval.PieceAdvanced[pColor][piece.KnightType]) +
+					int(eval.PieceAdvanced[pColor][piece.BishopType]) +
+					int(eval.PieceAdvanced[pColor][piece.RookType])
+				score -= developed * 5
+				terms.add(termCastling, pColor, -developed*5)
 			}
-			score += kingSafety(b, pColor)
-			// King attack zone: count enemy-attacked squares in the 3×3 ring around our king.
-			// Each additional attacked square triggers a penalty; exponential past 2 squares
-			// so a concentrated attack is penalized more severely than a diffuse one.
-			// Only relevant in the middlegame (phase > 64).
-			if phase > 64 {
-				enemy := pColor ^ 1
-				kingLoc := b.KingLocations[pColor]
-				var kingRing board.BitBoard
-				for dr := int8(-1); dr <= 1; dr++ {
-					for dc := int8(-1); dc <= 1; dc++ {
-						if ringLoc, ok := kingLoc.AddRelative(location.RelativeLocation{Row: dr, Col: dc}); ok {
-							kingRing.SetLocation(ringLoc)
-						}
+		}
+		if b.IsKingInCheck(pColor) {
+			score += KingCheckedWeight
+			terms.add(termInCheck, pColor, KingCheckedWeight)
+		}
+		safety := kingSafety(b, pColor)
+		score += safety
+		terms.add(termKingSafety, pColor, safety)
+		// King attack zone: count enemy-attacked squares in the 3×3 ring around our king.
+		// Each additional attacked square triggers a penalty; exponential past 2 squares
+		// so a concentrated attack is penalized more severely than a diffuse one.
+		// Only relevant in the middlegame (phase > 64).
+		if phase > 64 {
+			enemy := pColor ^ 1
+			kingLoc := b.KingLocations[pColor]
+			var kingRing board.BitBoard
+			for dr := int8(-1); dr <= 1; dr++ {
+				for dc := int8(-1); dc <= 1; dc++ {
+					if ringLoc, ok := kingLoc.AddRelative(location.RelativeLocation{Row: dr, Col: dc}); ok {
+						kingRing.SetLocation(ringLoc)
 					}
 				}
-				attackedInRing := int(hamming.CountBitsUint64(uint64(kingRing.IntersectBitBoards(combinedAttacks[enemy]))))
-				if attackedInRing >= 2 {
-					// Quadratic scaling: 2 squares = 1×weight, 3 = 3×, 4 = 6×, ...
-					score -= KingAttackZoneWeight * attackedInRing * (attackedInRing - 1) / 2
-				}
 			}
-			for column := location.CoordinateType(0); column < board.Width; column++ {
-				cnt := eval.PawnColumns[pColor][column]
-				if cnt == 0 {
-					continue
-				}
-				// Doubled pawn penalty grows exponentially per extra pawn on the file.
-				score += PawnStructureWeight * PawnDuplicateWeight * ((1 << (cnt - 1)) - 1)
-				// Isolated pawn: no friendly pawns on either adjacent file.
-				leftEmpty := column == 0 || eval.PawnColumns[pColor][column-1] == 0
-				rightEmpty := column == board.Width-1 || eval.PawnColumns[pColor][column+1] == 0
-				if leftEmpty && rightEmpty {
-					score += IsolatedPawnPenalty * int(cnt)
-				}
+			attackedInRing := int(hamming.CountBitsUint64(uint64(kingRing.IntersectBitBoards(combinedAttacks[enemy]))))
+			if attackedInRing >= 2 {
+				// Quadratic scaling: 2 squares = 1×weight, 3 = 3×, 4 = 6×, ...
+				attack := KingAttackZoneWeight * attackedInRing * (attackedInRing - 1) / 2
+				score -= attack
+				terms.add(termKingAttackZone, pColor, -attack)
 			}
-			goalRow := board.StartRow[pColor^1]["Piece"]
-			for row := location.CoordinateType(0); row < board.Height; row++ {
-				// boost score linearly for pawns that are closer to enemy start row
-				dist := int8(goalRow) - int8(row)
-				if dist < 0 {
-					dist = -dist
-				}
-				// height - 1 is distance from pawn start
-				progress := int(board.Height - 1 - dist)
-				// normalize for number of pawns 8
-				score += (PawnStructureWeight * PawnAdvancedWeight * progress * int(eval.PawnRows[pColor][row])) / 8
+		}
+		for column := location.CoordinateType(0); column < board.Width; column++ {
+			cnt := eval.PawnColumns[pColor][column]
+			if cnt == 0 {
+				continue
 			}
-			// pseudo-legal mobility: attackable squares (no board copies, no willMoveLeaveKingInCheck).
-			// Weight intentionally lower than the old legal-moves weight because pseudo-legal counts
-			// defended friendly squares too, which inflates the count vs strictly legal moves.
-			score += PieceNumMovesWeight * int(eval.NumMoves[pColor])
-
-			// Bishop pair bonus: tapered by total pawn count so it's weaker in closed positions.
-			if eval.PieceCounts[pColor][piece.BishopType] >= 2 {
-				totalPawns := int(eval.PieceCounts[color.White][piece.PawnType]) + int(eval.PieceCounts[color.Black][piece.PawnType])
-				// Base bonus always applies; the open-position term adds up to
-				// BishopPairOpenBonus more as pawns leave the board.
-				// e.g. 30cp at 16 pawns, ~33cp at 14, ~42cp at 8, 55cp at 0.
-				score += BishopPairBonus + BishopPairOpenBonus*(16-totalPawns)/16
+			// Doubled pawn penalty grows exponentially per extra pawn on the file.
+			doubled := PawnStructureWeight * PawnDuplicateWeight * ((1 << (cnt - 1)) - 1)
+			score += doubled
+			terms.add(termDoubledPawns, pColor, doubled)
+			// Isolated pawn: no friendly pawns on either adjacent file.
+			leftEmpty := column == 0 || eval.PawnColumns[pColor][column-1] == 0
+			rightEmpty := column == board.Width-1 || eval.PawnColumns[pColor][column+1] == 0
+			if leftEmpty && rightEmpty {
+				score += IsolatedPawnPenalty * int(cnt)
+				terms.add(termIsolatedPawns, pColor, IsolatedPawnPenalty*int(cnt))
 			}
-
-			if pColor == whoMoves {
-				eval.TotalScore += score
-			} else {
-				eval.TotalScore -= score
+		}
+		goalRow := board.StartRow[pColor^1]["Piece"]
+		for row := location.CoordinateType(0); row < board.Height; row++ {
+			// boost score linearly for pawns that are closer to enemy start row
+			dist := int8(goalRow) - int8(row)
+			if dist < 0 {
+				dist = -dist
 			}
+			// height - 1 is distance from pawn start
+			progress := int(board.Height - 1 - dist)
+			// normalize for number of pawns 8
+			pawnAdvance := (PawnStructureWeight * PawnAdvancedWeight * progress * int(eval.PawnRows[pColor][row])) / 8
+			score += pawnAdvance
+			terms.add(termPawnAdvance, pColor, pawnAdvance)
 		}
+		// pseudo-legal mobility: attackable squares (no board copies, no willMoveLeaveKingInCheck).
+		// Weight intentionally lower than the old legal-moves weight because pseudo-legal counts
+		// defended friendly squares too, which inflates the count vs strictly legal moves.
+		mobility := PieceNumMovesWeight * int(eval.NumMoves[pColor])
+		score += mobility
+		terms.add(termMobility, pColor, mobility)
 
-		// Mop-up heuristic: when one side has a large material advantage, reward
-		// pushing the losing king to the board edge and keeping kings close together.
-		whiteMat, blackMat := 0, 0
-		for pt, v := range PieceValue {
-			if pt == piece.KingType {
-				continue
-			}
-			whiteMat += v * int(eval.PieceCounts[color.White][pt])
-			blackMat += v * int(eval.PieceCounts[color.Black][pt])
+		// Bishop pair bonus: tapered by total pawn count so it's weaker in closed positions.
+		if eval.PieceCounts[pColor][piece.BishopType] >= 2 {
+			totalPawns := int(eval.PieceCounts[color.White][piece.PawnType]) + int(eval.PieceCounts[color.Black][piece.PawnType])
+			// Base bonus always applies; the open-position term adds up to
+			// BishopPairOpenBonus more as pawns leave the board.
+			// e.g. 30cp at 16 pawns, ~33cp at 14, ~42cp at 8, 55cp at 0.
+			pair := BishopPairBonus + BishopPairOpenBonus*(16-totalPawns)/16
+			score += pair
+			terms.add(termBishopPair, pColor, pair)
 		}
-		advantage := whiteMat - blackMat
-		if advantage >= MopupThreshold || advantage <= -MopupThreshold {
-			var winner, loser byte
-			if advantage > 0 {
-				winner, loser = color.White, color.Black
-			} else {
-				winner, loser = color.Black, color.White
-			}
-			loserKing := b.KingLocations[loser]
-			winnerKing := b.KingLocations[winner]
-			loserRow, loserCol := int(loserKing.GetRow()), int(loserKing.GetCol())
-			winRow, winCol := int(winnerKing.GetRow()), int(winnerKing.GetCol())
-			// distance from center (0–6): higher = more towards edge = better for winner
-			edgeBonus := abs(loserRow-3) + abs(loserCol-3)
-			// Manhattan distance between kings (0–14): lower = better for winner
-			kingDist := abs(winRow-loserRow) + abs(winCol-loserCol)
-			mopup := MopupWeight * (edgeBonus + (14 - kingDist))
-			if winner == whoMoves {
-				eval.TotalScore += mopup
-			} else {
-				eval.TotalScore -= mopup
-			}
+
+		if pColor == whoMoves {
+			eval.TotalScore += score
+		} else {
+			eval.TotalScore -= score
 		}
+	}
 
-		// King-to-passed-pawn support: in endgames, the winning king needs to escort
-		// its own passed pawns. Reward proximity (max Manhattan distance 14 → 0 bonus,
-		// distance 0 → +14 bonus). Also reward the defending king for approaching
-		// and blockading enemy passers; otherwise rook endings drift into passive
-		// checks while the enemy king/pawn net advances.
-		const kingPasserPhaseLimit = 192
-		if phase < kingPasserPhaseLimit {
-			endgameFactor := kingPasserPhaseLimit - phase // 0 near middlegame, max at full endgame
-			for row := location.CoordinateType(0); row < board.Height; row++ {
-				for col := location.CoordinateType(0); col < board.Width; col++ {
-					pt, c, ok := b.GetPieceTypeColor(location.NewLocation(row, col))
-					if !ok || pt != piece.PawnType {
-						continue
-					}
-					if !isPassedPawn(b, row, col, c) {
-						continue
-					}
-					kingLoc := b.KingLocations[c]
-					dist := abs(int(kingLoc.GetRow())-int(row)) + abs(int(kingLoc.GetCol())-int(col))
-					bonus := KingPassedPawnSupportWeight * (14 - dist) * endgameFactor / kingPasserPhaseLimit
-					if c == whoMoves {
-						eval.TotalScore += bonus
-					} else {
-						eval.TotalScore -= bonus
-					}
+	if winner, mopup := mopUpBonus(b, eval.PieceCounts); mopup != 0 {
+		terms.add(termMopUp, winner, mopup)
+		if winner == whoMoves {
+			eval.TotalScore += mopup
+		} else {
+			eval.TotalScore -= mopup
+		}
+	}
 
-					enemy := c ^ 1
-					enemyKing := b.KingLocations[enemy]
-					defenderDist := abs(int(enemyKing.GetRow())-int(row)) + abs(int(enemyKing.GetCol())-int(col))
-					rank := int(row)
-					if c == color.Black {
-						rank = 7 - int(row)
-					}
-					defense := KingPassedPawnDefenseWeight * (14 - defenderDist) * rank * endgameFactor / (6 * kingPasserPhaseLimit)
-					forward := 1
-					if c == color.Black {
-						forward = -1
-					}
-					blockRow := int(row) + forward
-					if blockRow >= 0 && blockRow < board.Height {
-						blockDist := abs(int(enemyKing.GetRow())-blockRow) + abs(int(enemyKing.GetCol())-int(col))
-						if blockDist <= 1 {
-							defense += KingPasserBlockadeBonus * rank * endgameFactor / (6 * kingPasserPhaseLimit)
-						}
-					}
-					if enemy == whoMoves {
-						eval.TotalScore += defense
-					} else {
-						eval.TotalScore -= defense
+	// King-to-passed-pawn support: in endgames, the winning king needs to escort
+	// its own passed pawns. Reward proximity (max Manhattan distance 14 → 0 bonus,
+	// distance 0 → +14 bonus). Also reward the defending king for approaching
+	// and blockading enemy passers; otherwise rook endings drift into passive
+	// checks while the enemy king/pawn net advances.
+	const kingPasserPhaseLimit = 192
+	if phase < kingPasserPhaseLimit {
+		endgameFactor := kingPasserPhaseLimit - phase // 0 near middlegame, max at full endgame
+		for row := location.CoordinateType(0); row < board.Height; row++ {
+			for col := location.CoordinateType(0); col < board.Width; col++ {
+				pt, c, ok := b.GetPieceTypeColor(location.NewLocation(row, col))
+				if !ok || pt != piece.PawnType {
+					continue
+				}
+				if !isPassedPawn(b, row, col, c) {
+					continue
+				}
+				kingLoc := b.KingLocations[c]
+				dist := abs(int(kingLoc.GetRow())-int(row)) + abs(int(kingLoc.GetCol())-int(col))
+				bonus := KingPassedPawnSupportWeight * (14 - dist) * endgameFactor / kingPasserPhaseLimit
+				terms.add(termKingPasserSupport, c, bonus)
+				if c == whoMoves {
+					eval.TotalScore += bonus
+				} else {
+					eval.TotalScore -= bonus
+				}
+
+				enemy := c ^ 1
+				enemyKing := b.KingLocations[enemy]
+				defenderDist := abs(int(enemyKing.GetRow())-int(row)) + abs(int(enemyKing.GetCol())-int(col))
+				rank := int(row)
+				if c == color.Black {
+					rank = 7 - int(row)
+				}
+				defense := KingPassedPawnDefenseWeight * (14 - defenderDist) * rank * endgameFactor / (6 * kingPasserPhaseLimit)
+				forward := 1
+				if c == color.Black {
+					forward = -1
+				}
+				blockRow := int(row) + forward
+				if blockRow >= 0 && blockRow < board.Height {
+					blockDist := abs(int(enemyKing.GetRow())-blockRow) + abs(int(enemyKing.GetCol())-int(col))
+					if blockDist <= 1 {
+						defense += KingPasserBlockadeBonus * rank * endgameFactor / (6 * kingPasserPhaseLimit)
 					}
 				}
+				terms.add(termKingPasserDefense, enemy, defense)
+				if enemy == whoMoves {
+					eval.TotalScore += defense
+				} else {
+					eval.TotalScore -= defense
+				}
 			}
 		}
 	}
-	return eval
+}
+
+// mopUpBonus is the mop-up heuristic: when one side has a large material
+// advantage, reward pushing the losing king to the board edge and keeping kings
+// close together. Returns a zero bonus when material is roughly level.
+func mopUpBonus(b *board.Board, pieceCounts pieceTypeCounts) (winner color.Color, bonus int) {
+	whiteMat, blackMat := 0, 0
+	for pt, v := range PieceValue {
+		if pt == piece.KingType {
+			continue
+		}
+		whiteMat += v * int(pieceCounts[color.White][pt])
+		blackMat += v * int(pieceCounts[color.Black][pt])
+	}
+	advantage := whiteMat - blackMat
+	if advantage < MopupThreshold && advantage > -MopupThreshold {
+		return 0, 0
+	}
+	loser := color.Black
+	if advantage < 0 {
+		winner, loser = color.Black, color.White
+	}
+	loserKing := b.KingLocations[loser]
+	winnerKing := b.KingLocations[winner]
+	loserRow, loserCol := int(loserKing.GetRow()), int(loserKing.GetCol())
+	winRow, winCol := int(winnerKing.GetRow()), int(winnerKing.GetCol())
+	// distance from center (0–6): higher = more towards edge = better for winner
+	edgeBonus := abs(loserRow-3) + abs(loserCol-3)
+	// Manhattan distance between kings (0–14): lower = better for winner
+	kingDist := abs(winRow-loserRow) + abs(winCol-loserCol)
+	return winner, MopupWeight * (edgeBonus + (14 - kingDist))
 }
 
 func abs(x int) int {
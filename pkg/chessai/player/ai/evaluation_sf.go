@@ -244,6 +244,34 @@ type sfEval struct {
 // the Stockfish-classical-style hand-crafted evaluation. Terminal positions
 // (checkmate/stalemate/draws) are handled by the caller before this is reached.
 func evaluateStockfishClassicScore(b *board.Board, whoMoves color.Color) int {
+	e := newSFEval(b)
+
+	// --- Per-color terms (positive = good for that color). ---
+	var total sfScore // from White's perspective
+	for _, us := range []color.Color{color.White, color.Black} {
+		s := e.colorScore(us)
+		if us == color.White {
+			total = total.add(s)
+		} else {
+			total = total.sub(s)
+		}
+	}
+
+	// --- Initiative: nudges the endgame value by position complexity. ---
+	total.eg += e.initiative(total.eg)
+
+	// --- Taper MG/EG by phase, then normalize to ~100cp/pawn. ---
+	cp := e.taper(total)
+
+	if whoMoves == color.Black {
+		cp = -cp
+	}
+	return cp
+}
+
+// newSFEval runs the shared setup passes (occupancy, piece counts, attack
+// bitboards, phase and mobility area) that every per-color term reads from.
+func newSFEval(b *board.Board) *sfEval {
 	e := &sfEval{b: b}
 
 	// --- Pass 1: occupancy, piece counts, per-piece attack bitboards. ---
@@ -313,29 +341,13 @@ func evaluateStockfishClassicScore(b *board.Board, whoMoves color.Color) int {
 		}
 		e.mobilityArea[us] = ^excluded
 	}
+	return e
+}
 
-	// --- Per-color terms (positive = good for that color). ---
-	var total sfScore // from White's perspective
-	for _, us := range []color.Color{color.White, color.Black} {
-		s := e.colorScore(us)
-		if us == color.White {
-			total = total.add(s)
-		} else {
-			total = total.sub(s)
-		}
-	}
-
-	// --- Initiative: nudges the endgame value by position complexity. ---
-	total.eg += e.initiative(total.eg)
-
-	// --- Taper MG/EG by phase, then normalize to ~100cp/pawn. ---
-	tapered := (total.mg*e.phase + total.eg*(256-e.phase)) / 256
-	cp := tapered * 100 / sfNormalizeToPawn
-
-	if whoMoves == color.Black {
-		cp = -cp
-	}
-	return cp
+// taper interpolates a packed score by game phase and normalizes it to ~100cp/pawn.
+func (e *sfEval) taper(s sfScore) int {
+	tapered := (s.mg*e.phase + s.eg*(256-e.phase)) / 256
+	return tapered * 100 / sfNormalizeToPawn
 }
 
 // pcGeneric is one piece on the board plus its precomputed attack set.
@@ -286,6 +286,7 @@ func (j *Jamboree) iterativeJamboree(b *board.Board, previousMove *board.LastMov
 			best = newBest
 			j.player.LastSearchDepth = j.currentSearchDepth
 			j.player.printer <- fmt.Sprintf("Best D:%d M:%s\n", j.player.LastSearchDepth, best.Move)
+			j.player.reportSearchInfo(b, previousMove, &best, start)
 		} else {
 			j.player.LastSearchDepth = j.currentSearchDepth - iterativeIncrement
 			j.player.printer <- fmt.Sprintf("%s hard abort! evaluated to depth %d\n", j.GetName(), j.player.LastSearchDepth)
@@ -72,6 +72,7 @@ func (smp *LazySMP) iterativeLazySMP(b *board.Board, previousMove *board.LastMov
 			best = result
 			smp.player.LastSearchDepth = smp.currentSearchDepth
 			smp.rootMoves[0] = threadRootMove{move: best.Move, score: best.Score, depth: smp.currentSearchDepth}
+			smp.player.reportSearchInfo(b, previousMove, &best, start)
 		}
 	}
 
@@ -156,6 +157,7 @@ func (smp *LazySMP) iterativeLazySMP(b *board.Board, previousMove *board.LastMov
 			best = smp.threadVote()
 			smp.player.LastSearchDepth = smp.currentSearchDepth
 			smp.player.printer <- fmt.Sprintf("Best D:%d M:%s Score:%d\n", smp.player.LastSearchDepth, best.Move, best.Score)
+			smp.player.reportSearchInfo(b, previousMove, &best, start)
 		} else {
 			smp.player.LastSearchDepth = smp.currentSearchDepth - iterativeIncrement
 			smp.player.printer <- fmt.Sprintf("%s hard abort! evaluated to depth %d\n", smp.GetName(), smp.player.LastSearchDepth)
@@ -0,0 +1,367 @@
+package ai
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+)
+
+// Monte Carlo Tree Search.
+//
+// Each playout descends the shared tree by PUCT (or plain UCT with
+// DisablePriors), expands the leaf it reaches with GetAllMoves, and backs up a
+// value in [0,1] for the side that moved into each node. Random rollouts are
+// useless in chess, so the leaf value comes from a quiescence search squashed
+// through a logistic curve instead. Threads share one tree; virtual loss makes
+// concurrent playouts spread over different lines instead of piling onto the
+// same leaf. The subtree for the position after our move and the opponent's
+// reply is kept for the next search.
+
+const (
+	defaultMCTSPUCTExploration = 1.5
+	defaultMCTSUCTExploration  = math.Sqrt2
+	// defaultMCTSIterations bounds a search when MaxThinkTime is unset.
+	defaultMCTSIterations = 20_000
+	// defaultMCTSMaxTreeNodes caps memory; once reached, playouts stop
+	// expanding and the search ends.
+	defaultMCTSMaxTreeNodes = 2_000_000
+
+	// mctsEvalWindow limits the quiescence search at leaves: scores beyond
+	// ±mctsEvalWindow are already saturated by the logistic value curve.
+	mctsEvalWindow = 30 * PawnValueWeight
+	// mctsValueScale is the centipawn score that maps to a ~73% value.
+	mctsValueScale = 4 * PawnValueWeight
+	// mctsFirstPlayValue is Q for unvisited children under PUCT, slightly
+	// pessimistic so the prior decides which untried move goes first.
+	mctsFirstPlayValue = 0.4
+	mctsVirtualLoss    = 3
+	// mctsValueUnit is the fixed-point scale for atomic value sums.
+	mctsValueUnit = 1 << 20
+)
+
+const (
+	mctsUnexpanded uint32 = iota
+	mctsExpanded
+	mctsTerminal
+)
+
+type MCTS struct {
+	// NumThreads defaults to runtime.NumCPU().
+	NumThreads int
+	// Exploration is the UCT/PUCT constant; 0 selects the mode's default.
+	Exploration float64
+	// DisablePriors switches PUCT selection to plain UCT.
+	DisablePriors bool
+	// MaxIterations bounds playouts when the player has no MaxThinkTime
+	// (0 selects defaultMCTSIterations).
+	MaxIterations int
+	// MaxTreeNodes bounds the tree (0 selects defaultMCTSMaxTreeNodes).
+	MaxTreeNodes int
+
+	tree      *mctsNode
+	treeBoard *board.Board
+	treeSide  color.Color
+	nodes     int64
+}
+
+type mctsNode struct {
+	move  location.Move
+	prior float64
+
+	// state is published atomically after children or terminalValue are set.
+	state         uint32
+	mu            sync.Mutex
+	children      []*mctsNode
+	terminalValue float64
+
+	visits   int64
+	virtual  int64
+	valueSum int64 // fixed-point, from the perspective of the side that played move
+}
+
+func (m *MCTS) GetName() string {
+	return AlgorithmMCTS
+}
+
+func (m *MCTS) GetBestMove(p *AIPlayer, b *board.Board, previousMove *board.LastMove) *ScoredMove {
+	if m.NumThreads <= 0 {
+		m.NumThreads = runtime.NumCPU()
+		log.Printf("MCTS defaulting to %d threads\n", m.NumThreads)
+	}
+	root := b.Copy()
+	root.CacheGetAllMoves = false
+	root.CacheGetAllAttackableMoves = false
+
+	tree := m.reuseTree(root, p.PlayerColor)
+	if tree == nil {
+		tree = &mctsNode{}
+		atomic.StoreInt64(&m.nodes, 1)
+	}
+	m.tree, m.treeBoard, m.treeSide = tree, root, p.PlayerColor
+	m.evaluateLeaf(p, tree, root, previousMove, p.PlayerColor, 0)
+	if atomic.LoadUint32(&tree.state) != mctsExpanded {
+		return &ScoredMove{Score: p.EvaluateBoard(root, p.PlayerColor).TotalScore}
+	}
+
+	start := time.Now()
+	p.setAbort(false)
+	thinking, done := make(chan bool), make(chan bool, 1)
+	go p.trackThinkTime(thinking, done, start)
+	maxIterations := int64(-1)
+	if p.MaxThinkTime == 0 {
+		maxIterations = defaultMCTSIterations
+		if m.MaxIterations > 0 {
+			maxIterations = int64(m.MaxIterations)
+		}
+	}
+	var iterations int64
+	var wg sync.WaitGroup
+	for t := 0; t < m.NumThreads; t++ {
+		wg.Add(1)
+		// Quiescence runs on a per-thread player, like ABDADA's workers.
+		worker := p.NewRootWorkerPlayer()
+		go func() {
+			defer wg.Done()
+			for !p.isAborted() && atomic.LoadInt64(&m.nodes) < m.maxTreeNodes() {
+				if maxIterations >= 0 && atomic.AddInt64(&iterations, 1) > maxIterations {
+					return
+				}
+				m.playout(worker, tree, root, previousMove)
+			}
+		}()
+	}
+	wg.Wait()
+	close(thinking)
+	<-done
+
+	best := mctsBestChild(tree)
+	p.LastSearchDepth = mctsPrincipalDepth(tree)
+	p.printer <- fmt.Sprintf("MCTS: %d playouts, %d nodes, best %s visits=%d q=%.3f depth=%d\n",
+		atomic.LoadInt64(&tree.visits), atomic.LoadInt64(&m.nodes), best.move,
+		atomic.LoadInt64(&best.visits), best.q(), p.LastSearchDepth)
+	return &ScoredMove{Move: best.move, Score: mctsScore(best.q())}
+}
+
+func (m *MCTS) maxTreeNodes() int64 {
+	if m.MaxTreeNodes > 0 {
+		return int64(m.MaxTreeNodes)
+	}
+	return defaultMCTSMaxTreeNodes
+}
+
+func (m *MCTS) exploration() float64 {
+	switch {
+	case m.Exploration > 0:
+		return m.Exploration
+	case m.DisablePriors:
+		return defaultMCTSUCTExploration
+	default:
+		return defaultMCTSPUCTExploration
+	}
+}
+
+// reuseTree returns the subtree for b if it is the previous search root or an
+// expanded grandchild of it (our move, then the opponent's reply); nil
+// otherwise. A grandchild is only terminal relative to the old root (a
+// repetition or draw rule counted from there), so one is never reused.
+func (m *MCTS) reuseTree(b *board.Board, side color.Color) *mctsNode {
+	if m.tree == nil || m.treeSide != side {
+		return nil
+	}
+	h := b.Hash()
+	if m.treeBoard.Hash() == h {
+		return m.tree
+	}
+	for _, child := range m.tree.expandedChildren() {
+		childBoard := m.treeBoard.Copy()
+		board.MakeMove(&child.move, childBoard)
+		for _, grandchild := range child.expandedChildren() {
+			gb := childBoard.Copy()
+			board.MakeMove(&grandchild.move, gb)
+			if gb.Hash() == h {
+				if atomic.LoadUint32(&grandchild.state) != mctsExpanded {
+					return nil
+				}
+				atomic.StoreInt64(&m.nodes, grandchild.countNodes())
+				return grandchild
+			}
+		}
+	}
+	return nil
+}
+
+func (m *MCTS) playout(p *AIPlayer, root *mctsNode, rootBoard *board.Board, previousMove *board.LastMove) {
+	b := rootBoard.Copy()
+	last := previousMove
+	side := m.treeSide
+	path := []*mctsNode{root}
+	node := root
+	for atomic.LoadUint32(&node.state) == mctsExpanded {
+		node = m.selectChild(node)
+		atomic.AddInt64(&node.virtual, mctsVirtualLoss)
+		path = append(path, node)
+		last = board.MakeMove(&node.move, b)
+		side ^= 1
+	}
+	// value is for the side to move at the leaf; each node stores it for the
+	// side that moved into it.
+	value := 1 - m.evaluateLeaf(p, node, b, last, side, len(path)-1)
+	for i := len(path) - 1; i >= 0; i-- {
+		n := path[i]
+		atomic.AddInt64(&n.visits, 1)
+		atomic.AddInt64(&n.valueSum, int64(value*mctsValueUnit))
+		if i > 0 {
+			atomic.AddInt64(&n.virtual, -mctsVirtualLoss)
+		}
+		value = 1 - value
+	}
+}
+
+func (m *MCTS) selectChild(n *mctsNode) *mctsNode {
+	c := m.exploration()
+	parentVisits := float64(atomic.LoadInt64(&n.visits) + atomic.LoadInt64(&n.virtual) + 1)
+	var best *mctsNode
+	bestScore := math.Inf(-1)
+	for _, child := range n.children {
+		visits := float64(atomic.LoadInt64(&child.visits) + atomic.LoadInt64(&child.virtual))
+		var score float64
+		if m.DisablePriors {
+			if visits == 0 {
+				// UCT tries every child once, in move-generation order.
+				return child
+			}
+			score = float64(atomic.LoadInt64(&child.valueSum))/mctsValueUnit/visits +
+				c*math.Sqrt(math.Log(parentVisits)/visits)
+		} else {
+			q := mctsFirstPlayValue
+			if visits > 0 {
+				q = float64(atomic.LoadInt64(&child.valueSum)) / mctsValueUnit / visits
+			}
+			score = q + c*child.prior*math.Sqrt(parentVisits)/(1+visits)
+		}
+		if score > bestScore {
+			best, bestScore = child, score
+		}
+	}
+	return best
+}
+
+// evaluateLeaf returns n's value for side (to move at n) and expands n on its
+// first visit. Terminal positions are cached on the node.
+func (m *MCTS) evaluateLeaf(p *AIPlayer, n *mctsNode, b *board.Board, last *board.LastMove, side color.Color, ply int) float64 {
+	if atomic.LoadUint32(&n.state) == mctsTerminal {
+		return n.terminalValue
+	}
+	moves := b.GetAllMoves(side, last)
+	terminal, value := false, 0.5
+	switch {
+	case ply > 0 && (b.CurrentPositionRepeats >= 1 || b.MovesSinceNoDraw >= 100 || b.IsInsufficientMaterial()):
+		terminal = true
+	case len(*moves) == 0:
+		terminal = true
+		if b.IsKingInCheck(side) {
+			value = 0
+		}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if terminal {
+		n.terminalValue = value
+		atomic.StoreUint32(&n.state, mctsTerminal)
+		return value
+	}
+	if atomic.LoadUint32(&n.state) == mctsUnexpanded && atomic.LoadInt64(&m.nodes) < m.maxTreeNodes() {
+		n.children = m.newChildren(b, *moves, side)
+		atomic.AddInt64(&m.nodes, int64(len(n.children)))
+		atomic.StoreUint32(&n.state, mctsExpanded)
+	}
+	score := p.Quiesce(b, -mctsEvalWindow, mctsEvalWindow, side, last)
+	return 1 / (1 + math.Exp(-float64(score)/mctsValueScale))
+}
+
+// newChildren builds n's children with move-ordering priors: promotions and
+// SEE-winning captures first, losing captures last.
+func (m *MCTS) newChildren(b *board.Board, moves []location.Move, side color.Color) []*mctsNode {
+	children := make([]*mctsNode, len(moves))
+	total := 0.0
+	for i, move := range moves {
+		weight := 1.0
+		if promo, _ := move.End.GetPawnPromotion(); promo {
+			weight += 5
+		}
+		if b.GetPiece(move.End) != nil {
+			if see := b.SEE(move, side); see >= 0 {
+				weight += 2 + math.Min(float64(see)/PawnValueWeight, 8)
+			} else {
+				weight = 0.5
+			}
+		}
+		children[i] = &mctsNode{move: move, prior: weight}
+		total += weight
+	}
+	for _, child := range children {
+		child.prior /= total
+	}
+	return children
+}
+
+func (n *mctsNode) expandedChildren() []*mctsNode {
+	if atomic.LoadUint32(&n.state) != mctsExpanded {
+		return nil
+	}
+	return n.children
+}
+
+func (n *mctsNode) countNodes() int64 {
+	count := int64(1)
+	for _, child := range n.expandedChildren() {
+		count += child.countNodes()
+	}
+	return count
+}
+
+func (n *mctsNode) q() float64 {
+	visits := atomic.LoadInt64(&n.visits)
+	if visits == 0 {
+		return 0.5
+	}
+	return float64(atomic.LoadInt64(&n.valueSum)) / mctsValueUnit / float64(visits)
+}
+
+// mctsBestChild picks the most visited child, breaking ties by value.
+func mctsBestChild(n *mctsNode) *mctsNode {
+	var best *mctsNode
+	for _, child := range n.children {
+		if best == nil || child.visits > best.visits || (child.visits == best.visits && child.q() > best.q()) {
+			best = child
+		}
+	}
+	return best
+}
+
+func mctsPrincipalDepth(n *mctsNode) int {
+	depth := 0
+	for len(n.expandedChildren()) > 0 {
+		n = mctsBestChild(n)
+		if n.visits == 0 {
+			break
+		}
+		depth++
+	}
+	return depth
+}
+
+// mctsScore inverts the value curve to report a centipawn score.
+func mctsScore(q float64) int {
+	q = math.Max(0.001, math.Min(0.999, q))
+	return int(-mctsValueScale * math.Log(1/q-1))
+}
@@ -0,0 +1,93 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+)
+
+func newMCTSTestPlayer(m *MCTS) *AIPlayer {
+	p := NewAIPlayer(color.White, m)
+	p.MaxThinkTime = 0
+	p.PrintInfo = false
+	p.Debug = false
+	return p
+}
+
+func TestMCTSFindsHangingQueen(t *testing.T) {
+	for _, disablePriors := range []bool{false, true} {
+		b := boardFromFENPlacement(t, "4k3/8/8/8/3q4/8/8/3RK3")
+		m := &MCTS{NumThreads: 2, MaxIterations: 2000, DisablePriors: disablePriors}
+		p := newMCTSTestPlayer(m)
+		got := m.GetBestMove(p, b, nil)
+		if !got.Move.Equals(&rxd4) {
+			t.Fatalf("DisablePriors=%v: expected Rxd4, got %v", disablePriors, got.Move)
+		}
+		if got.Score <= 0 {
+			t.Fatalf("DisablePriors=%v: expected a winning score, got %d", disablePriors, got.Score)
+		}
+	}
+}
+
+func TestMCTSReusesSubtreeAfterReply(t *testing.T) {
+	b := &board.Board{}
+	b.ResetDefault()
+	m := &MCTS{NumThreads: 1, MaxIterations: 3000}
+	p := newMCTSTestPlayer(m)
+	ours := m.GetBestMove(p, b, nil).Move
+
+	last := board.MakeMove(&ours, b)
+	var reply *mctsNode
+	for _, child := range m.tree.children {
+		if child.move.Equals(&ours) {
+			reply = mctsBestChild(child)
+		}
+	}
+	if reply == nil || reply.visits == 0 {
+		t.Fatal("expected the played move to have an explored reply")
+	}
+	last = board.MakeMove(&reply.move, b)
+
+	if got := m.reuseTree(b, color.White); got != reply {
+		t.Fatal("expected the reply's subtree to become the new root")
+	}
+	if got := m.reuseTree(b, color.Black); got != nil {
+		t.Fatal("a tree searched for the other side must not be reused")
+	}
+	m.GetBestMove(p, b, last)
+	if m.tree != reply {
+		t.Fatal("expected GetBestMove to continue from the reused subtree")
+	}
+}
+
+func TestMCTSDoesNotReuseTerminalSubtree(t *testing.T) {
+	b := &board.Board{}
+	b.ResetDefault()
+	nf3 := location.Move{Start: location.NewLocation(0, 1), End: location.NewLocation(2, 2)}
+	nf6 := location.Move{Start: location.NewLocation(7, 1), End: location.NewLocation(5, 2)}
+	board.MakeMove(&nf3, b)
+	board.MakeMove(&nf6, b)
+
+	// The search from here saw Ng1 Ng8 repeat the starting position
+	ng1 := location.Move{Start: nf3.End, End: nf3.Start}
+	ng8 := location.Move{Start: nf6.End, End: nf6.Start}
+	repeated := &mctsNode{move: ng8, state: mctsTerminal, terminalValue: 0.5}
+	m := &MCTS{NumThreads: 1, MaxIterations: 200}
+	m.tree = &mctsNode{state: mctsExpanded, children: []*mctsNode{
+		{move: ng1, state: mctsExpanded, children: []*mctsNode{repeated}},
+	}}
+	m.treeBoard, m.treeSide = b.Copy(), color.White
+	p := newMCTSTestPlayer(m)
+
+	board.MakeMove(&ng1, b)
+	last := board.MakeMove(&ng8, b)
+	if got := m.reuseTree(b, color.White); got != nil {
+		t.Fatal("expected a subtree that was terminal for the old root not to be reused")
+	}
+	got := m.GetBestMove(p, b, last)
+	if !isMoveInList(got.Move, b.GetAllMoves(color.White, last)) {
+		t.Fatalf("expected a legal move in the repeated position, got %v", got.Move)
+	}
+}
@@ -65,6 +65,7 @@ func (miniMax *MiniMax) IterativeMiniMax(b *board.Board, previousMove *board.Las
 			best = newBest
 			miniMax.player.LastSearchDepth = miniMax.currentSearchDepth
 			miniMax.player.printer <- fmt.Sprintf("Best D:%d M:%s\n", miniMax.player.LastSearchDepth, best.Move)
+			miniMax.player.reportSearchInfo(b, previousMove, best, start)
 		} else {
 			// -1 due to discard of current level due to hard abort
 			miniMax.player.LastSearchDepth = miniMax.currentSearchDepth - 1
@@ -51,6 +51,7 @@ func (m *MTDf) IterativeMTDf(b *board.Board, guess *ScoredMove, previousMove *bo
 			guess = newGuess
 			m.player.LastSearchDepth = m.currentSearchDepth
 			m.player.printer <- fmt.Sprintf("Best D:%d M:%s\n", m.player.LastSearchDepth, guess.Move)
+			m.player.reportSearchInfo(b, previousMove, guess, start)
 		} else {
 			// -1 due to discard of current level due to hard abort
 			m.player.LastSearchDepth = m.currentSearchDepth - iterativeIncrement
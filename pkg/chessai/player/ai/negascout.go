@@ -21,6 +21,12 @@ type NegaScout struct {
 	killers            [maxKillerDepth][2]location.Move
 	history            [board.Height * board.Width][board.Height * board.Width]int32
 	countermove        [board.Height * board.Width][board.Height * board.Width]location.Move
+
+	// ProbCut settings, as on ABDADA. ProbCut is forward pruning, so like
+	// futility pruning and LMR it only runs with negaScoutForwardPruning.
+	DisableProbCut      bool
+	ProbCutMargin       int
+	ProbCutSEEThreshold int
 }
 
 const negaScoutForwardPruning = false
@@ -127,6 +133,25 @@ func (n *NegaScout) search(root *board.Board, depth, alpha, beta int, currentPla
 		}
 	}
 
+	if negaScoutForwardPruning && !n.DisableProbCut && !inCheck && depth >= probCutMinDepth && ply > 0 && beta-alpha == 1 {
+		if pcBeta, ok := probCutBeta(beta, n.ProbCutMargin); ok {
+			for _, move := range probCutCaptures(root, *movesArr, currentPlayer, n.ProbCutSEEThreshold) {
+				if n.player.isAborted() {
+					break
+				}
+				child, pm := n.player.applyMove(root, &move)
+				if -n.player.Quiesce(child, -pcBeta, -pcBeta+1, currentPlayer^1, pm) < pcBeta {
+					continue
+				}
+				value := n.search(child, depth-probCutReduction, -pcBeta, -pcBeta+1, currentPlayer^1, pm, true, ply+1, extensions)
+				if -value.Score >= pcBeta && !n.player.isAborted() {
+					atomic.AddUint64(&n.player.Metrics.MovesPrunedAB, uint64(len(*movesArr)))
+					return ScoredMove{Score: beta, Move: move}
+				}
+			}
+		}
+	}
+
 	var standPat int
 	canFutilityPrune := false
 	if negaScoutForwardPruning && !inCheck && depth <= futilityMaxDepth && alpha < WinScore && beta > LossScore {
@@ -321,6 +346,7 @@ func (n *NegaScout) IterativeNegaScout(b *board.Board, previousMove *board.LastM
 			best = stableDepthMove(best, newGuess)
 			n.player.LastSearchDepth = n.currentSearchDepth
 			n.player.printer <- fmt.Sprintf("Best D:%d M:%s score:%d\n", n.player.LastSearchDepth, best.Move, best.Score)
+			n.player.reportSearchInfo(b, previousMove, &best, start)
 		} else {
 			if best.Move.Start.Equals(best.Move.End) && !newGuess.Move.Start.Equals(newGuess.Move.End) {
 				best = newGuess
@@ -5,8 +5,10 @@ import (
 	"github.com/360EntSecGroup-Skylar/excelize"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/config"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -31,8 +33,8 @@ func CreatePerformanceLogger(MakeExcel bool, MakeLog bool, ExcelFileName string,
 	logger := &PerformanceLogger{
 		MakeExcel:     MakeExcel,
 		MakeLog:       MakeLog,
-		ExcelFileName: updateNameWhileExists(ExcelFileName),
-		LogFileName:   updateNameWhileExists(LogFileName),
+		ExcelFileName: updateNameWhileExists(config.LogPath(ExcelFileName)),
+		LogFileName:   updateNameWhileExists(config.LogPath(LogFileName)),
 	}
 	logger.setupExcelFile()
 	return logger
@@ -45,8 +47,8 @@ func updateNameWhileExists(name string) string {
 		if _, err := os.Stat(newName); os.IsNotExist(err) {
 			break
 		}
-		n := strings.Split(name, ".")
-		newName = fmt.Sprintf("%s_%d.%s", n[0], id, n[1])
+		ext := filepath.Ext(name)
+		newName = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, ext), id, ext)
 		id++
 	}
 	return newName
@@ -0,0 +1,47 @@
+package ai
+
+import (
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+)
+
+// ProbCut: at deep null-window (non-PV) nodes, a capture that wins material by SEE
+// and still beats beta+margin in a reduced-depth null-window search almost
+// always means the full-depth search would fail high too, so the node is cut.
+// Each candidate is first checked with a quiescence search against the same
+// bound, which discards most of them before the reduced search is paid for.
+const (
+	probCutMinDepth      = 5
+	probCutReduction     = 4
+	DefaultProbCutMargin = 2 * PawnValueWeight // 200 cp above beta
+)
+
+// probCutBeta returns the raised bound for a node with window upper bound
+// beta; margin <= 0 selects DefaultProbCutMargin. ok is false when beta is a
+// mate bound, where adding a margin is meaningless.
+func probCutBeta(beta, margin int) (int, bool) {
+	if margin <= 0 {
+		margin = DefaultProbCutMargin
+	}
+	if beta >= WinScore || beta <= LossScore {
+		return 0, false
+	}
+	return beta + margin, true
+}
+
+// probCutCaptures returns the captures in moves whose SEE exceeds
+// seeThreshold, most valuable victim first.
+func probCutCaptures(b *board.Board, moves []location.Move, stm color.Color, seeThreshold int) []location.Move {
+	var captures []location.Move
+	for _, m := range moves {
+		if b.GetPiece(m.End) == nil {
+			continue
+		}
+		if b.SEE(m, stm) > seeThreshold {
+			captures = append(captures, m)
+		}
+	}
+	sortCapturesMVVLVA(captures, b)
+	return captures
+}
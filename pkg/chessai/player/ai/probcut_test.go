@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+)
+
+func TestProbCutBetaMargins(t *testing.T) {
+	if got, ok := probCutBeta(100, 0); !ok || got != 100+DefaultProbCutMargin {
+		t.Fatalf("expected default margin, got %d ok=%v", got, ok)
+	}
+	if got, ok := probCutBeta(100, 50); !ok || got != 150 {
+		t.Fatalf("expected configured margin, got %d ok=%v", got, ok)
+	}
+	if _, ok := probCutBeta(WinScore, 50); ok {
+		t.Fatal("mate bounds must not be raised")
+	}
+}
+
+func TestProbCutCapturesFiltersBySEE(t *testing.T) {
+	// The rook on d1 can take the queen on d4 or the pawn on b1, both
+	// undefended.
+	b := boardFromFENPlacement(t, "4k3/8/8/8/3q4/8/8/1p1RK3")
+	moves := *b.GetAllMoves(color.White, nil)
+
+	captures := probCutCaptures(b, moves, color.White, 0)
+	if len(captures) != 2 {
+		t.Fatalf("expected both winning captures, got %v", captures)
+	}
+	if !captures[0].Equals(&rxd4) {
+		t.Fatalf("expected the queen capture first, got %v", captures[0])
+	}
+
+	captures = probCutCaptures(b, moves, color.White, PawnValueWeight)
+	if len(captures) != 1 || !captures[0].Equals(&rxd4) {
+		t.Fatalf("expected only the queen capture above a pawn, got %v", captures)
+	}
+}
+
+func TestProbCutKeepsBestMove(t *testing.T) {
+	for _, disable := range []bool{false, true} {
+		for _, algorithm := range []Algorithm{
+			&ABDADA{NumThreads: 1, DisableProbCut: disable},
+			&NegaScout{DisableProbCut: disable},
+		} {
+			b := boardFromFENPlacement(t, "4k3/8/8/8/3q4/8/8/3RK3")
+			p := NewAIPlayer(color.White, algorithm)
+			p.TranspositionTableEnabled = true
+			p.MaxSearchDepth = probCutMinDepth + 1
+			p.PrintInfo = false
+			p.Debug = false
+			got := algorithm.GetBestMove(p, b, nil)
+			if !got.Move.Equals(&rxd4) {
+				t.Fatalf("%s DisableProbCut=%v: expected Rxd4, got %v", algorithm.GetName(), disable, got.Move)
+			}
+		}
+	}
+}
+
+// TestNegaScoutProbCutNeedsForwardPruning checks that NegaScout, a reference
+// search without forward pruning, searches the same tree whatever its ProbCut
+// setting.
+func TestNegaScoutProbCutNeedsForwardPruning(t *testing.T) {
+	if negaScoutForwardPruning {
+		t.Skip("NegaScout prunes forward")
+	}
+	var metrics [2]Metrics
+	var scores [2]int
+	for i, disable := range []bool{false, true} {
+		b := boardFromFENPlacement(t, "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R")
+		algorithm := &NegaScout{DisableProbCut: disable}
+		p := NewAIPlayer(color.White, algorithm)
+		p.TranspositionTableEnabled = true
+		p.MaxSearchDepth = probCutMinDepth + 1
+		p.MaxThinkTime = time.Minute
+		p.PrintInfo = false
+		p.Debug = false
+		scores[i] = algorithm.GetBestMove(p, b, nil).Score
+		metrics[i] = *p.Metrics
+	}
+	if scores[0] != scores[1] || metrics[0].MovesConsidered != metrics[1].MovesConsidered {
+		t.Fatalf("ProbCut changed the search: score %d and %d nodes, without it %d and %d nodes",
+			scores[0], metrics[0].MovesConsidered, scores[1], metrics[1].MovesConsidered)
+	}
+}
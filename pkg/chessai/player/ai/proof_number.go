@@ -0,0 +1,295 @@
+package ai
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+)
+
+// Proof-number search for forced mates.
+//
+// The alpha-beta searches only see a mate once it fits inside their nominal
+// depth, and their pruning is tuned for positional play, not for proving that
+// every defence loses. Proof-number search grows a best-first AND/OR tree
+// instead: OR nodes (attacker to move) need one mating child, AND nodes
+// (defender to move) need every child mated. Each node carries a proof number
+// (leaves still to prove) and a disproof number (leaves still to refute), and
+// the search always expands the most-proving leaf, which heads straight down
+// narrow forcing lines however deep they run.
+//
+// The tree is owned by one solve and bounded by a node budget. Nodes keep only
+// their move; the board is rebuilt by replaying from the root on each descent,
+// so memory grows with the node count rather than with board copies.
+
+const (
+	DefaultProofNumberMaxNodes = 500_000
+
+	// pnInfinity marks a proven (disproof) or disproven (proof) node. Sums
+	// saturate at it; finite sums stay far below it for any node budget.
+	pnInfinity = uint64(1) << 62
+	// pnAbortCheckInterval is how many expansions run between abort checks.
+	pnAbortCheckInterval = 256
+)
+
+type ProofNumber struct {
+	// MaxNodes bounds the tree size per solve (0 selects
+	// DefaultProofNumberMaxNodes).
+	MaxNodes int
+	// Fallback picks the move when no mate is proven; nil uses ABDADA.
+	Fallback Algorithm
+}
+
+// MateResult is the outcome of one proof-number solve.
+type MateResult struct {
+	// Proven is set when every defence was shown to be mated; Line is then a
+	// mating line through the proof tree, with the defender taking its
+	// longest resistance. Proof-number search proves a mate, not the shortest
+	// one, so the line can be longer than the position's best mate.
+	Proven bool
+	// Disproven is set when the tree showed there is no forced mate at all
+	// (every attacker line ends in a draw, an escape, or the attacker mated).
+	// Neither flag set means the node budget or abort ran out first.
+	Disproven bool
+	Line      []location.Move
+	Nodes     int
+}
+
+// MateIn returns the number of attacker moves in Line.
+func (r MateResult) MateIn() int {
+	return (len(r.Line) + 1) / 2
+}
+
+type pnNode struct {
+	move           location.Move
+	parent         *pnNode
+	children       []*pnNode
+	proof          uint64
+	disproof       uint64
+	attackerToMove bool
+}
+
+func (pn *ProofNumber) GetName() string {
+	return AlgorithmProofNumber
+}
+
+func (pn *ProofNumber) maxNodes() int {
+	if pn.MaxNodes <= 0 {
+		return DefaultProofNumberMaxNodes
+	}
+	return pn.MaxNodes
+}
+
+// GetBestMove plays a proven mate when one is found within a quarter of the
+// think time, and otherwise hands the remaining time to the fallback search.
+func (pn *ProofNumber) GetBestMove(p *AIPlayer, b *board.Board, previousMove *board.LastMove) *ScoredMove {
+	start := time.Now()
+	budget := p.MaxThinkTime / 4
+	result := SolveMate(b, p.PlayerColor, previousMove, pn.maxNodes(), func() bool {
+		return p.isAborted() || (budget > 0 && time.Since(start) > budget)
+	})
+	if result.Proven {
+		p.printer <- fmt.Sprintf("%s: mate in %d proven (%d nodes)\n", pn.GetName(), result.MateIn(), result.Nodes)
+		return &ScoredMove{Move: result.Line[0], MoveSequence: result.Line, Score: WinScore}
+	}
+	p.printer <- fmt.Sprintf("%s: no mate found (%d nodes), falling back\n", pn.GetName(), result.Nodes)
+
+	fallback := pn.Fallback
+	if fallback == nil {
+		fallback = &ABDADA{}
+	}
+	if p.MaxThinkTime > 0 {
+		thinkTime := p.MaxThinkTime
+		p.MaxThinkTime -= time.Since(start)
+		if p.MaxThinkTime <= 0 {
+			p.MaxThinkTime = time.Millisecond
+		}
+		defer func() { p.MaxThinkTime = thinkTime }()
+	}
+	return fallback.GetBestMove(p, b, previousMove)
+}
+
+// SolveMate runs a proof-number search for a forced mate by attacker, who must
+// be the side to move in b. abort may be nil; it is polled periodically.
+func SolveMate(b *board.Board, attacker color.Color, previousMove *board.LastMove, maxNodes int, abort func() bool) MateResult {
+	root := b.Copy()
+	// The solver generates moves for every node it expands; caching them would
+	// fill the shared per-board move cache with positions nobody revisits.
+	root.CacheGetAllMoves = false
+	root.CacheGetAllAttackableMoves = false
+
+	s := &pnSolver{root: root, previousMove: previousMove, attacker: attacker}
+	tree := &pnNode{attackerToMove: true}
+	s.expand(tree, root, previousMove)
+	s.update(tree)
+
+	for i := 0; tree.proof != 0 && tree.disproof != 0 && s.nodes < maxNodes; i++ {
+		if abort != nil && i%pnAbortCheckInterval == 0 && abort() {
+			break
+		}
+		leaf, leafBoard, leafLast := s.selectMostProving(tree)
+		s.expand(leaf, leafBoard, leafLast)
+		for n := leaf; n != nil; n = n.parent {
+			s.update(n)
+		}
+	}
+
+	result := MateResult{Nodes: s.nodes}
+	switch {
+	case tree.proof == 0:
+		result.Proven = true
+		result.Line = pnMateLine(tree, map[*pnNode]int{})
+	case tree.disproof == 0:
+		result.Disproven = true
+	}
+	return result
+}
+
+type pnSolver struct {
+	root         *board.Board
+	previousMove *board.LastMove
+	attacker     color.Color
+	nodes        int
+}
+
+func (s *pnSolver) sideToMove(n *pnNode) color.Color {
+	if n.attackerToMove {
+		return s.attacker
+	}
+	return s.attacker ^ 1
+}
+
+// selectMostProving descends from the root to the leaf whose expansion most
+// reduces the root's proof (OR nodes) or disproof (AND nodes) number, and
+// returns it with its replayed board.
+func (s *pnSolver) selectMostProving(n *pnNode) (*pnNode, *board.Board, *board.LastMove) {
+	b := s.root.Copy()
+	last := s.previousMove
+	for n.children != nil {
+		var next *pnNode
+		for _, child := range n.children {
+			if next == nil ||
+				(n.attackerToMove && child.proof < next.proof) ||
+				(!n.attackerToMove && child.disproof < next.disproof) {
+				next = child
+			}
+		}
+		n = next
+		last = board.MakeMove(&n.move, b)
+	}
+	return n, b, last
+}
+
+// expand creates n's children and initializes each from its own position:
+// terminal children get their final numbers, the rest are seeded with their
+// mobility so that forcing moves (few legal replies) are preferred.
+func (s *pnSolver) expand(n *pnNode, b *board.Board, last *board.LastMove) {
+	moves := *b.GetAllMoves(s.sideToMove(n), last)
+	n.children = make([]*pnNode, len(moves))
+	for i := range moves {
+		child := &pnNode{move: moves[i], parent: n, attackerToMove: !n.attackerToMove}
+		childBoard := b.Copy()
+		childLast := board.MakeMove(&child.move, childBoard)
+		s.initLeaf(child, childBoard, childLast)
+		n.children[i] = child
+	}
+	s.nodes += len(moves)
+	if len(moves) == 0 {
+		// Only reachable for the root: every other node is initialized as
+		// terminal before it can be selected.
+		s.initLeaf(n, b, last)
+	}
+}
+
+func (s *pnSolver) initLeaf(n *pnNode, b *board.Board, last *board.LastMove) {
+	// Any repetition on the path is scored as a draw, like the main search.
+	if b.CurrentPositionRepeats >= 1 || b.MovesSinceNoDraw >= 100 || b.IsInsufficientMaterial() {
+		n.proof, n.disproof = pnInfinity, 0
+		return
+	}
+	side := s.sideToMove(n)
+	moves := len(*b.GetAllMoves(side, last))
+	switch {
+	case moves == 0 && !n.attackerToMove && b.IsKingInCheck(side):
+		n.proof, n.disproof = 0, pnInfinity
+	case moves == 0:
+		n.proof, n.disproof = pnInfinity, 0
+	case n.attackerToMove:
+		n.proof, n.disproof = 1, uint64(moves)
+	default:
+		n.proof, n.disproof = uint64(moves), 1
+	}
+}
+
+// update recomputes an expanded node's numbers from its children.
+func (s *pnSolver) update(n *pnNode) {
+	if len(n.children) == 0 {
+		return
+	}
+	minimum, sum := pnInfinity, uint64(0)
+	for _, child := range n.children {
+		own, other := child.proof, child.disproof
+		if !n.attackerToMove {
+			own, other = other, own
+		}
+		if own < minimum {
+			minimum = own
+		}
+		sum += other
+		if sum > pnInfinity {
+			sum = pnInfinity
+		}
+	}
+	if n.attackerToMove {
+		n.proof, n.disproof = minimum, sum
+	} else {
+		n.disproof, n.proof = minimum, sum
+	}
+}
+
+// pnMateLine follows a proven tree: the attacker takes the fastest mate, the
+// defender the longest resistance.
+func pnMateLine(n *pnNode, lengths map[*pnNode]int) []location.Move {
+	var line []location.Move
+	for len(n.children) > 0 {
+		var next *pnNode
+		for _, child := range n.children {
+			if child.proof != 0 {
+				continue
+			}
+			l := pnMateLength(child, lengths)
+			if next == nil ||
+				(n.attackerToMove && l < pnMateLength(next, lengths)) ||
+				(!n.attackerToMove && l > pnMateLength(next, lengths)) {
+				next = child
+			}
+		}
+		line = append(line, next.move)
+		n = next
+	}
+	return line
+}
+
+// pnMateLength returns the number of plies to mate below a proven node.
+func pnMateLength(n *pnNode, lengths map[*pnNode]int) int {
+	if l, ok := lengths[n]; ok {
+		return l
+	}
+	best := -1
+	for _, child := range n.children {
+		if child.proof != 0 {
+			continue
+		}
+		l := 1 + pnMateLength(child, lengths)
+		if best < 0 || (n.attackerToMove && l < best) || (!n.attackerToMove && l > best) {
+			best = l
+		}
+	}
+	if best < 0 {
+		best = 0
+	}
+	lengths[n] = best
+	return best
+}
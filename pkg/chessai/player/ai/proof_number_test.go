@@ -0,0 +1,118 @@
+package ai
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+)
+
+func assertMateLine(t *testing.T, b *board.Board, attacker color.Color, line []location.Move) {
+	t.Helper()
+	b = b.Copy()
+	var last *board.LastMove
+	side := attacker
+	for i := range line {
+		if !isMoveInList(line[i], b.GetAllMoves(side, last)) {
+			t.Fatalf("move %d (%v) of the mate line is illegal", i, line[i])
+		}
+		last = board.MakeMove(&line[i], b)
+		side ^= 1
+	}
+	if !b.IsInCheckmate(side, last) {
+		t.Fatalf("mate line %v does not end in checkmate", line)
+	}
+}
+
+func TestSolveMateFindsBackRankMate(t *testing.T) {
+	b := boardFromFENPlacement(t, "6k1/5ppp/8/8/8/8/8/R5K1")
+	result := SolveMate(b, color.White, nil, 1000, nil)
+	if !result.Proven || result.MateIn() != 1 {
+		t.Fatalf("expected mate in 1, got %+v", result)
+	}
+	assertMateLine(t, b, color.White, result.Line)
+}
+
+func TestSolveMateFindsMateInTwo(t *testing.T) {
+	// 1.Nf6+ gxf6 2.Bxf7#
+	b := boardFromFENPlacement(t, "r2qkb1r/pp2nppp/3p4/2pNN1B1/2BnP3/3P4/PPP2PPP/R2bK2R")
+	result := SolveMate(b, color.White, nil, 10000, nil)
+	if !result.Proven || result.MateIn() != 2 {
+		t.Fatalf("expected mate in 2, got %+v", result)
+	}
+	assertMateLine(t, b, color.White, result.Line)
+}
+
+func TestSolveMateDisprovesBareKings(t *testing.T) {
+	b := boardFromFENPlacement(t, "8/8/8/4k3/8/8/8/4K3")
+	result := SolveMate(b, color.White, nil, 1000, nil)
+	if !result.Disproven || result.Proven {
+		t.Fatalf("expected a disproof, got %+v", result)
+	}
+}
+
+func TestSolveMateRespectsNodeBudget(t *testing.T) {
+	b := &board.Board{}
+	b.ResetDefault()
+	result := SolveMate(b, color.White, nil, 500, nil)
+	if result.Proven || result.Disproven {
+		t.Fatalf("expected an undecided result, got %+v", result)
+	}
+	if result.Nodes > 500+100 {
+		t.Fatalf("node budget overrun: %d", result.Nodes)
+	}
+}
+
+func TestProofNumberFallsBackWithoutMate(t *testing.T) {
+	b := &board.Board{}
+	b.ResetDefault()
+	p := NewAIPlayer(color.White, &ProofNumber{MaxNodes: 200, Fallback: &Random{}})
+	p.PrintInfo = false
+	p.Debug = false
+	got := p.Algorithm.GetBestMove(p, b, nil)
+	if !isMoveInList(got.Move, b.GetAllMoves(color.White, nil)) {
+		t.Fatalf("expected a legal fallback move, got %v", got.Move)
+	}
+}
+
+func TestProofNumberLeavesDefaultFallbackUnset(t *testing.T) {
+	b := &board.Board{}
+	b.ResetDefault()
+	pn := &ProofNumber{MaxNodes: 200}
+	p := NewAIPlayer(color.White, pn)
+	p.PrintInfo = false
+	p.Debug = false
+	p.MaxSearchDepth = 1
+	pn.GetBestMove(p, b, nil)
+	if pn.Fallback != nil {
+		t.Fatalf("expected the shared algorithm to be left as it was, got fallback %v", pn.Fallback)
+	}
+}
+
+func TestProvenMateOverrideReplacesNonMateMove(t *testing.T) {
+	b := boardFromFENPlacement(t, "6k1/5ppp/8/8/8/8/8/R5K1")
+	p := NewAIPlayer(color.White, &ABDADA{})
+	p.MateSolverNodes = 1000
+	quiet := ScoredMove{Move: location.Move{
+		Start: location.NewLocation(0, 1), // g1
+		End:   location.NewLocation(1, 1), // g2
+	}}
+	got := p.provenMateOverride(b, nil, &quiet, time.Now())
+	mate := location.Move{Start: location.NewLocation(0, 7), End: location.NewLocation(7, 7)} // Ra8#
+	if !got.Move.Equals(&mate) || got.Score < WinScore {
+		t.Fatalf("expected Ra8# to override %v, got %v score %d", quiet.Move, got.Move, got.Score)
+	}
+
+	p.MaxThinkTime = 10 * time.Millisecond
+	if got := p.provenMateOverride(b, nil, &quiet, time.Now().Add(-time.Second)); !got.Move.Equals(&quiet.Move) {
+		t.Fatalf("expected the helper to be off with no think time left, got %v", got.Move)
+	}
+
+	p.MaxThinkTime = 0
+	p.MateSolverNodes = 0
+	if got := p.provenMateOverride(b, nil, &quiet, time.Now()); !got.Move.Equals(&quiet.Move) {
+		t.Fatalf("expected the helper to be off without a node budget, got %v", got.Move)
+	}
+}
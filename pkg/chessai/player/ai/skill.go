@@ -0,0 +1,50 @@
+package ai
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+)
+
+// MaxSkillLevel is full strength. Skill levels 1 to MaxSkillLevel-1 weaken the
+// AI for casual play; see SetSkillLevel.
+const MaxSkillLevel = 20
+
+// skillBlunderPercent is the chance per level below MaxSkillLevel that a
+// weakened AI plays a random legal move instead of searching: 76% at level 1,
+// 4% at level 19.
+const skillBlunderPercent = 4
+
+// SetSkillLevel weakens the AI to level, from 1 (weakest) to MaxSkillLevel
+// (full strength). Below full strength it searches at most 1+level/2 plies,
+// which lowers MaxSearchDepth if that is set higher, and sometimes plays a
+// random legal move.
+func (p *AIPlayer) SetSkillLevel(level int) {
+	p.SkillLevel = level
+	if level <= 0 || level >= MaxSkillLevel {
+		return
+	}
+	if depth := 1 + level/2; p.MaxSearchDepth > depth {
+		p.MaxSearchDepth = depth
+	}
+}
+
+// skillBlunder returns a random legal move when the skill level calls for a
+// blunder on this move, or nil to search as usual.
+func (p *AIPlayer) skillBlunder(b *board.Board, previousMove *board.LastMove) *location.Move {
+	if p.SkillLevel <= 0 || p.SkillLevel >= MaxSkillLevel {
+		return nil
+	}
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	if r.Intn(100) >= (MaxSkillLevel-p.SkillLevel)*skillBlunderPercent {
+		return nil
+	}
+	scored := (&Random{Rand: r}).RandomMove(b, p.PlayerColor, previousMove)
+	if scored.Move.Start.Equals(scored.Move.End) {
+		return nil
+	}
+	p.printer <- "skill level: playing a random move\n"
+	return &scored.Move
+}
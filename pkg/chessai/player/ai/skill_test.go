@@ -0,0 +1,49 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetSkillLevelCapsDepth(t *testing.T) {
+	p := NewAIPlayer(color.White, &Random{})
+	p.MaxSearchDepth = 64
+	p.SetSkillLevel(7)
+	assert.Equal(t, 4, p.MaxSearchDepth)
+
+	p.MaxSearchDepth = 2
+	p.SetSkillLevel(7)
+	assert.Equal(t, 2, p.MaxSearchDepth, "a lower depth is kept")
+
+	p.MaxSearchDepth = 64
+	p.SetSkillLevel(MaxSkillLevel)
+	assert.Equal(t, 64, p.MaxSearchDepth)
+}
+
+func TestSkillBlunder(t *testing.T) {
+	b := &board.Board{}
+	b.ResetDefault()
+	p := NewAIPlayer(color.White, &Random{})
+	p.PrintInfo = false
+
+	p.SetSkillLevel(MaxSkillLevel)
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, p.skillBlunder(b, nil), "full strength never blunders")
+	}
+
+	// Level 1 blunders 76% of the time, so 100 tries without one would be a bug.
+	p.SetSkillLevel(1)
+	legal := *b.GetAllMovesUnShuffled(color.White, nil)
+	blunders := 0
+	for i := 0; i < 100; i++ {
+		if move := p.skillBlunder(b, nil); move != nil {
+			blunders++
+			assert.Contains(t, legal, *move)
+		}
+	}
+	assert.NotZero(t, blunders)
+	assert.True(t, blunders < 100, "level 1 still searches sometimes")
+}
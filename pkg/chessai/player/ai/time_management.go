@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+)
+
+// ThinkTimeForClock allocates think time from the remaining clock.
+// Uses the standard time-management formula: think = usableTime/movesLeft + increment.
+//
+// Tuned for 3+0: spend the clock in the opening/midgame (where the position is
+// rich and the per-move cap binds anyway) and shrink aggressively in the
+// endgame, holding back a growing safety buffer so we don't flag in long
+// K+P / R endgames.
+//
+//   - endgameStart (turnCount 17 ≈ ply 35): both the per-move divisor and the
+//     reserve begin to ramp up, so each endgame move costs less and we keep more
+//     time in the bank.
+//   - The reserve grows from 3s up to 18s (i.e. ~15s more buffer than before)
+//     as the endgame deepens.
+//   - The first few moves are capped to 500ms so opening play stays responsive.
+//   - Capped by game_conf.json AIMaxThinkTimeMs (or 8s if unset) so we never
+//     burn the clock on a single move.
+func ThinkTimeForClock(timeLeft, increment time.Duration, turnCount int) time.Duration {
+	const baseReserve = 3 * time.Second
+	const maxExtraReserve = 15 * time.Second // larger buffer once we reach the endgame
+	const openingCap = 500 * time.Millisecond
+
+	// Endgame begins ~35 ply, i.e. this side's ~17th move (TurnCount counts our
+	// own moves). reserveRamp is the number of our moves over which the reserve
+	// climbs from baseReserve to baseReserve+maxExtraReserve.
+	const endgameStart = 17
+	const reserveRamp = 13
+
+	// Reserve ramps up through the endgame so we always keep a healthy buffer.
+	reserve := baseReserve
+	if turnCount > endgameStart {
+		grown := time.Duration(turnCount-endgameStart) * (maxExtraReserve / reserveRamp)
+		if grown > maxExtraReserve {
+			grown = maxExtraReserve
+		}
+		reserve += grown
+	}
+
+	// Estimated moves remaining for THIS side. Low in the midgame (spend more of
+	// the clock where it matters), then rising through the endgame so per-move
+	// time shrinks aggressively and the growing reserve survives.
+	var movesLeft time.Duration
+	switch {
+	case turnCount < 10:
+		movesLeft = 45
+	case turnCount < endgameStart:
+		movesLeft = 22
+	case turnCount < 25:
+		movesLeft = 28
+	case turnCount < 35:
+		movesLeft = 34
+	default:
+		movesLeft = 40
+	}
+
+	usable := timeLeft - reserve
+	if usable < 0 {
+		usable = 0
+	}
+	think := usable/movesLeft + increment
+	// In long endgames we still want the larger reserve, but not at the cost of
+	// blitzing critical queen/pawn positions while a minute remains. Spend a
+	// controlled slice of clock above 45s; below that, keep the conservative
+	// scramble behavior covered by TestThinkTimeForClockShrinksAndBuffersEndgame.
+	if turnCount >= 35 && timeLeft > 45*time.Second {
+		think += (timeLeft - 45*time.Second) / 15
+	}
+	if think < 50*time.Millisecond {
+		think = 50 * time.Millisecond
+	}
+	if turnCount < 4 && think > openingCap {
+		think = openingCap
+	}
+	maxThink := MaxThinkTimeForClock(timeLeft)
+	if think > maxThink {
+		think = maxThink
+	}
+	return think
+}
+
+// MaxThinkTimeForClock caps a single move's think time. The configured
+// AIMaxThinkTimeMs is tuned for 3+0 blitz (where it binds through most of the
+// game); at longer time controls that fixed cap made the bot think it had far
+// less time than it actually did, since it never scaled up with a bigger
+// clock. Scale the cap with the clock itself (1/60th of remaining time, so a
+// 3-minute clock reproduces the old 3s cap exactly) and take whichever cap is
+// larger.
+func MaxThinkTimeForClock(timeLeft time.Duration) time.Duration {
+	maxThink := 8 * time.Second
+	if game_config.Get().AIMaxThinkTimeMs > 0 {
+		maxThink = game_config.Get().AIMaxThinkTimeMs * time.Millisecond
+	}
+	if dynamicCap := timeLeft / 60; dynamicCap > maxThink {
+		maxThink = dynamicCap
+	}
+	return maxThink
+}
@@ -2,6 +2,7 @@ package ai
 
 import (
 	"testing"
+	"time"
 
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
@@ -79,3 +80,41 @@ func TestIterativeABDADAEasyMove(t *testing.T) {
 		t.Errorf("easy move should not deepen the search; LastSearchDepth = %d, want 0", p.LastSearchDepth)
 	}
 }
+
+// TestPonderHit starts a ponder search, which is not bound by the move's time
+// limit, and checks that PonderHit makes the same search finish within the
+// limit it is given from then on.
+func TestPonderHit(t *testing.T) {
+	b := &board.Board{}
+	b.ResetDefault()
+	p := NewAIPlayer(color.White, &ABDADA{})
+	p.MaxSearchDepth = 64
+	ponder := p.NewPonderPlayer(color.White)
+	ponder.Opening, ponder.TurnCount = OpeningNone, 10
+	ponder.MaxThinkTime = time.Minute
+	ponder.StartPondering()
+
+	done := make(chan *location.Move)
+	go func() { done <- ponder.GetBestMove(b, nil, nil) }()
+	select {
+	case <-done:
+		t.Fatal("ponder search stopped before the ponder hit")
+	case <-time.After(300 * time.Millisecond):
+	}
+	ponder.PonderHit(200 * time.Millisecond)
+	select {
+	case move := <-done:
+		if move == nil || move.Start.Equals(move.End) {
+			t.Fatalf("no move after ponder hit: %v", move)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("search did not stop within the limit given by the ponder hit")
+	}
+	if ponder.LastSearchTime < 300*time.Millisecond {
+		t.Errorf("search restarted on ponder hit: it took %s", ponder.LastSearchTime)
+	}
+	p.AdoptSearch(ponder)
+	if !p.HasLastScore || len(p.LastLine) == 0 || p.LastSearchDepth != ponder.LastSearchDepth {
+		t.Errorf("AdoptSearch did not take over the search results")
+	}
+}
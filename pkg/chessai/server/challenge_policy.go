@@ -0,0 +1,118 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+)
+
+// Lichess challenge decline reason codes (POST /api/challenge/{id}/decline).
+const (
+	DeclineGeneric     = "generic"
+	DeclineLater       = "later"
+	DeclineTooFast     = "tooFast"
+	DeclineTooSlow     = "tooSlow"
+	DeclineTimeControl = "timeControl"
+	DeclineRated       = "rated"
+	DeclineCasual      = "casual"
+	DeclineVariant     = "variant"
+	DeclineNoBot       = "noBot"
+	DeclineOnlyBot     = "onlyBot"
+)
+
+const (
+	variantStandard  = "standard"
+	timeControlClock = "clock"
+	titleBot         = "BOT"
+)
+
+// challengePolicy returns the configured policy, or the default one when the
+// config sets it to null.
+func (l *Lichess) challengePolicy() *game_config.ChallengePolicy {
+	if l.Policy != nil {
+		return l.Policy
+	}
+	if p := game_config.Get().ChallengePolicy; p != nil {
+		return p
+	}
+	return game_config.DefaultChallengePolicy()
+}
+
+// evaluateChallenge returns the Lichess decline reason for c under policy, or
+// "" if the challenge is acceptable. Fields Lichess left out of the event are
+// not filtered on.
+func evaluateChallenge(policy *game_config.ChallengePolicy, c *Challenge) string {
+	challenger := ChallengeUser{}
+	if c.Challenger != nil {
+		challenger = *c.Challenger
+	}
+	if containsUser(policy.BlockUsers, challenger.ID) ||
+		(len(policy.AllowUsers) > 0 && !containsUser(policy.AllowUsers, challenger.ID)) {
+		return DeclineGeneric
+	}
+	if challenger.Title == titleBot && !policy.AcceptBots {
+		return DeclineNoBot
+	}
+	if challenger.Title != titleBot && !policy.AcceptHumans {
+		return DeclineOnlyBot
+	}
+
+	variant := variantStandard
+	if c.Variant != nil && c.Variant.Key != "" {
+		variant = c.Variant.Key
+	}
+	if reason := evaluateTerms(policy, variant, c.TimeControl, c.Rated); reason != "" {
+		return reason
+	}
+
+	if challenger.Rating > 0 &&
+		((policy.MinRating > 0 && challenger.Rating < policy.MinRating) ||
+			(policy.MaxRating > 0 && challenger.Rating > policy.MaxRating)) {
+		return DeclineGeneric
+	}
+	return ""
+}
+
+// evaluateTerms returns the Lichess decline reason for a game's variant, clock
+// and ratedness under policy, or "" if they are acceptable. A nil tc is not
+// filtered on.
+func evaluateTerms(policy *game_config.ChallengePolicy, variant string, tc *ChallengeTimeControl, rated bool) string {
+	if !containsFold(policy.Variants, variant) {
+		return DeclineVariant
+	}
+
+	if tc != nil {
+		if tc.Type != timeControlClock {
+			return DeclineTimeControl
+		}
+		switch {
+		case tc.Limit < policy.MinInitialSec || tc.Increment < policy.MinIncrementSec:
+			return DeclineTooFast
+		case policy.MaxInitialSec > 0 && tc.Limit > policy.MaxInitialSec,
+			policy.MaxIncrementSec > 0 && tc.Increment > policy.MaxIncrementSec:
+			return DeclineTooSlow
+		}
+	}
+
+	// The reason names what we would accept instead.
+	if rated && !policy.AcceptRated {
+		return DeclineCasual
+	}
+	if !rated && !policy.AcceptCasual {
+		return DeclineRated
+	}
+	return ""
+}
+
+func containsUser(users []string, id string) bool {
+	return id != "" && containsFold(users, id)
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	"github.com/stretchr/testify/assert"
+)
+
+func blitzChallenge(id, challenger string) *Challenge {
+	return &Challenge{
+		ID:          id,
+		Challenger:  &ChallengeUser{ID: challenger, Rating: 1800},
+		Rated:       true,
+		Variant:     &ChallengeVariant{Key: "standard"},
+		TimeControl: &ChallengeTimeControl{Type: "clock", Limit: 180, Increment: 2},
+	}
+}
+
+func TestEvaluateChallengeDeclineReasons(t *testing.T) {
+	policy := game_config.DefaultChallengePolicy()
+	policy.MinInitialSec = 60
+	policy.MaxInitialSec = 600
+	policy.MaxIncrementSec = 10
+	policy.MinRating = 1500
+	policy.MaxRating = 2500
+	policy.AcceptBots = false
+	policy.BlockUsers = []string{"Troll"}
+
+	cases := []struct {
+		name   string
+		modify func(c *Challenge)
+		reason string
+	}{
+		{"acceptable", func(c *Challenge) {}, ""},
+		{"blocked user", func(c *Challenge) { c.Challenger.ID = "troll" }, DeclineGeneric},
+		{"bot", func(c *Challenge) { c.Challenger.Title = "BOT" }, DeclineNoBot},
+		{"variant", func(c *Challenge) { c.Variant.Key = "chess960" }, DeclineVariant},
+		{"correspondence", func(c *Challenge) { c.TimeControl.Type = "correspondence" }, DeclineTimeControl},
+		{"bullet", func(c *Challenge) { c.TimeControl.Limit = 30 }, DeclineTooFast},
+		{"classical", func(c *Challenge) { c.TimeControl.Limit = 1800 }, DeclineTooSlow},
+		{"big increment", func(c *Challenge) { c.TimeControl.Increment = 30 }, DeclineTooSlow},
+		{"casual", func(c *Challenge) { c.Rated = false }, DeclineRated},
+		{"weak opponent", func(c *Challenge) { c.Challenger.Rating = 1200 }, DeclineGeneric},
+		{"strong opponent", func(c *Challenge) { c.Challenger.Rating = 2700 }, DeclineGeneric},
+	}
+	for _, tc := range cases {
+		c := blitzChallenge("id", "someHuman")
+		tc.modify(c)
+		assert.Equalf(t, tc.reason, evaluateChallenge(policy, c), "case %s", tc.name)
+	}
+
+	policy.AcceptRated, policy.AcceptCasual = false, true
+	assert.Equal(t, DeclineCasual, evaluateChallenge(policy, blitzChallenge("id", "someHuman")))
+
+	policy = game_config.DefaultChallengePolicy()
+	policy.AllowUsers = []string{"Friend"}
+	assert.Equal(t, DeclineGeneric, evaluateChallenge(policy, blitzChallenge("id", "stranger")))
+	assert.Equal(t, "", evaluateChallenge(policy, blitzChallenge("id", "friend")))
+	policy.AcceptHumans = false
+	assert.Equal(t, DeclineOnlyBot, evaluateChallenge(policy, blitzChallenge("id", "friend")))
+}
+
+func challengeURLs(rec *recordingClient, suffix string) []string {
+	var urls []string
+	for _, u := range rec.urls {
+		if strings.HasSuffix(u, suffix) {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+func TestQueuesChallengesWhileBusy(t *testing.T) {
+	rec := &recordingClient{}
+	base, _ := url.Parse("http://test.local")
+	policy := game_config.DefaultChallengePolicy()
+	policy.MaxQueue = 1
+	l := &Lichess{
+		Client: &Client{BaseURL: base, APIKey: "x", HttpClient: rec},
+		Policy: policy,
+		GameID: "BUSY",
+		Player: randomAI(color.White),
+		Game:   game.NewGame(randomAI(color.White), randomAI(color.Black)),
+	}
+
+	for _, id := range []string{"first", "second"} {
+		assert.NoError(t, l.handleEvent(&Event{Type: EventTypeChallenge, Challenge: blitzChallenge(id, "someHuman")}))
+	}
+	assert.Empty(t, challengeURLs(rec, "/accept"), "no challenge may be accepted mid-game")
+	assert.Equal(t, []string{"http://test.local/api/challenge/second/decline"}, challengeURLs(rec, "/decline"),
+		"the challenge beyond MaxQueue should be declined")
+
+	assert.NoError(t, l.handleEvent(&Event{Type: EventTypeGameFinish}))
+	assert.Equal(t, []string{"http://test.local/api/challenge/first/accept"}, challengeURLs(rec, "/accept"),
+		"the queued challenge should be accepted once the game ends")
+	assert.Empty(t, l.challengeQueue)
+}
+
+func TestCanceledChallengeLeavesQueue(t *testing.T) {
+	l := &Lichess{challengeQueue: []string{"a", "b", "c"}}
+	assert.NoError(t, l.handleEvent(&Event{Type: EventTypeChallengeCanceled, Challenge: &Challenge{ID: "b"}}))
+	assert.Equal(t, []string{"a", "c"}, l.challengeQueue)
+}
+
+// accountClient answers /api/account with a fixed bot identity.
+type accountClient struct{ recordingClient }
+
+func (c *accountClient) Do(req *http.Request) (*http.Response, error) {
+	if req.URL.Path == "/api/account" {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"id":"mybot","username":"MyBot","title":"BOT"}`)),
+			Header:     make(http.Header),
+		}, nil
+	}
+	return c.recordingClient.Do(req)
+}
+
+func TestIgnoresOwnChallengeUsingAccountIdentity(t *testing.T) {
+	client := &accountClient{}
+	base, _ := url.Parse("http://test.local")
+	l := &Lichess{Client: &Client{BaseURL: base, APIKey: "x", HttpClient: client}}
+
+	account, err := l.FetchAccount()
+	assert.NoError(t, err)
+	assert.Equal(t, "MyBot", account.Username)
+	l.botID = account.ID
+
+	assert.NoError(t, l.handleEvent(&Event{Type: EventTypeChallenge, Challenge: blitzChallenge("out", "MyBot")}))
+	assert.Empty(t, client.urls, "our own outgoing challenge must be neither accepted nor declined")
+
+	assert.NoError(t, l.handleEvent(&Event{Type: EventTypeChallenge, Challenge: blitzChallenge("in", "vadbot")}))
+	assert.Equal(t, []string{"http://test.local/api/challenge/in/accept"}, challengeURLs(&client.recordingClient, "/accept"))
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/config"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ChatRoomPlayer    = "player"
+	ChatRoomSpectator = "spectator"
+)
+
+// chatPVMoves caps the PV in !eval answers; Lichess cuts chat lines at 140
+// characters.
+const chatPVMoves = 8
+
+// gameChat is the per-game chat state (see game_config.Chat).
+type gameChat struct {
+	// answered is when we last answered a command in each room.
+	answered map[string]time.Time
+}
+
+func (l *Lichess) chatConfig() *game_config.Chat {
+	if l.Chat != nil {
+		return l.Chat
+	}
+	if c := game_config.Get().Chat; c != nil {
+		return c
+	}
+	return game_config.DefaultChat()
+}
+
+// greetLocked posts the greeting to both rooms of the current game. Must be
+// called with the mutex held.
+func (l *Lichess) greetLocked() {
+	l.announceLocked(l.chatConfig().Greeting)
+}
+
+// sayGoodbyeLocked posts the goodbye to both rooms of the current game. Must
+// be called with the mutex held, before resetGame.
+func (l *Lichess) sayGoodbyeLocked() {
+	l.announceLocked(l.chatConfig().Goodbye)
+}
+
+func (l *Lichess) announceLocked(text string) {
+	if !l.chatConfig().Enabled || text == "" {
+		return
+	}
+	for _, room := range []string{ChatRoomPlayer, ChatRoomSpectator} {
+		if err := l.PostChat(l.GameID, room, text); err != nil {
+			log.Errorf("failed to chat in game %s: %s", l.GameID, err)
+		}
+	}
+}
+
+// handleChatLineLocked answers a chat command in the room it was asked in.
+// Our own lines, which Lichess echoes back, and plain chatter are ignored.
+// Must be called with the mutex held.
+func (l *Lichess) handleChatLineLocked(event *GameEvent) {
+	cfg := l.chatConfig()
+	if !cfg.Enabled || l.Player == nil || strings.EqualFold(event.Username, l.botID) {
+		return
+	}
+	fields := strings.Fields(strings.ToLower(event.Text))
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "!") {
+		return
+	}
+	answer := l.chatAnswerLocked(fields[0])
+	if answer == "" {
+		return
+	}
+	now := time.Now()
+	if last, ok := l.chat.answered[event.Room]; ok && now.Sub(last) < time.Duration(cfg.MinIntervalSec)*time.Second {
+		log.Infof("not answering %s from %s in game %s: rate limited", fields[0], event.Username, l.GameID)
+		return
+	}
+	if l.chat.answered == nil {
+		l.chat.answered = make(map[string]time.Time)
+	}
+	l.chat.answered[event.Room] = now
+	if err := l.PostChat(l.GameID, event.Room, answer); err != nil {
+		log.Errorf("failed to answer %s in game %s: %s", fields[0], l.GameID, err)
+	}
+}
+
+// chatAnswerLocked is the answer to a chat command, "" for unknown commands.
+// Scores and lines are those of our last searched move. Must be called with
+// the mutex held.
+func (l *Lichess) chatAnswerLocked(command string) string {
+	p := l.Player
+	switch command {
+	case "!help":
+		return "Commands: !eval, !name, !hardware, !stats"
+	case "!name":
+		return fmt.Sprintf("GolangChessAI %s, searching with %s.", engineVersion(), p.Algorithm.GetName())
+	case "!hardware":
+		return fmt.Sprintf("%d search threads on %d CPUs, hash of up to %d positions.",
+			searchThreads(p.Algorithm), runtime.NumCPU(), config.Get().CacheMaxPlayerElements)
+	case "!eval":
+		if !p.HasLastScore {
+			return "My last move came from the opening book, not a search."
+		}
+		score := p.LastScore
+		if p.PlayerColor == color.Black {
+			score = -score
+		}
+		line := p.LastLine
+		if len(line) > chatPVMoves {
+			line = line[:chatPVMoves]
+		}
+		pv := make([]string, len(line))
+		for i, m := range line {
+			pv[i] = m.UCIString()
+		}
+		return fmt.Sprintf("Eval %s (White's view) at depth %d, PV: %s", formatChatScore(score), p.LastSearchDepth, strings.Join(pv, " "))
+	case "!stats":
+		if !p.HasLastScore {
+			return "My last move came from the opening book, not a search."
+		}
+		nodes := p.Metrics.MovesConsidered
+		seconds := p.LastSearchTime.Seconds()
+		nps := 0.0
+		if seconds > 0 {
+			nps = float64(nodes) / seconds
+		}
+		return fmt.Sprintf("Last search: depth %d, %d nodes in %.1fs (%.0f nodes/s). Network lag %dms, move overhead %dms. Ponder hits %s.",
+			p.LastSearchDepth, nodes, seconds, nps, l.lag.average.Milliseconds(), l.moveOverhead().Milliseconds(), l.ponderStats)
+	}
+	return ""
+}
+
+// formatChatScore formats a centipawn score from White's side in pawns, or as
+// a forced mate.
+func formatChatScore(score int) string {
+	switch {
+	case score >= ai.WinScore:
+		return "mate for White"
+	case score <= ai.LossScore:
+		return "mate for Black"
+	}
+	return fmt.Sprintf("%+.2f", float64(score)/100)
+}
+
+// searchThreads is how many threads a searches with. The parallel searches
+// default to one per CPU.
+func searchThreads(a ai.Algorithm) int {
+	switch a := a.(type) {
+	case *ai.ABDADA:
+		if a.NumThreads > 0 {
+			return a.NumThreads
+		}
+	case *ai.MCTS:
+		if a.NumThreads > 0 {
+			return a.NumThreads
+		}
+	case *ai.LazySMP:
+	default:
+		return 1
+	}
+	return runtime.NumCPU()
+}
+
+// engineVersion is the module version, or the VCS revision for a build from
+// a checkout.
+func engineVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(unknown version)"
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" && len(s.Value) >= 7 {
+			return s.Value[:7]
+		}
+	}
+	if info.Main.Version == "" {
+		return "(devel)"
+	}
+	return info.Main.Version
+}
+
+// PostChat posts text to a chat room ("player" or "spectator") of a game.
+func (l *Lichess) PostChat(gameID, room, text string) error {
+	params := url.Values{}
+	params.Set("room", room)
+	params.Set("text", text)
+
+	rel := &url.URL{Path: fmt.Sprintf("/api/bot/game/%s/chat", gameID)}
+	fullURL := l.Client.BaseURL.ResolveReference(rel)
+	req, err := http.NewRequest("POST", fullURL.String(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", l.Client.APIKey))
+	req.Header.Set("User-Agent", l.Client.UserAgent)
+
+	resp, err := l.Client.HttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	log.Infof("chat %s %s %q status %s", gameID, room, text, resp.Status)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("chat in game %s rejected: %s", gameID, string(bodyBytes))
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/server/lichesstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatChatScore(t *testing.T) {
+	assert.Equal(t, "+0.35", formatChatScore(35))
+	assert.Equal(t, "-1.20", formatChatScore(-120))
+	assert.Equal(t, "+0.00", formatChatScore(0))
+	assert.Equal(t, "mate for White", formatChatScore(ai.WinScore+3))
+	assert.Equal(t, "mate for Black", formatChatScore(ai.LossScore-3))
+	assert.Equal(t, 4, searchThreads(&ai.ABDADA{NumThreads: 4}))
+	assert.Equal(t, 1, searchThreads(&ai.NegaScout{}))
+}
+
+// TestAnswersChatCommands greets, answers commands in the room they were
+// asked in, drops a command inside the rate limit and says goodbye.
+func TestAnswersChatCommands(t *testing.T) {
+	fake := lichesstest.NewServer("TestBot")
+	fake.Token = "test-token"
+	defer fake.Close()
+	t.Setenv("LICHESS_TOKEN", fake.Token)
+
+	bot := ConnectLichess(fake.URL).(*Lichess)
+	policy := game_config.DefaultChallengePolicy()
+	policy.Variants = []string{"fromPosition"}
+	bot.Policy = policy
+	bot.Chat = &game_config.Chat{Enabled: true, Greeting: "hello", Goodbye: "bye", MinIntervalSec: 60}
+	done := runUntilDone(bot)
+	defer stopBot(t, bot, done)
+
+	// Starting from a position keeps the opening book out of the way, so the
+	// bot's first move is searched.
+	id := fake.Challenge(lichesstest.ChallengeOptions{
+		Rated:      true,
+		Variant:    "fromPosition",
+		Initial:    time.Minute,
+		BotColor:   color.White,
+		InitialFEN: "4k3/8/8/8/8/8/3QP3/4K3 w - - 0 40",
+	})
+	waitFor := func(what string, done func(r lichesstest.GameResult) bool) lichesstest.GameResult {
+		t.Helper()
+		deadline := time.Now().Add(30 * time.Second)
+		for {
+			r, _ := fake.Game(id)
+			if done(r) {
+				return r
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s: %+v", what, r)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	r := waitFor("bot move", func(r lichesstest.GameResult) bool { return len(r.Moves) == 1 })
+	assert.Equal(t, []lichesstest.ChatLine{{Room: "player", Text: "hello"}, {Room: "spectator", Text: "hello"}}, r.Chat)
+
+	assert.NoError(t, fake.Say(id, "Kibitzer", "spectator", "hi there"))
+	assert.NoError(t, fake.Say(id, "Kibitzer", "spectator", "!eval please"))
+	r = waitFor("eval", func(r lichesstest.GameResult) bool { return len(r.Chat) == 3 })
+	assert.Equal(t, "spectator", r.Chat[2].Room)
+	assert.True(t, strings.HasPrefix(r.Chat[2].Text, "Eval +"), r.Chat[2].Text)
+	assert.Contains(t, r.Chat[2].Text, "PV: "+r.Moves[0])
+
+	assert.NoError(t, fake.Say(id, "Kibitzer", "spectator", "!stats"))
+	assert.NoError(t, fake.Say(id, "Opponent", "player", "!name"))
+	r = waitFor("name", func(r lichesstest.GameResult) bool { return len(r.Chat) == 4 })
+	assert.Equal(t, "player", r.Chat[3].Room, "!stats in the spectator room is rate limited")
+	assert.Contains(t, r.Chat[3].Text, "GolangChessAI")
+
+	assert.NoError(t, fake.OpponentResigns(id))
+	r = waitFor("goodbye", func(r lichesstest.GameResult) bool { return len(r.Chat) == 6 })
+	assert.Equal(t, []lichesstest.ChatLine{{Room: "player", Text: "bye"}, {Room: "spectator", Text: "bye"}}, r.Chat[4:])
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/piece"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
+	log "github.com/sirupsen/logrus"
+)
+
+// gameDecisions is the per-game state behind draw offers, resignation and
+// takeback answers (see game_config.DecisionPolicy).
+type gameDecisions struct {
+	score     int
+	haveScore bool
+	// Consecutive searched moves at or below ResignScore / within
+	// ±DrawOfferScore.
+	losingMoves int
+	equalMoves  int
+	// drawAnswered and takebackAnswered are 1 + the ply count at which we last
+	// answered that kind of offer; Lichess repeats the offer flag on every
+	// gameState until the next move, and each offer gets one answer.
+	drawAnswered     int
+	takebackAnswered int
+	// takebackAccepted is set once we grant a takeback, so that a shorter move
+	// list is read as the rewind rather than as a stale event.
+	takebackAccepted bool
+}
+
+func (l *Lichess) decisionPolicy() *game_config.DecisionPolicy {
+	if l.DecisionPolicy != nil {
+		return l.DecisionPolicy
+	}
+	if p := game_config.Get().DecisionPolicy; p != nil {
+		return p
+	}
+	return game_config.DefaultDecisionPolicy()
+}
+
+// recordScore tracks the score of one of our searched moves.
+func (d *gameDecisions) recordScore(policy *game_config.DecisionPolicy, score int) {
+	d.score, d.haveScore = score, true
+	if score <= policy.ResignScore {
+		d.losingMoves++
+	} else {
+		d.losingMoves = 0
+	}
+	if score >= -policy.DrawOfferScore && score <= policy.DrawOfferScore {
+		d.equalMoves++
+	} else {
+		d.equalMoves = 0
+	}
+}
+
+func (d *gameDecisions) shouldResign(policy *game_config.DecisionPolicy) bool {
+	return policy.ResignMoves > 0 && d.losingMoves >= policy.ResignMoves
+}
+
+// shouldOfferDraw reports whether a long, dead-equal endgame has been reached.
+func (d *gameDecisions) shouldOfferDraw(policy *game_config.DecisionPolicy, b *board.Board, moveNumber int) bool {
+	return policy.DrawOfferMoves > 0 &&
+		d.equalMoves >= policy.DrawOfferMoves &&
+		moveNumber >= policy.DrawOfferMinMove &&
+		countPieces(b) <= policy.DrawOfferMaxPieces
+}
+
+// acceptDraw decides an opponent's draw offer from our last search score, the
+// material balance and both clocks.
+func (d *gameDecisions) acceptDraw(policy *game_config.DecisionPolicy, b *board.Board, side color.Color, ourClock, theirClock time.Duration) bool {
+	if !d.haveScore {
+		// Still in the book: nothing to judge the position by.
+		return false
+	}
+	if materialBalance(b, side) >= policy.DrawRefuseMaterial {
+		return false
+	}
+	if d.score <= policy.DrawAcceptScore {
+		return true
+	}
+	lowClock := time.Duration(policy.DrawAcceptLowClockSec) * time.Second
+	return ourClock < lowClock && ourClock < theirClock && d.score <= policy.DrawAcceptLowClockScore
+}
+
+// materialBalance returns side's material minus the opponent's, in centipawns.
+func materialBalance(b *board.Board, side color.Color) int {
+	balance := 0
+	for row := location.CoordinateType(0); row < board.Height; row++ {
+		for col := location.CoordinateType(0); col < board.Width; col++ {
+			p := b.GetPiece(location.NewLocation(row, col))
+			if p == nil || p.GetPieceType() == piece.KingType {
+				continue
+			}
+			value := ai.PieceValue[p.GetPieceType()] * ai.PawnValueWeight
+			if p.GetColor() != side {
+				value = -value
+			}
+			balance += value
+		}
+	}
+	return balance
+}
+
+func countPieces(b *board.Board) int {
+	count := 0
+	for row := location.CoordinateType(0); row < board.Height; row++ {
+		for col := location.CoordinateType(0); col < board.Width; col++ {
+			if b.GetPiece(location.NewLocation(row, col)) != nil {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// decideAfterSearch runs after our PlayTurn and before the move is posted. It
+// resigns a hopeless game (returning true, in which case the move must not be
+// posted) or marks the move to carry a draw offer. Must be called with the
+// mutex held.
+func (l *Lichess) decideAfterSearch() (resigned bool) {
+	if !l.Player.HasLastScore {
+		return false
+	}
+	policy := l.decisionPolicy()
+	l.decisions.recordScore(policy, l.Player.LastScore)
+	if l.decisions.shouldResign(policy) {
+		log.Infof("resigning game %s: score %d for %d moves", l.GameID, l.decisions.score, l.decisions.losingMoves)
+		if err := l.Resign(l.GameID); err != nil {
+			log.Errorf("failed to resign game %s, playing on: %s", l.GameID, err)
+			return false
+		}
+		return true
+	}
+	if l.decisions.shouldOfferDraw(policy, l.Game.CurrentBoard, l.Player.TurnCount) {
+		log.Infof("offering a draw in game %s: score %d for %d moves", l.GameID, l.decisions.score, l.decisions.equalMoves)
+		l.offerDrawNextMove = true
+	}
+	return false
+}
+
+// respondToOffers answers the opponent's draw offer or takeback request in
+// event, if any. It returns true when a draw was accepted, i.e. the game is
+// over and no move should follow. Must be called with the mutex held.
+func (l *Lichess) respondToOffers(event *GameEvent) (drawAccepted bool) {
+	opponent := l.Player.PlayerColor ^ 1
+	drawOffered, takebackOffered := event.WhiteDraw, event.WhiteTakeback
+	if opponent == color.Black {
+		drawOffered, takebackOffered = event.BlackDraw, event.BlackTakeback
+	}
+	ply := len(strings.Fields(event.Moves))
+	policy := l.decisionPolicy()
+
+	if drawOffered && l.decisions.drawAnswered != ply+1 {
+		l.decisions.drawAnswered = ply + 1
+		ourMS, theirMS := event.WhiteTimeMS, event.BlackTimeMS
+		if l.Player.PlayerColor == color.Black {
+			ourMS, theirMS = theirMS, ourMS
+		}
+		accept := l.decisions.acceptDraw(policy, l.Game.CurrentBoard, l.Player.PlayerColor,
+			time.Duration(ourMS)*time.Millisecond, time.Duration(theirMS)*time.Millisecond)
+		log.Infof("opponent offers a draw in game %s (score %d, have score %t): accept=%t",
+			l.GameID, l.decisions.score, l.decisions.haveScore, accept)
+		if err := l.RespondDraw(l.GameID, accept); err != nil {
+			log.Errorf("failed to answer draw offer in game %s: %s", l.GameID, err)
+		} else if accept {
+			return true
+		}
+	}
+	if takebackOffered && l.decisions.takebackAnswered != ply+1 {
+		l.decisions.takebackAnswered = ply + 1
+		log.Infof("opponent requests a takeback in game %s: accept=%t", l.GameID, policy.AcceptTakebacks)
+		if err := l.RespondTakeback(l.GameID, policy.AcceptTakebacks); err != nil {
+			log.Errorf("failed to answer takeback in game %s: %s", l.GameID, err)
+		} else if policy.AcceptTakebacks {
+			l.decisions.takebackAccepted = true
+		}
+	}
+	return false
+}
+
+// rebuildGameLocked replaces the local game with one replaying moves from the
+// game's start position. Lichess shortens the move list after a granted takeback, and
+// the local board cannot unmake moves. Must be called with the mutex held.
+func (l *Lichess) rebuildGameLocked(moves []string) {
+	l.stopPonder()
+	l.Game.Stop()
+	if err := l.newLocalGameLocked(); err != nil {
+		log.Errorf("failed to rebuild game %s: %s", l.GameID, err)
+	}
+	l.Player.TurnCount = 0
+	l.Player.IncrementTTGeneration()
+	for _, m := range moves {
+		l.applyOpponentMove(parseUCIMove(m))
+	}
+	l.movesApplied = len(moves)
+	l.decisions.losingMoves, l.decisions.equalMoves = 0, 0
+	l.decisions.takebackAccepted = false
+}
+
+func (l *Lichess) Resign(gameID string) error {
+	return l.postGameAction(gameID, "resign")
+}
+
+func (l *Lichess) RespondDraw(gameID string, accept bool) error {
+	return l.postGameAction(gameID, "draw/"+yesNo(accept))
+}
+
+func (l *Lichess) RespondTakeback(gameID string, accept bool) error {
+	return l.postGameAction(gameID, "takeback/"+yesNo(accept))
+}
+
+func (l *Lichess) postGameAction(gameID, action string) error {
+	return l.post(fmt.Sprintf("/api/bot/game/%s/%s", gameID, action))
+}
+
+// post sends a body-less POST and fails unless Lichess answers 200.
+func (l *Lichess) post(u string) error {
+	r, err := l.Client.newRequest("POST", u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := l.Client.HttpClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	log.Infof("%s status %s %s", u, resp.Status, string(bodyBytes))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s rejected: %s", u, string(bodyBytes))
+	}
+	return nil
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/server/lichesstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecisionThresholds(t *testing.T) {
+	policy := game_config.DefaultDecisionPolicy()
+	start := &board.Board{}
+	start.ResetDefault()
+
+	var d gameDecisions
+	assert.False(t, d.acceptDraw(policy, start, color.White, time.Minute, time.Minute), "no score yet: still in book")
+	d.recordScore(policy, 50)
+	assert.False(t, d.acceptDraw(policy, start, color.White, time.Minute, time.Minute), "better: play on")
+	assert.True(t, d.acceptDraw(policy, start, color.White, 5*time.Second, time.Minute), "slightly better but short of time")
+	assert.False(t, d.acceptDraw(policy, start, color.White, 5*time.Second, 2*time.Second), "opponent is shorter of time")
+	d.recordScore(policy, -20)
+	assert.True(t, d.acceptDraw(policy, start, color.White, time.Minute, time.Minute))
+
+	for i := 0; i < policy.ResignMoves-1; i++ {
+		d.recordScore(policy, policy.ResignScore-1)
+	}
+	assert.False(t, d.shouldResign(policy))
+	d.recordScore(policy, policy.ResignScore)
+	assert.True(t, d.shouldResign(policy))
+	d.recordScore(policy, 0)
+	assert.False(t, d.shouldResign(policy), "a playable score resets the count")
+
+	for i := 1; i < policy.DrawOfferMoves; i++ {
+		d.recordScore(policy, 0)
+	}
+	assert.True(t, d.equalMoves >= policy.DrawOfferMoves)
+	assert.False(t, d.shouldOfferDraw(policy, start, policy.DrawOfferMinMove), "too many pieces left")
+	assert.Equal(t, 0, materialBalance(start, color.Black))
+	assert.Equal(t, 32, countPieces(start))
+}
+
+// TestAnswersTakebackAndDrawOffers grants a takeback against the fake server,
+// checks the bot resynchronizes and plays on, then declines a draw offer.
+func TestAnswersTakebackAndDrawOffers(t *testing.T) {
+	fake := lichesstest.NewServer("TestBot")
+	defer fake.Close()
+	fake.Token = "test-token"
+	t.Setenv("LICHESS_TOKEN", fake.Token)
+
+	bot := ConnectLichess(fake.URL).(*Lichess)
+	policy := game_config.DefaultDecisionPolicy()
+	policy.AcceptTakebacks = true
+	policy.DrawAcceptScore = -100000
+	bot.DecisionPolicy = policy
+	done := runUntilDone(bot)
+	defer stopBot(t, bot, done)
+
+	id := fake.Challenge(lichesstest.ChallengeOptions{Rated: true, Initial: time.Minute, BotColor: color.Black})
+	waitFor := func(what string, done func(r lichesstest.GameResult) bool) lichesstest.GameResult {
+		t.Helper()
+		deadline := time.Now().Add(30 * time.Second)
+		for {
+			r, _ := fake.Game(id)
+			if done(r) {
+				return r
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s: %+v", what, r)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor("game start", func(r lichesstest.GameResult) bool { return r.Status == lichesstest.StatusStarted })
+	assert.NoError(t, fake.PlayOpponentMove(id, "e2e4"))
+	waitFor("bot reply", func(r lichesstest.GameResult) bool { return len(r.Moves) == 2 })
+
+	assert.NoError(t, fake.ProposeTakeback(id))
+	r := waitFor("takeback", func(r lichesstest.GameResult) bool { return len(r.TakebackAnswers) == 1 })
+	assert.Equal(t, []string{"yes"}, r.TakebackAnswers)
+	assert.Empty(t, r.Moves)
+
+	assert.NoError(t, fake.PlayOpponentMove(id, "d2d4"))
+	r = waitFor("bot reply after takeback", func(r lichesstest.GameResult) bool { return len(r.Moves) == 2 })
+	assert.Equal(t, "d2d4", r.Moves[0])
+	assert.Zero(t, r.RejectedMoves, "the bot must play from the rewound position")
+
+	assert.NoError(t, fake.OfferDraw(id))
+	r = waitFor("draw answer", func(r lichesstest.GameResult) bool { return len(r.DrawAnswers) == 1 })
+	assert.Equal(t, []string{"no"}, r.DrawAnswers)
+	assert.Equal(t, lichesstest.StatusStarted, r.Status)
+}
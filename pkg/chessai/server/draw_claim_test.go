@@ -5,6 +5,7 @@ import (
 	"math/rand"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -12,12 +13,17 @@ import (
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/analysis"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/config"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/game"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/player"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
 	"github.com/stretchr/testify/assert"
 )
 
+func TestMain(m *testing.M) {
+	os.Exit(config.RunWithTempLogs(m))
+}
+
 // recordingClient captures every request URL and returns 200 {"ok":true}.
 type recordingClient struct{ urls []string }
 
@@ -35,7 +41,7 @@ func randomAI(c color.Color) *ai.AIPlayer {
 }
 
 func TestThinkTimeForClockHonorsConfiguredCap(t *testing.T) {
-	assert.Equal(t, 3*time.Second, thinkTimeForClock(180*time.Second, 0, 15))
+	assert.Equal(t, 3*time.Second, ai.ThinkTimeForClock(180*time.Second, 0, 15))
 }
 
 // TestThinkTimeForClockScalesCapForLongerTimeControls reproduces the 10+5
@@ -43,18 +49,18 @@ func TestThinkTimeForClockHonorsConfiguredCap(t *testing.T) {
 // longer time control the bot never spent more than 3s/move even with a
 // 10-minute clock banked. The cap should scale up when there's plenty of time.
 func TestThinkTimeForClockScalesCapForLongerTimeControls(t *testing.T) {
-	blitz := thinkTimeForClock(180*time.Second, 0, 15)
+	blitz := ai.ThinkTimeForClock(180*time.Second, 0, 15)
 	assert.Equal(t, 3*time.Second, blitz, "3+0 baseline should keep the old 3s cap")
 
-	longFormat := thinkTimeForClock(10*time.Minute, 5*time.Second, 15)
+	longFormat := ai.ThinkTimeForClock(10*time.Minute, 5*time.Second, 15)
 	assert.True(t, longFormat > blitz, "10+5 with the same move number should think longer than 3+0, got %s vs %s", longFormat, blitz)
 	assert.True(t, longFormat >= 10*time.Second, "10+5 should scale the cap up toward the clock size, got %s", longFormat)
 }
 
 func TestThinkTimeForClockCapsOpeningMoves(t *testing.T) {
-	assert.Equal(t, 500*time.Millisecond, thinkTimeForClock(180*time.Second, 0, 0))
-	assert.Equal(t, 500*time.Millisecond, thinkTimeForClock(180*time.Second, 0, 3))
-	assert.Equal(t, 3*time.Second, thinkTimeForClock(180*time.Second, 0, 4))
+	assert.Equal(t, 500*time.Millisecond, ai.ThinkTimeForClock(180*time.Second, 0, 0))
+	assert.Equal(t, 500*time.Millisecond, ai.ThinkTimeForClock(180*time.Second, 0, 3))
+	assert.Equal(t, 3*time.Second, ai.ThinkTimeForClock(180*time.Second, 0, 4))
 }
 
 // TestThinkTimeForClockShrinksAndBuffersEndgame checks the 3+0 tuning: from the
@@ -63,8 +69,8 @@ func TestThinkTimeForClockCapsOpeningMoves(t *testing.T) {
 func TestThinkTimeForClockShrinksAndBuffersEndgame(t *testing.T) {
 	const clock = 45 * time.Second
 
-	midgame := thinkTimeForClock(clock, 0, 14) // ~ply 28
-	endgame := thinkTimeForClock(clock, 0, 40) // deep endgame, reserve fully ramped
+	midgame := ai.ThinkTimeForClock(clock, 0, 14) // ~ply 28
+	endgame := ai.ThinkTimeForClock(clock, 0, 40) // deep endgame, reserve fully ramped
 
 	// Endgame moves cost less than midgame moves at the same clock.
 	assert.True(t, endgame < midgame, "endgame (%s) should think less than midgame (%s) at equal clock", endgame, midgame)
@@ -80,7 +86,7 @@ func TestThinkTimeForClockShrinksAndBuffersEndgame(t *testing.T) {
 }
 
 func TestThinkTimeForClockSpendsSurplusDeepEndgameClock(t *testing.T) {
-	think := thinkTimeForClock(60*time.Second, 0, 40)
+	think := ai.ThinkTimeForClock(60*time.Second, 0, 40)
 	assert.True(t, think >= 2*time.Second, "deep endgame with 60s left should spend enough to search, got %s", think)
 	assert.True(t, think <= 3*time.Second, "deep endgame think time should still honor cap, got %s", think)
 }
@@ -89,7 +95,7 @@ func TestThinkTimeForPositionExtendsCriticalQueenPasserEndgame(t *testing.T) {
 	parsed, err := analysis.ParseFEN("8/5k2/2Q4P/1P6/8/3KPP2/6q1/8 b - - 2 61")
 	assert.NoError(t, err)
 
-	base := thinkTimeForClock(50*time.Second, 0, 40)
+	base := ai.ThinkTimeForClock(50*time.Second, 0, 40)
 	critical := thinkTimeForPosition(50*time.Second, 0, 40, parsed.Board, color.Black)
 
 	assert.True(t, base < 2*time.Second, "test setup expected base think time below critical floor, got %s", base)
@@ -100,7 +106,7 @@ func TestThinkTimeForPositionExtendsLowMaterialRookEndgame(t *testing.T) {
 	parsed, err := analysis.ParseFEN("8/7k/4r3/1K4RP/4N1P1/5P2/8/8 b - g3 0 65")
 	assert.NoError(t, err)
 
-	base := thinkTimeForClock(22*time.Second, 0, 65)
+	base := ai.ThinkTimeForClock(22*time.Second, 0, 65)
 	critical := thinkTimeForPosition(22*time.Second, 0, 65, parsed.Board, color.Black)
 
 	assert.True(t, base < 200*time.Millisecond, "test setup expected tiny base think time, got %s", base)
@@ -112,7 +118,7 @@ func TestThinkTimeForPositionDoesNotExtendQuietOpening(t *testing.T) {
 	b.ResetDefault()
 
 	assert.Equal(t,
-		thinkTimeForClock(50*time.Second, 0, 6),
+		ai.ThinkTimeForClock(50*time.Second, 0, 6),
 		thinkTimeForPosition(50*time.Second, 0, 6, b, color.White),
 	)
 }
@@ -188,8 +194,9 @@ func TestPlaysOnAfterOwnMoveLeftClaimableDraw(t *testing.T) {
 		l.Game.PlayTurnMove(parseUCIMove(m))
 	}
 	l.movesApplied = len(setup)
-	assert.Equal(t, game.RepeatedActionThreeTimeDraw, l.Game.GameStatus,
-		"setup: our move should have left the local game in a claimable draw")
+	assert.Equal(t, game.Active, l.Game.GameStatus, "setup: a threefold does not end the game")
+	assert.Equal(t, game.RepeatedActionThreeTimeDraw, l.Game.ClaimableDraw(),
+		"setup: our move should have left a claimable draw on the board")
 	assert.Equal(t, color.Black, l.Game.CurrentTurnColor,
 		"setup: it should be the opponent's turn after our move")
 
@@ -225,7 +232,7 @@ func TestPlaysOnAfterOwnMoveLeftClaimableDraw(t *testing.T) {
 }
 
 // TestMakeMoveOffersDrawForClaimableStatuses locks in that MakeMove offers a draw for
-// both claimable draw statuses and not otherwise.
+// both claimable draw statuses and dead positions, and not otherwise.
 func TestMakeMoveOffersDrawForClaimableStatuses(t *testing.T) {
 	cases := []struct {
 		status     byte
@@ -234,6 +241,8 @@ func TestMakeMoveOffersDrawForClaimableStatuses(t *testing.T) {
 	}{
 		{game.RepeatedActionThreeTimeDraw, true, "threefold"},
 		{game.FiftyMoveDraw, true, "fifty-move"},
+		{game.DeadPositionDraw, true, "dead position"},
+		{game.FivefoldRepetitionDraw, false, "fivefold"},
 		{game.Active, false, "active"},
 		{game.BlackWin, false, "loss"},
 	}
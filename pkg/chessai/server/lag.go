@@ -0,0 +1,122 @@
+package server
+
+import (
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+)
+
+// maxLagSample bounds a single lag sample; anything larger is a clock
+// anomaly (e.g. a server restart) rather than network delay.
+const maxLagSample = 3 * time.Second
+
+// lagEstimator measures the time our moves spend between Lichess and us: the
+// clock Lichess charged for a move, less the time we took to answer between
+// receiving the opponent's move and posting ours. It keeps a moving average
+// and mean deviation, like a TCP round-trip estimator, and is not reset
+// between games since lag belongs to the link.
+type lagEstimator struct {
+	// turnPly, turnStart, turnClock and turnIncrement describe the turn we are
+	// answering: the ply count, when its gameState arrived, and our clock and
+	// increment in it.
+	turnPly       int
+	turnStart     time.Time
+	turnClock     time.Duration
+	turnIncrement time.Duration
+	// pendingPly is the ply count after the move we posted at sentAt, or 0
+	// when no sample is outstanding.
+	pendingPly int
+	sentAt     time.Time
+
+	average   time.Duration
+	deviation time.Duration
+	last      time.Duration
+	samples   int
+}
+
+// turnStarted records the gameState that put us on move at ply. Lichess only
+// runs the clock once both sides have moved, so earlier turns are skipped.
+func (e *lagEstimator) turnStarted(ply int, at time.Time, clock, increment time.Duration) {
+	e.pendingPly = 0
+	if at.IsZero() {
+		at = time.Now()
+	}
+	if ply < 2 {
+		e.turnPly = 0
+		return
+	}
+	e.turnPly, e.turnStart, e.turnClock, e.turnIncrement = ply, at, clock, increment
+}
+
+// moveSent records that our move at ply was posted at at.
+func (e *lagEstimator) moveSent(ply int, at time.Time) {
+	if e.turnPly == 0 || ply != e.turnPly {
+		return
+	}
+	e.pendingPly, e.sentAt = ply+1, at
+	e.turnPly = 0
+}
+
+// cancel drops the turn and sample in progress, keeping the estimate.
+func (e *lagEstimator) cancel() {
+	e.turnPly, e.pendingPly = 0, 0
+}
+
+// observe takes a sample from the gameState that includes our move, with
+// clock being what is left on our clock in it, and reports whether it did.
+func (e *lagEstimator) observe(ply int, clock time.Duration) bool {
+	if e.pendingPly == 0 || ply != e.pendingPly {
+		return false
+	}
+	e.pendingPly = 0
+	charged := e.turnClock + e.turnIncrement - clock
+	lag := charged - e.sentAt.Sub(e.turnStart)
+	if lag < 0 {
+		// Lichess compensates some lag; it cannot be negative for us.
+		lag = 0
+	}
+	if lag > maxLagSample {
+		lag = maxLagSample
+	}
+	e.add(lag)
+	return true
+}
+
+func (e *lagEstimator) add(lag time.Duration) {
+	e.last = lag
+	if e.samples == 0 {
+		e.average, e.deviation = lag, lag/2
+	} else {
+		diff := lag - e.average
+		if diff < 0 {
+			diff = -diff
+		}
+		e.deviation += (diff - e.deviation) / 4
+		e.average += (lag - e.average) / 8
+	}
+	e.samples++
+}
+
+// overhead is the time to hold back from each move for the network: the
+// average lag plus twice its deviation, but at least floor.
+func (e *lagEstimator) overhead(floor time.Duration) time.Duration {
+	if o := e.average + 2*e.deviation; e.samples > 0 && o > floor {
+		return o
+	}
+	return floor
+}
+
+// moveOverhead is the current per-move network allowance.
+func (l *Lichess) moveOverhead() time.Duration {
+	return l.lag.overhead(time.Duration(game_config.Get().MoveOverheadMinMs) * time.Millisecond)
+}
+
+// withMoveOverhead takes the move overhead off a think time: the clock keeps
+// running while our move travels to Lichess.
+func withMoveOverhead(think, overhead time.Duration) time.Duration {
+	think -= overhead
+	if think < 50*time.Millisecond {
+		think = 50 * time.Millisecond
+	}
+	return think
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/server/lichesstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLagEstimator(t *testing.T) {
+	var e lagEstimator
+	floor := 100 * time.Millisecond
+	assert.Equal(t, floor, e.overhead(floor), "no samples yet")
+
+	start := time.Now()
+	e.turnStarted(0, start, time.Minute, 0)
+	e.moveSent(0, start.Add(time.Second))
+	assert.False(t, e.observe(1, time.Minute-2*time.Second), "clocks only run from the second move")
+
+	// We took 1s, Lichess charged 1.3s (less the 2s increment).
+	e.turnStarted(4, start, time.Minute, 2*time.Second)
+	e.moveSent(4, start.Add(time.Second))
+	assert.False(t, e.observe(4, time.Minute), "a repeated gameState before our move is no sample")
+	assert.True(t, e.observe(5, time.Minute+700*time.Millisecond))
+	assert.Equal(t, 300*time.Millisecond, e.average)
+	assert.Equal(t, 600*time.Millisecond, e.overhead(floor))
+	assert.False(t, e.observe(5, time.Minute), "one sample per move")
+
+	for i := 0; i < 30; i++ {
+		e.add(300 * time.Millisecond)
+	}
+	assert.InDelta(t, float64(300*time.Millisecond), float64(e.overhead(floor)), float64(20*time.Millisecond), "steady lag leaves little deviation")
+	assert.Equal(t, time.Second, e.overhead(time.Second), "the floor still applies")
+
+	assert.Equal(t, 700*time.Millisecond, withMoveOverhead(time.Second, 300*time.Millisecond))
+	assert.Equal(t, 50*time.Millisecond, withMoveOverhead(200*time.Millisecond, 300*time.Millisecond))
+}
+
+// TestMeasuresLagFromClocks plays against a fake server that charges every
+// bot move 300ms extra and checks the bot's estimate finds it.
+func TestMeasuresLagFromClocks(t *testing.T) {
+	fake := lichesstest.NewServer("TestBot")
+	fake.Token = "test-token"
+	fake.Lag = 300 * time.Millisecond
+	fake.Opponent = lichesstest.ScriptedOpponent("g8f6", "b8c6", "h8g8", "a8b8")
+	defer fake.Close()
+	t.Setenv("LICHESS_TOKEN", fake.Token)
+
+	bot := ConnectLichess(fake.URL).(*Lichess)
+	done := runUntilDone(bot)
+	defer stopBot(t, bot, done)
+
+	id := fake.Challenge(lichesstest.ChallengeOptions{Rated: true, Initial: 5 * time.Minute, BotColor: color.White})
+	result, err := fake.WaitGame(id, 60*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, lichesstest.StatusResign, result.Status, "moves %v", result.Moves)
+
+	bot.Mutex.Lock()
+	defer bot.Mutex.Unlock()
+	if assert.NotZero(t, bot.lag.samples) {
+		assert.InDelta(t, float64(fake.Lag), float64(bot.lag.average), float64(100*time.Millisecond))
+		assert.True(t, bot.moveOverhead() >= fake.Lag)
+	}
+}
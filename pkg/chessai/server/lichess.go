@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/analysis"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
 	"github.com/Vadman97/GolangChessAI/pkg/chessai/game"
@@ -43,6 +44,7 @@ type StateType string
 const (
 	StateTypeGame     = "gameState"
 	StateTypeGameFull = "gameFull"
+	StateTypeChatLine = "chatLine"
 )
 
 type Game struct {
@@ -56,17 +58,67 @@ type Game struct {
 	IsMyTurn        bool    `json:"isMyTurn"`
 	SecondsLeft     float64 `json:"secondsLeft"`
 	Source          string  `json:"source"`
+	Rated           bool    `json:"rated"`
+	// Status and Winner ("white" or "black", empty for a draw) describe the
+	// result on gameFinish.
+	Status   *GameStatus   `json:"status"`
+	Winner   string        `json:"winner"`
+	Opponent *GameOpponent `json:"opponent"`
+}
+
+type GameStatus struct {
+	Name string `json:"name"` // "started", "mate", "resign", "aborted", ...
+}
+
+type GameOpponent struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Rating   int    `json:"rating"`
 }
 
 type ChallengeUser struct {
-	ID string `json:"id"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Title  string `json:"title"`  // "BOT" for bot accounts
+	Rating int    `json:"rating"` // rating for the challenge's speed
+}
+
+type ChallengeVariant struct {
+	Key string `json:"key"`
+}
+
+type ChallengeTimeControl struct {
+	Type      string `json:"type"` // "clock", "correspondence" or "unlimited"
+	Limit     int    `json:"limit"`
+	Increment int    `json:"increment"`
 }
 
 type Challenge struct {
-	ID         string         `json:"id"`
-	Direction  string         `json:"direction"`  // present in API responses, absent in event stream
-	Challenger *ChallengeUser `json:"challenger"` // the user who sent the challenge
-	Rated      bool           `json:"rated"`
+	ID          string                `json:"id"`
+	Direction   string                `json:"direction"`  // present in API responses, absent in event stream
+	Challenger  *ChallengeUser        `json:"challenger"` // the user who sent the challenge
+	DestUser    *ChallengeUser        `json:"destUser"`
+	Rated       bool                  `json:"rated"`
+	Variant     *ChallengeVariant     `json:"variant"`
+	TimeControl *ChallengeTimeControl `json:"timeControl"`
+	// DeclineReason is set on challengeDeclined events.
+	DeclineReason string `json:"declineReason"`
+}
+
+// Account is the subset of /api/account the bot needs to know who it is. The
+// online bot list (/api/bot/online) uses the same shape.
+type Account struct {
+	ID       string          `json:"id"`
+	Username string          `json:"username"`
+	Title    string          `json:"title"`
+	Perfs    map[string]Perf `json:"perfs"`
+}
+
+// Perf is a rating in one speed category ("bullet", "blitz", ...).
+type Perf struct {
+	Rating      int  `json:"rating"`
+	Games       int  `json:"games"`
+	Provisional bool `json:"prov"`
 }
 
 type Event struct {
@@ -84,9 +136,25 @@ type GameEvent struct {
 	BlackIncMS  int        `json:"binc"`
 	Status      string     `json:"status"`
 	State       *GameEvent `json:"state"`
+	// InitialFen is "startpos" or the FEN a game from position started at;
+	// only gameFull has it.
+	InitialFen string `json:"initialFen"`
+	// Pending draw offers and takeback proposals by each side.
+	WhiteDraw     bool `json:"wdraw"`
+	BlackDraw     bool `json:"bdraw"`
+	WhiteTakeback bool `json:"wtakeback"`
+	BlackTakeback bool `json:"btakeback"`
+	// Username, Text and Room are the author, text and room ("player" or
+	// "spectator") of a chatLine.
+	Username string `json:"username"`
+	Text     string `json:"text"`
+	Room     string `json:"room"`
 	// GameID is set locally (not from JSON) so the handler can discard stale
 	// events from a previous game's stream that are still in the channel.
 	GameID string `json:"-"`
+	// ReceivedAt is when the event was read off the stream, for lag
+	// measurement.
+	ReceivedAt time.Time `json:"-"`
 }
 
 type ChallengeConfig struct {
@@ -108,36 +176,87 @@ type Lichess struct {
 	Events           chan Event
 	GameEvents       chan GameEvent
 	ChallengeOnStart *ChallengeConfig
-	// exitAfterGame signals Run() to stop after the first game finishes.
-	exitAfterGame chan struct{}
+	// Policy overrides game_config's ChallengePolicy when set.
+	Policy *game_config.ChallengePolicy
+	// DecisionPolicy overrides game_config's DecisionPolicy when set.
+	DecisionPolicy *game_config.DecisionPolicy
+	// botID is our own Lichess user ID from /api/account, used to recognize
+	// our outgoing challenges in the event stream.
+	botID string
+	// challengeQueue holds IDs of acceptable challenges that arrived while a
+	// game was in progress, oldest first.
+	challengeQueue []string
+	// Matchmaking overrides game_config's Matchmaking when set.
+	Matchmaking *game_config.Matchmaking
+	// matchmaker is the state of outgoing challenges to online bots.
+	matchmaker matchmaker
+	// Chat overrides game_config's Chat when set.
+	Chat *game_config.Chat
+	// Tournament, when set, puts the bot in tournament mode: it plays in that
+	// event instead of taking challenges.
+	Tournament *game_config.Tournament
+	// tournament is the state of the event in tournament mode.
+	tournament tournamentState
+	// exit signals Run() to stop: after the first game in challenge mode,
+	// after the last game once the bot has left the event in tournament mode,
+	// and when a shutdown is done.
+	exit chan struct{}
+	// shutdown is the state of a graceful shutdown (see Shutdown).
+	shutdown shutdownState
 	// movesApplied tracks how many total moves from lichess events we've applied
 	// to our local board. Used to skip duplicate events (e.g. after stream reconnect).
 	movesApplied int
 
+	// initialFEN is the current game's start position from gameFull, "" for
+	// the standard start; initialSide is the side to move in it.
+	initialFEN  string
+	initialSide color.Color
+
 	// clockIncrement is the per-move increment for the current game, set from
-	// the first gameState event and used by thinkTimeForClock.
+	// the first gameState event and used by ai.ThinkTimeForClock.
 	clockIncrement time.Duration
+	// lag estimates network delay from our clock in consecutive gameStates;
+	// it sets the move overhead taken off every think time.
+	lag lagEstimator
 
 	// offerDrawNextMove preserves a claimable draw detected before our move.
 	// PlayTurn may move away from the repeated position and set GameStatus back to
 	// Active, but the outbound Lichess move should still carry offeringDraw=true.
 	offerDrawNextMove bool
 
+	// decisions tracks scores and answered offers for draw, resign and
+	// takeback decisions in the current game.
+	decisions gameDecisions
+	// chat rate-limits answers to chat commands in the current game.
+	chat gameChat
+
 	// boardStreamCancel cancels the active game board stream so stale goroutines
 	// from previous games don't corrupt the current game's board state.
 	boardStreamCancel context.CancelFunc
 
-	// Pondering: search during the opponent's turn to warm the TT.
+	// Pondering: search during the opponent's turn. With a predicted reply,
+	// predicted is the search of our answer to it; otherwise the ponder only
+	// warms the TT.
 	ponderStop chan struct{}
 	ponderDone chan struct{}
+	predicted  *predictedPonder
+	// ponderStats counts predicted replies and hits in the current game.
+	ponderStats ponderStats
 }
 
-func ConnectLichess() Server {
-	return ConnectLichessWithChallenge(nil)
+// LichessURL is the production Lichess host. Request paths carry their own
+// /api prefix, so a fake server (see lichesstest) is addressed by its root URL.
+const LichessURL = "https://lichess.org"
+
+func ConnectLichess(baseURL string) Server {
+	return ConnectLichessWithChallenge(baseURL, nil)
 }
 
-func ConnectLichessWithChallenge(challenge *ChallengeConfig) Server {
-	base, _ := url.Parse("https://lichess.org/api")
+func ConnectLichessWithChallenge(baseURL string, challenge *ChallengeConfig) Server {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		log.Fatalf("invalid Lichess URL %q: %s", baseURL, err)
+	}
 	s := &Lichess{
 		Mutex: sync.Mutex{},
 		Client: &Client{
@@ -149,16 +268,33 @@ func ConnectLichessWithChallenge(challenge *ChallengeConfig) Server {
 		Events:           make(chan Event),
 		GameEvents:       make(chan GameEvent),
 		ChallengeOnStart: challenge,
+		exit:             make(chan struct{}),
 	}
-	if challenge != nil {
-		s.exitAfterGame = make(chan struct{})
-	}
 	return s
 }
 
-// startPonder begins a background search on the current board position so the
-// transposition table is warm when it's our turn again. Must be called with
-// the Lichess mutex held (it snapshots the board and then releases into a goroutine).
+// ConnectLichessForTournament connects a bot that plays in the arena or Swiss
+// selected by t until it ends (see game_config.Tournament).
+func ConnectLichessForTournament(baseURL string, t *game_config.Tournament) Server {
+	s := ConnectLichessWithChallenge(baseURL, nil).(*Lichess)
+	s.Tournament = t
+	return s
+}
+
+// exitLocked stops Run. Must be called with the mutex held.
+func (l *Lichess) exitLocked() {
+	select {
+	case <-l.exit:
+	default:
+		close(l.exit)
+	}
+}
+
+// startPonder begins a background search while the opponent thinks: our
+// answer to their predicted reply (see startPredictedPonder) or, without a
+// prediction, their side of the current position so the transposition table
+// is warm when it's our turn again. Must be called with the Lichess mutex held
+// (it snapshots the board and then releases into a goroutine).
 func (l *Lichess) startPonder() {
 	if l.Player == nil || l.Game == nil {
 		return
@@ -173,11 +309,14 @@ func (l *Lichess) startPonder() {
 	if l.Game.GameStatus != game.Active {
 		return
 	}
+	if l.startPredictedPonder() {
+		return
+	}
 	boardSnap := l.Game.CurrentBoard.Copy()
 	prevMove := l.Game.PreviousMove
 	ponderColor := l.Game.CurrentTurnColor
 	ponderPlayer := l.Player.NewPonderPlayer(ponderColor)
-	ponderPlayer.MaxThinkTime = 60 * time.Second
+	ponderPlayer.MaxThinkTime = ponderTimeCap
 
 	stop := make(chan struct{})
 	done := make(chan struct{})
@@ -219,6 +358,7 @@ func (l *Lichess) stopPonder() {
 	}
 	l.ponderStop = nil
 	l.ponderDone = nil
+	l.predicted = nil
 	if l.Player != nil {
 		l.Player.ResetAbort()
 	}
@@ -241,107 +381,15 @@ func (l *Lichess) resetGame() {
 	l.Player = nil
 	l.Game = nil
 	l.movesApplied = 0
-}
-
-// thinkTimeForClock allocates think time from the remaining clock.
-// Uses the standard time-management formula: think = usableTime/movesLeft + increment.
-//
-// Tuned for 3+0: spend the clock in the opening/midgame (where the position is
-// rich and the per-move cap binds anyway) and shrink aggressively in the
-// endgame, holding back a growing safety buffer so we don't flag in long
-// K+P / R endgames.
-//
-//   - endgameStart (turnCount 17 ≈ ply 35): both the per-move divisor and the
-//     reserve begin to ramp up, so each endgame move costs less and we keep more
-//     time in the bank.
-//   - The reserve grows from 3s up to 18s (i.e. ~15s more buffer than before)
-//     as the endgame deepens.
-//   - The first few moves are capped to 500ms so opening play stays responsive.
-//   - Capped by game_conf.json AIMaxThinkTimeMs (or 8s if unset) so we never
-//     burn the clock on a single move.
-func thinkTimeForClock(timeLeft, increment time.Duration, turnCount int) time.Duration {
-	const baseReserve = 3 * time.Second
-	const maxExtraReserve = 15 * time.Second // larger buffer once we reach the endgame
-	const openingCap = 500 * time.Millisecond
-
-	// Endgame begins ~35 ply, i.e. this side's ~17th move (TurnCount counts our
-	// own moves). reserveRamp is the number of our moves over which the reserve
-	// climbs from baseReserve to baseReserve+maxExtraReserve.
-	const endgameStart = 17
-	const reserveRamp = 13
-
-	// Reserve ramps up through the endgame so we always keep a healthy buffer.
-	reserve := baseReserve
-	if turnCount > endgameStart {
-		grown := time.Duration(turnCount-endgameStart) * (maxExtraReserve / reserveRamp)
-		if grown > maxExtraReserve {
-			grown = maxExtraReserve
-		}
-		reserve += grown
-	}
-
-	// Estimated moves remaining for THIS side. Low in the midgame (spend more of
-	// the clock where it matters), then rising through the endgame so per-move
-	// time shrinks aggressively and the growing reserve survives.
-	var movesLeft time.Duration
-	switch {
-	case turnCount < 10:
-		movesLeft = 45
-	case turnCount < endgameStart:
-		movesLeft = 22
-	case turnCount < 25:
-		movesLeft = 28
-	case turnCount < 35:
-		movesLeft = 34
-	default:
-		movesLeft = 40
-	}
-
-	usable := timeLeft - reserve
-	if usable < 0 {
-		usable = 0
-	}
-	think := usable/movesLeft + increment
-	// In long endgames we still want the larger reserve, but not at the cost of
-	// blitzing critical queen/pawn positions while a minute remains. Spend a
-	// controlled slice of clock above 45s; below that, keep the conservative
-	// scramble behavior covered by TestThinkTimeForClockShrinksAndBuffersEndgame.
-	if turnCount >= 35 && timeLeft > 45*time.Second {
-		think += (timeLeft - 45*time.Second) / 15
-	}
-	if think < 50*time.Millisecond {
-		think = 50 * time.Millisecond
-	}
-	if turnCount < 4 && think > openingCap {
-		think = openingCap
-	}
-	maxThink := maxThinkTimeForClock(timeLeft)
-	if think > maxThink {
-		think = maxThink
-	}
-	return think
-}
-
-// maxThinkTimeForClock caps a single move's think time. The configured
-// AIMaxThinkTimeMs is tuned for 3+0 blitz (where it binds through most of the
-// game); at longer time controls that fixed cap made the bot think it had far
-// less time than it actually did, since it never scaled up with a bigger
-// clock. Scale the cap with the clock itself (1/60th of remaining time, so a
-// 3-minute clock reproduces the old 3s cap exactly) and take whichever cap is
-// larger.
-func maxThinkTimeForClock(timeLeft time.Duration) time.Duration {
-	maxThink := 8 * time.Second
-	if game_config.Get().AIMaxThinkTimeMs > 0 {
-		maxThink = game_config.Get().AIMaxThinkTimeMs * time.Millisecond
-	}
-	if dynamicCap := timeLeft / 60; dynamicCap > maxThink {
-		maxThink = dynamicCap
-	}
-	return maxThink
+	l.decisions = gameDecisions{}
+	l.chat = gameChat{}
+	l.lag.cancel()
+	l.ponderStats = ponderStats{}
+	l.initialFEN, l.initialSide = "", color.White
 }
 
 func thinkTimeForPosition(timeLeft, increment time.Duration, turnCount int, b *board.Board, side color.Color) time.Duration {
-	think := thinkTimeForClock(timeLeft, increment, turnCount)
+	think := ai.ThinkTimeForClock(timeLeft, increment, turnCount)
 	if b == nil || !isCriticalSearchPosition(b, side) || timeLeft <= 20*time.Second {
 		return think
 	}
@@ -349,7 +397,7 @@ func thinkTimeForPosition(timeLeft, increment time.Duration, turnCount int, b *b
 	if think < minThink {
 		think = minThink
 	}
-	maxThink := maxThinkTimeForClock(timeLeft)
+	maxThink := ai.MaxThinkTimeForClock(timeLeft)
 	if think > maxThink {
 		think = maxThink
 	}
@@ -441,113 +489,247 @@ func isPassedPawnForSearch(b *board.Board, row, col location.CoordinateType, paw
 	return true
 }
 
+// startGameLocked sets up a fresh player and local game for g and starts its
+// board stream. Must be called with the mutex held.
+func (l *Lichess) startGameLocked(g *Game) {
+	if l.Game != nil {
+		log.Warnf("gameStart received while game %s active — resetting stale game state", l.GameID)
+		l.resetGame()
+	}
+	l.GameID = g.GameID
+	l.matchmaker.gameStarted(g.GameID)
+	playerColor := color.White
+	if g.Color == "black" {
+		playerColor = color.Black
+	}
+	// Fresh algorithm + AIPlayer per game: NewAIPlayer allocates a new
+	// transposition table and evaluation cache, and NewAlgorithm gives an
+	// unshared search instance, so no state (caches or search heuristics)
+	// leaks from the previous game.
+	l.Player = ai.NewAIPlayer(playerColor, ai.NewAlgorithm(game_config.Get().Algorithm))
+	l.Player.MaxSearchDepth = game_config.Get().AIMaxSearchDepth
+	l.Player.MateSolverNodes = game_config.Get().AIMateSolverNodes
+	l.berserkLocked(g)
+	l.Player.MaxThinkTime = ai.ThinkTimeForClock(time.Duration(g.SecondsLeft*float64(time.Second)), l.clockIncrement, l.Player.TurnCount)
+	// The start position is standard until gameFull says otherwise.
+	l.initialFEN, l.initialSide = "", color.White
+	if err := l.newLocalGameLocked(); err != nil {
+		log.Errorf("failed to set up game %s: %s", g.GameID, err)
+	}
+	l.movesApplied = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	l.boardStreamCancel = cancel
+	go func() {
+		err := l.StreamBoardUpdate(ctx, g.GameID, l.GameEvents)
+		if err != nil && ctx.Err() == nil {
+			log.Errorf("failed to stream board update %s", err)
+		}
+	}()
+
+	// Only a fresh standard game is answered straight away. A resumed game,
+	// or one from a position, waits for gameFull to replay its moves first:
+	// playing here would send an opening move into the middle of the game.
+	if isFreshStandardGame(g) && l.Game.CurrentTurnColor == playerColor {
+		l.Game.PlayTurn()
+		if err := l.MakeMove(g.GameID, l.Game.PreviousMove); err != nil {
+			log.Errorf("first move rejected by lichess, resetting game state: %s", err)
+			l.resetGame()
+			return
+		}
+		l.movesApplied++ // our move is now on the board; keep movesApplied in sync
+	}
+	if !g.HasMoved {
+		l.greetLocked()
+	}
+	// Ponder while waiting for the opponent's first move.
+	l.startPonder()
+	// otherwise we wait for board updates and react there..
+}
+
+// newLocalGameLocked creates l.Game for l.Player against a human stand-in for
+// the opponent, starting from l.initialFEN. Must be called with the mutex held.
+func (l *Lichess) newLocalGameLocked() error {
+	opponent := player.NewHumanPlayer(l.Player.PlayerColor ^ 1)
+	if l.Player.PlayerColor == color.White {
+		l.Game = game.NewGame(l.Player, opponent)
+	} else {
+		l.Game = game.NewGame(opponent, l.Player)
+	}
+	l.Game.MoveLimit = game_config.Get().MovesToPlay
+	l.Game.TimeLimit = game_config.Get().SecondsToPlay * time.Second
+	if l.initialFEN == "" {
+		return nil
+	}
+	parsed, err := analysis.ParseFEN(l.initialFEN)
+	if err != nil {
+		return err
+	}
+	l.Game.CurrentBoard, l.Game.CurrentTurnColor, l.Game.PreviousMove = parsed.Board, parsed.Active, parsed.Previous
+	l.Game.FullMove = parsed.FullMove
+	// The opening book is indexed from the standard start.
+	l.Player.Opening = ai.OpeningNone
+	return nil
+}
+
+// sideToMove returns whose turn it is after ply moves of the current game.
+func (l *Lichess) sideToMove(ply int) color.Color {
+	return l.initialSide ^ color.Color(ply%2)
+}
+
+const startPlacement = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR"
+
+// isFreshStandardGame reports whether g is at the standard start with White
+// to move, i.e. nobody has moved yet.
+func isFreshStandardGame(g *Game) bool {
+	fields := strings.Fields(g.BoardSerialized)
+	if len(fields) == 0 {
+		return !g.HasMoved
+	}
+	return fields[0] == startPlacement && (len(fields) == 1 || fields[1] == "w")
+}
+
+// resumeOngoingGameLocked starts playing a game already in progress on
+// Lichess, such as one left running by a crashed or restarted process. With
+// several, the most urgent (our turn, least time) comes first; the rest are
+// resumed as each game ends. skipID is a game known to be over. Must be called
+// with the mutex held.
+func (l *Lichess) resumeOngoingGameLocked(skipID string) bool {
+	games, err := l.FetchOngoingGames()
+	if err != nil {
+		log.Errorf("failed to fetch ongoing games: %s", err)
+		return false
+	}
+	var next *Game
+	for i := range games {
+		g := &games[i]
+		if g.GameID == skipID {
+			continue
+		}
+		if next == nil || (g.IsMyTurn && !next.IsMyTurn) ||
+			(g.IsMyTurn == next.IsMyTurn && g.SecondsLeft < next.SecondsLeft) {
+			next = g
+		}
+	}
+	if next == nil {
+		return false
+	}
+	log.Infof("resuming game %s as %s with %.1fs left (%d games in progress)", next.GameID, next.Color, next.SecondsLeft, len(games))
+	l.startGameLocked(next)
+	return true
+}
+
 func (l *Lichess) handleEvent(event *Event) error {
 	l.Mutex.Lock()
 	defer l.Mutex.Unlock()
 	switch event.Type {
 	case EventTypeGameStart:
-		if l.Game != nil {
-			log.Warnf("gameStart received while game %s active — resetting stale game state", l.GameID)
-			l.resetGame()
+		if event.Game == nil {
+			return errors.New("gameStart event missing game data")
 		}
-		l.GameID = event.Game.GameID
-		// human is the other player
-		playerColor := color.White
-		enemyColor := color.Black
-		if event.Game.Color == "black" {
-			playerColor = color.Black
-			enemyColor = color.White
-		}
-		enemyPlayer := player.NewHumanPlayer(enemyColor)
-		// Fresh algorithm + AIPlayer per game: NewAIPlayer allocates a new
-		// transposition table and evaluation cache, and NewAlgorithm gives an
-		// unshared search instance, so no state (caches or search heuristics)
-		// leaks from the previous game.
-		l.Player = ai.NewAIPlayer(playerColor, ai.NewAlgorithm(game_config.Get().Algorithm))
-		l.Player.MaxSearchDepth = game_config.Get().AIMaxSearchDepth
-		l.Player.MaxThinkTime = thinkTimeForClock(time.Duration(event.Game.SecondsLeft*float64(time.Second)), l.clockIncrement, l.Player.TurnCount)
-
-		// Create game and start game loop
-		if playerColor == color.White {
-			l.Game = game.NewGame(l.Player, enemyPlayer)
-		} else {
-			l.Game = game.NewGame(enemyPlayer, l.Player)
+		if l.Game != nil && l.GameID == event.Game.GameID {
+			// The event stream repeats gameStart for ongoing games on every
+			// (re)connect, and resumeOngoingGameLocked may have started this
+			// one already.
+			log.Infof("gameStart for game %s, which is already being played — ignoring", l.GameID)
+			break
 		}
-
-		l.Game.MoveLimit = game_config.Get().MovesToPlay
-		l.Game.TimeLimit = game_config.Get().SecondsToPlay * time.Second
-		l.movesApplied = 0
-
-		ctx, cancel := context.WithCancel(context.Background())
-		l.boardStreamCancel = cancel
-		go func() {
-			err := l.StreamBoardUpdate(ctx, event.Game.GameID, l.GameEvents)
-			if err != nil && ctx.Err() == nil {
-				log.Errorf("failed to stream board update %s", err)
-			}
-		}()
-
-		if l.Game.CurrentTurnColor == playerColor {
-			l.Game.PlayTurn()
-			if err := l.MakeMove(event.Game.GameID, l.Game.PreviousMove); err != nil {
-				log.Errorf("first move rejected by lichess, resetting game state: %s", err)
-				l.resetGame()
-				return nil
-			}
-			l.movesApplied++ // our move is now on the board; keep movesApplied in sync
+		if l.Game != nil && !l.gameInProgress(event.Game.GameID) {
+			// Games that end within moments of starting, as arena pairings
+			// can, leave their gameStart behind in the stream after we have
+			// moved on to the next game.
+			log.Infof("gameStart for game %s, which is already over — ignoring", event.Game.GameID)
+			break
 		}
-		// Ponder while waiting for the opponent's first move.
-		l.startPonder()
-		// otherwise we wait for board updates and react there..
+		l.startGameLocked(event.Game)
 	case EventTypeGameFinish:
 		if l.Game == nil {
 			log.Warnf("gameFinish received but no active game — ignoring")
 			break
 		}
+		if event.Game != nil && event.Game.GameID != "" && event.Game.GameID != l.GameID {
+			log.Infof("gameFinish for game %s while playing %s — ignoring", event.Game.GameID, l.GameID)
+			break
+		}
+		l.recordGameResult(event.Game)
+		l.sayGoodbyeLocked()
+		log.Infof("game %s finished, ponder hits %s", l.GameID, l.ponderStats)
+		finishedID := l.GameID
 		l.resetGame()
-		if l.exitAfterGame != nil {
-			select {
-			case <-l.exitAfterGame:
-			default:
-				close(l.exitAfterGame)
-			}
+		if l.ChallengeOnStart != nil || l.shutdown.started || (l.Tournament != nil && l.tournament.leaving) {
+			l.exitLocked()
+			break
 		}
+		// Another game may have been left running, e.g. from before a restart.
+		if l.resumeOngoingGameLocked(finishedID) {
+			break
+		}
+		l.acceptQueuedChallenge()
 	case EventTypeChallenge:
 		if event.Challenge == nil {
 			return errors.New("challenge event missing challenge data")
 		}
 		// Only accept incoming challenges. The Lichess event stream does NOT include a
 		// "direction" field for challenge events, so we detect outgoing challenges by
-		// checking if the challenger is our own bot account (vadbot).
+		// checking if the challenger is our own bot account (from /api/account).
 		// Fallback: also check the direction fields for future-proofing.
-		ourUsername := "vadbot"
 		challengerID := ""
 		if event.Challenge.Challenger != nil {
 			challengerID = event.Challenge.Challenger.ID
 		}
-		// Outgoing challenge: direction field is absent in event stream, so detect by
-		// checking if the challenger is our own bot account.
 		isOutgoing := event.Challenge.Direction == "out" ||
 			event.ChallengeDirection == "out" ||
-			challengerID == ourUsername
+			(l.botID != "" && strings.EqualFold(challengerID, l.botID))
 		if isOutgoing {
 			log.Debugf("ignoring our own outgoing challenge %s", event.Challenge.ID)
 			break
 		}
-		if l.Game != nil {
-			log.Infof("ignoring challenge %s: game %s already active", event.Challenge.ID, l.GameID)
+		if l.shutdown.started {
+			log.Infof("declining challenge %s from %s: shutting down", event.Challenge.ID, challengerID)
+			if err := l.DeclineChallenge(event.Challenge.ID, DeclineLater); err != nil {
+				log.Errorf("failed to decline challenge %s: %s", event.Challenge.ID, err)
+			}
 			break
 		}
-		if !event.Challenge.Rated {
-			log.Infof("declining casual challenge %s from %s: only rated games accepted", event.Challenge.ID, challengerID)
-			if err := l.DeclineChallenge(event.Challenge.ID, "casual"); err != nil {
-				log.Errorf("failed to decline casual challenge %s: %s", event.Challenge.ID, err)
+		if l.Tournament != nil {
+			// Tournament pairings do not wait for a challenge to finish.
+			log.Infof("declining challenge %s from %s: playing in a tournament", event.Challenge.ID, challengerID)
+			if err := l.DeclineChallenge(event.Challenge.ID, DeclineLater); err != nil {
+				log.Errorf("failed to decline challenge %s: %s", event.Challenge.ID, err)
 			}
 			break
 		}
+		policy := l.challengePolicy()
+		if reason := evaluateChallenge(policy, event.Challenge); reason != "" {
+			log.Infof("declining challenge %s from %s: %s", event.Challenge.ID, challengerID, reason)
+			if err := l.DeclineChallenge(event.Challenge.ID, reason); err != nil {
+				log.Errorf("failed to decline challenge %s: %s", event.Challenge.ID, err)
+			}
+			break
+		}
+		if l.Game != nil {
+			if len(l.challengeQueue) >= policy.MaxQueue {
+				log.Infof("declining challenge %s: game %s active and queue full (%d)", event.Challenge.ID, l.GameID, len(l.challengeQueue))
+				if err := l.DeclineChallenge(event.Challenge.ID, DeclineLater); err != nil {
+					log.Errorf("failed to decline challenge %s: %s", event.Challenge.ID, err)
+				}
+				break
+			}
+			l.challengeQueue = append(l.challengeQueue, event.Challenge.ID)
+			log.Infof("queued challenge %s from %s behind game %s (%d queued)", event.Challenge.ID, challengerID, l.GameID, len(l.challengeQueue))
+			break
+		}
 		if err := l.AcceptChallenge(event.Challenge.ID); err != nil {
 			log.Errorf("failed to accept challenge %s: %s", event.Challenge.ID, err)
 		}
+	case EventTypeChallengeCanceled:
+		if event.Challenge != nil {
+			l.dequeueChallenge(event.Challenge.ID)
+		}
+	case EventTypeChallengeDeclined:
+		if event.Challenge != nil {
+			l.outgoingChallengeDeclined(event.Challenge)
+		}
 	case EventTypePing:
 		log.Debugf("ping...")
 	default:
@@ -556,6 +738,31 @@ func (l *Lichess) handleEvent(event *Event) error {
 	return nil
 }
 
+// acceptQueuedChallenge accepts the oldest queued challenge. Challenges that
+// Lichess no longer offers (expired or withdrawn without an event) fail to
+// accept and are skipped. Must be called with the mutex held.
+func (l *Lichess) acceptQueuedChallenge() {
+	for len(l.challengeQueue) > 0 {
+		id := l.challengeQueue[0]
+		l.challengeQueue = l.challengeQueue[1:]
+		if err := l.AcceptChallenge(id); err != nil {
+			log.Errorf("failed to accept queued challenge %s: %s", id, err)
+			continue
+		}
+		return
+	}
+}
+
+func (l *Lichess) dequeueChallenge(id string) {
+	for i, queued := range l.challengeQueue {
+		if queued == id {
+			l.challengeQueue = append(l.challengeQueue[:i], l.challengeQueue[i+1:]...)
+			log.Infof("challenge %s withdrawn from queue", id)
+			return
+		}
+	}
+}
+
 // parseUCIMove converts a UCI move string (e.g. "e2e4", "a7a8q") to a Move.
 func parseUCIMove(uci string) *location.Move {
 	sCol := 7 - (uci[0] - 'a')
@@ -602,9 +809,12 @@ func (l *Lichess) handleBoardUpdate(event *GameEvent) error {
 		// Reconnect path: replay the full move history to sync the board, then
 		// act if it's our turn. handleBoardUpdateLocked is not used here because
 		// its "not our turn" early-return would skip the board sync entirely.
-		return l.handleGameFullLocked(event.State)
+		event.State.ReceivedAt = event.ReceivedAt
+		return l.handleGameFullLocked(event.State, event.InitialFen)
 	case StateTypeGame:
 		return l.handleBoardUpdateLocked(event)
+	case StateTypeChatLine:
+		l.handleChatLineLocked(event)
 	default:
 		log.Warnf("unhandled game event %+v", *event)
 	}
@@ -614,42 +824,61 @@ func (l *Lichess) handleBoardUpdate(event *GameEvent) error {
 // handleGameFullLocked syncs the board from a gameFull event by replaying all
 // historical moves, then responds if it is our turn. Must be called with the
 // mutex held.
-func (l *Lichess) handleGameFullLocked(state *GameEvent) error {
+func (l *Lichess) handleGameFullLocked(state *GameEvent, initialFen string) error {
 	if l.Player == nil || l.Game == nil {
 		log.Errorf("gameFull received but no active game")
 		return nil
 	}
-	if state.Moves == "" {
-		// No moves yet: game just started. If we're White, gameStart already
-		// handled our first move; if Black, just wait for the opponent.
+	if initialFen != "" && initialFen != "startpos" && initialFen != l.initialFEN && l.movesApplied == 0 {
+		// A game from position: restart the local game from it.
+		parsed, err := analysis.ParseFEN(initialFen)
+		if err != nil {
+			log.Errorf("gameFull: bad initial FEN %q, abandoning game: %s", initialFen, err)
+			l.resetGame()
+			return nil
+		}
+		log.Infof("gameFull: game starts from %s", initialFen)
+		l.stopPonder()
+		l.Game.Stop()
+		l.initialFEN, l.initialSide = initialFen, parsed.Active
+		if err := l.newLocalGameLocked(); err != nil {
+			log.Errorf("gameFull: failed to set up position, abandoning game: %s", err)
+			l.resetGame()
+			return nil
+		}
+	}
+	moves := strings.Fields(state.Moves)
+	if len(moves) < l.movesApplied {
+		// Sent before the move we made from gameStart: nothing to do.
 		l.startPonder()
 		return nil
 	}
-	moves := strings.Split(state.Moves, " ")
 	// Replay any moves we haven't applied yet (all of them on a fresh reconnect).
 	log.Infof("gameFull: replaying moves %d..%d to sync board", l.movesApplied, len(moves)-1)
 	l.stopPonder()
 	l.Player.IncrementTTGeneration()
+	if l.movesApplied == 0 && len(moves) > int(l.Player.PlayerColor^l.initialSide) {
+		// Resuming a game whose earlier moves of ours were made by another
+		// process. The book is indexed by turn, not by position, so it would
+		// carry on a line this game never followed.
+		l.Player.Opening = ai.OpeningNone
+	}
 	for l.movesApplied < len(moves) {
 		m := parseUCIMove(moves[l.movesApplied])
 		l.applyOpponentMove(m)
 		l.movesApplied++
 	}
 	// After replay, check whose turn it is.
-	playerTimeMS := state.WhiteTimeMS
-	playerIncMS := state.WhiteIncMS
-	if l.Player.PlayerColor == color.Black {
-		playerTimeMS = state.BlackTimeMS
-		playerIncMS = state.BlackIncMS
-	}
-	l.clockIncrement = time.Duration(playerIncMS) * time.Millisecond
-	playerTimeLeft := time.Duration(playerTimeMS) * time.Millisecond
-	l.Player.MaxThinkTime = thinkTimeForPosition(playerTimeLeft, l.clockIncrement, l.Player.TurnCount, l.Game.CurrentBoard, l.Player.PlayerColor)
-	if len(moves)%2 == int(l.Player.PlayerColor) {
+	playerTimeLeft, increment := l.ourClock(state)
+	l.clockIncrement = increment
+	overhead := l.moveOverhead()
+	l.Player.MaxThinkTime = withMoveOverhead(thinkTimeForPosition(playerTimeLeft, l.clockIncrement, l.Player.TurnCount, l.Game.CurrentBoard, l.Player.PlayerColor), overhead)
+	if l.sideToMove(len(moves)) == l.Player.PlayerColor {
 		// It's our turn.
-		log.Infof("gameFull: our turn after replay, thinking... have time %s, inc %s, set max to %s", playerTimeLeft, l.clockIncrement, l.Player.MaxThinkTime)
-		if l.Game.GameStatus != game.Active {
-			if !game.IsClaimableDraw(l.Game.GameStatus) {
+		l.lag.turnStarted(len(moves), state.ReceivedAt, playerTimeLeft, increment)
+		log.Infof("gameFull: our turn after replay, thinking... have time %s, inc %s, move overhead %s, set max to %s", playerTimeLeft, l.clockIncrement, overhead, l.Player.MaxThinkTime)
+		if l.Game.GameStatus != game.Active || l.drawClaimable() {
+			if !l.drawClaimable() {
 				log.Infof("gameFull: local game already ended (status %d) — not making a move", l.Game.GameStatus)
 				return nil
 			}
@@ -663,6 +892,9 @@ func (l *Lichess) handleGameFullLocked(state *GameEvent) error {
 			return nil
 		}
 		l.Game.PlayTurn()
+		if l.decideAfterSearch() {
+			return nil
+		}
 		if err := l.MakeMove(l.GameID, l.Game.PreviousMove); err != nil {
 			l.handleRejectedMove(err)
 			return nil
@@ -683,12 +915,35 @@ func (l *Lichess) handleGameFullLocked(state *GameEvent) error {
 // next PlayTurn blocked forever in WaitForMove while our clock runs out. This is the
 // idle-on-the-clock loss seen in game DpqEDBdP.
 func (l *Lichess) applyOpponentMove(m *location.Move) {
-	if game.IsClaimableDraw(l.Game.GameStatus) {
+	if l.Game.GameStatus != game.Active && l.drawClaimable() {
 		l.Game.GameStatus = game.Active
 	}
 	l.Game.PlayTurnMove(m)
 }
 
+// drawClaimable reports whether the local board holds a draw that Lichess
+// leaves to the players: a threefold repetition or fifty-move draw the game
+// keeps going through (Game.ClaimableDraw), or a dead position such as a
+// pawn fortress, which Lichess only ends on insufficient material.
+func (l *Lichess) drawClaimable() bool {
+	switch status := l.Game.GameStatus; {
+	case status == game.Active:
+		return l.Game.ClaimableDraw() != game.Active
+	case game.IsClaimableDraw(status), status == game.DeadPositionDraw:
+		return true
+	}
+	return false
+}
+
+// ourClock is our remaining time and increment in a gameState.
+func (l *Lichess) ourClock(state *GameEvent) (timeLeft, increment time.Duration) {
+	timeMS, incMS := state.WhiteTimeMS, state.WhiteIncMS
+	if l.Player.PlayerColor == color.Black {
+		timeMS, incMS = state.BlackTimeMS, state.BlackIncMS
+	}
+	return time.Duration(timeMS) * time.Millisecond, time.Duration(incMS) * time.Millisecond
+}
+
 // ourTurnLocally reports whether the local board agrees it is our turn before we
 // call PlayTurn. If it does not, the board has desynced from Lichess: PlayTurn would
 // block forever in the opponent HumanPlayer's WaitForMove and flag us on the clock,
@@ -717,12 +972,47 @@ func (l *Lichess) handleBoardUpdateLocked(event *GameEvent) error {
 			log.Infof("game status %q — not making a move", event.Status)
 			return nil
 		}
-		if event.Moves == "" {
-			// No moves yet — game just started and it is white's turn.
+		if l.respondToOffers(event) {
+			// Draw accepted: the game is over, gameFinish follows.
+			return nil
+		}
+		moves := strings.Fields(event.Moves)
+		if timeLeft, _ := l.ourClock(event); l.lag.observe(len(moves), timeLeft) {
+			log.Infof("lag in game %s: last %s, average %s ± %s over %d moves, move overhead %s",
+				l.GameID, l.lag.last.Round(time.Millisecond), l.lag.average.Round(time.Millisecond),
+				l.lag.deviation.Round(time.Millisecond), l.lag.samples, l.moveOverhead().Round(time.Millisecond))
+		}
+		if l.decisions.takebackAccepted && len(moves) < l.movesApplied {
+			// The takeback we granted went through: rewind the local game.
+			log.Infof("takeback: rewinding from %d to %d moves", l.movesApplied, len(moves))
+			if l.sideToMove(len(moves)) != l.Player.PlayerColor {
+				l.rebuildGameLocked(moves)
+				l.startPonder()
+				return nil
+			}
+			if len(moves) == 0 {
+				// Back at the start with us to move: just play the first move.
+				l.rebuildGameLocked(moves)
+				if !l.ourTurnLocally() {
+					return nil
+				}
+				l.Game.PlayTurn()
+				if err := l.MakeMove(l.GameID, l.Game.PreviousMove); err != nil {
+					l.handleRejectedMove(err)
+					return nil
+				}
+				l.movesApplied++
+				l.startPonder()
+				return nil
+			}
+			// Our turn: leave the opponent's last move to the normal flow below.
+			l.rebuildGameLocked(moves[:len(moves)-1])
+		}
+		if len(moves) == 0 {
+			// No moves yet — gameStart or gameFull makes the first move.
 			return nil
 		}
-		moves := strings.Split(event.Moves, " ")
-		if len(moves)%2 != int(l.Player.PlayerColor) {
+		if l.sideToMove(len(moves)) != l.Player.PlayerColor {
 			return nil
 		}
 		// Skip events we've already applied — lichess can resend after a stream reconnect.
@@ -737,25 +1027,30 @@ func (l *Lichess) handleBoardUpdateLocked(event *GameEvent) error {
 		}
 		m := parseUCIMove(moves[len(moves)-1])
 		log.Infof("saw opponent move %s (%s)", m.String(), m.UCIString())
-		// Stop any in-progress ponder before touching the board or the player.
-		l.stopPonder()
-		// Invalidate ponder TT entries: opponent deviated from our predicted move,
-		// so entries written during the ponder are from the wrong subtree.
-		l.Player.IncrementTTGeneration()
+		hit := l.ponderHitLocked(len(moves), m)
+		if hit == nil {
+			// Stop any in-progress ponder before touching the board or the player.
+			l.stopPonder()
+			// Invalidate ponder TT entries: opponent deviated from our predicted move,
+			// so entries written during the ponder are from the wrong subtree.
+			l.Player.IncrementTTGeneration()
+		}
 		l.applyOpponentMove(m)
 		l.movesApplied = len(moves)
 		// If the local board is already over after the opponent's move, decide
 		// whether we still owe a move.
-		if l.Game.GameStatus != game.Active {
-			if !game.IsClaimableDraw(l.Game.GameStatus) {
+		if l.Game.GameStatus != game.Active || l.drawClaimable() {
+			if !l.drawClaimable() {
 				// Checkmate / stalemate / insufficient material: no move to make.
 				// PreviousMove still holds the opponent's last move, and posting it
 				// would send their move as ours — so stay idle.
 				log.Infof("local game ended (status %d) after opponent's move — not making a move", l.Game.GameStatus)
+				l.stopPonder()
 				return nil
 			}
-			// Threefold repetition / fifty-move: Lichess does NOT end the game
-			// automatically, so going idle here flags us (this lost a drawn-and-then-
+			// Threefold repetition / fifty-move / a dead position Lichess does not
+			// detect: Lichess does NOT end the game automatically, so going idle
+			// here flags us (this lost a drawn-and-then-
 			// winning game). Re-activate and play on: PlayTurn re-detects the draw and
 			// MakeMove attaches offeringDraw=true to claim it. If the opponent declines
 			// and we keep repeating, Lichess auto-draws at fivefold — either way we
@@ -764,20 +1059,23 @@ func (l *Lichess) handleBoardUpdateLocked(event *GameEvent) error {
 			l.offerDrawNextMove = true
 			l.Game.GameStatus = game.Active
 		}
-		playerTimeMS := event.WhiteTimeMS
-		playerIncMS := event.WhiteIncMS
-		if l.Player.PlayerColor == color.Black {
-			playerTimeMS = event.BlackTimeMS
-			playerIncMS = event.BlackIncMS
-		}
-		l.clockIncrement = time.Duration(playerIncMS) * time.Millisecond
-		playerTimeLeft := time.Duration(playerTimeMS) * time.Millisecond
-		l.Player.MaxThinkTime = thinkTimeForPosition(playerTimeLeft, l.clockIncrement, l.Player.TurnCount, l.Game.CurrentBoard, l.Player.PlayerColor)
-		log.Infof("player thinking... have time %s, inc %s, set max to %s", playerTimeLeft, l.clockIncrement, l.Player.MaxThinkTime)
+		playerTimeLeft, increment := l.ourClock(event)
+		l.clockIncrement = increment
+		l.lag.turnStarted(len(moves), event.ReceivedAt, playerTimeLeft, increment)
+		overhead := l.moveOverhead()
+		l.Player.MaxThinkTime = withMoveOverhead(thinkTimeForPosition(playerTimeLeft, l.clockIncrement, l.Player.TurnCount, l.Game.CurrentBoard, l.Player.PlayerColor), overhead)
+		log.Infof("player thinking... have time %s, inc %s, move overhead %s, set max to %s", playerTimeLeft, l.clockIncrement, overhead, l.Player.MaxThinkTime)
 		if !l.ourTurnLocally() {
 			return nil
 		}
-		l.Game.PlayTurn()
+		if hit != nil {
+			l.playPonderHitLocked(hit)
+		} else {
+			l.Game.PlayTurn()
+		}
+		if l.decideAfterSearch() {
+			return nil
+		}
 		if err := l.MakeMove(l.GameID, l.Game.PreviousMove); err != nil {
 			l.handleRejectedMove(err)
 			return nil
@@ -811,7 +1109,8 @@ func (l *Lichess) handleRejectedMove(originalErr error) {
 	l.resetGame()
 }
 
-func (l *Lichess) ChallengeUser(cfg *ChallengeConfig) error {
+// ChallengeUser challenges cfg.Username and returns the challenge ID.
+func (l *Lichess) ChallengeUser(cfg *ChallengeConfig) (string, error) {
 	u := fmt.Sprintf("/api/challenge/%s", cfg.Username)
 	params := url.Values{}
 	params.Set("rated", fmt.Sprintf("%t", cfg.Rated))
@@ -824,7 +1123,7 @@ func (l *Lichess) ChallengeUser(cfg *ChallengeConfig) error {
 	fullURL := l.Client.BaseURL.ResolveReference(rel)
 	req, err := http.NewRequest("POST", fullURL.String(), strings.NewReader(params.Encode()))
 	if err != nil {
-		return err
+		return "", err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", l.Client.APIKey))
@@ -832,21 +1131,56 @@ func (l *Lichess) ChallengeUser(cfg *ChallengeConfig) error {
 
 	resp, err := l.Client.HttpClient.Do(req)
 	if err != nil {
-		return err
+		return "", err
 	}
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return "", err
 	}
 	log.Infof("challenge %s status %s %s", cfg.Username, resp.Status, string(bodyBytes))
-	return nil
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "", errRateLimited
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("challenge %s rejected: %s", cfg.Username, string(bodyBytes))
+	}
+	// Lichess has answered both with the bare challenge and wrapped in
+	// {"challenge": ...}.
+	var created struct {
+		ID        string     `json:"id"`
+		Challenge *Challenge `json:"challenge"`
+	}
+	if err := json.Unmarshal(bodyBytes, &created); err != nil {
+		return "", err
+	}
+	if created.Challenge != nil {
+		return created.Challenge.ID, nil
+	}
+	return created.ID, nil
 }
 
 func (l *Lichess) Run() {
+	if account, err := l.FetchAccount(); err != nil {
+		log.Errorf("failed to fetch bot account, outgoing challenges are detected by direction only: %s", err)
+	} else {
+		l.botID = account.ID
+		log.Infof("playing as %s", account.Username)
+	}
 	if l.ChallengeOnStart != nil {
-		if err := l.ChallengeUser(l.ChallengeOnStart); err != nil {
+		if _, err := l.ChallengeUser(l.ChallengeOnStart); err != nil {
 			log.Errorf("failed to send challenge: %s", err)
 		}
+	} else {
+		// Pick up a game left running by a previous process before anything
+		// else: its clock has been running since the crash.
+		l.Mutex.Lock()
+		l.resumeOngoingGameLocked("")
+		l.Mutex.Unlock()
+	}
+	if l.Tournament != nil {
+		go l.runTournament()
+	} else if l.ChallengeOnStart == nil && l.matchmakingConfig().Enabled {
+		go l.runMatchmaking()
 	}
 
 	var g errgroup.Group
@@ -862,9 +1196,18 @@ func (l *Lichess) Run() {
 		backoff := 3 * time.Second
 		for {
 			err := l.Stream(l.Events)
+			select {
+			case <-l.exit:
+				return nil
+			default:
+			}
 			if err != nil {
 				log.Errorf("failed to stream event %s — reconnecting in %s", err, backoff)
-				time.Sleep(backoff)
+				select {
+				case <-time.After(backoff):
+				case <-l.exit:
+					return nil
+				}
 				// Exponential backoff capped at 30s for EOF/context errors
 				// to avoid hammering Lichess when rate-limited.
 				if backoff < 30*time.Second {
@@ -878,29 +1221,32 @@ func (l *Lichess) Run() {
 	})
 
 	// Event handler: processes game events from the event stream. Never exits on errors
-	// since a bad event should not kill the whole bot.
+	// since a bad event should not kill the whole bot, only when Run stops.
 	g.Go(func() error {
-		// exitAfterGame is nil when not in challenge mode; a nil channel in
-		// select blocks forever, so this case only fires in challenge mode.
-		var exitCh <-chan struct{} = l.exitAfterGame
 		for {
+			var e Event
 			select {
-			case e := <-l.Events:
-				if err := l.handleEvent(&e); err != nil {
-					log.Errorf("failed to handle event %s — continuing", err)
-					// Don't return: a bad event (nil challenge, etc.) should not kill the bot.
-				}
-			case <-exitCh:
-				log.Infof("game finished, exiting")
+			case e = <-l.Events:
+			case <-l.exit:
 				return nil
 			}
+			if err := l.handleEvent(&e); err != nil {
+				log.Errorf("failed to handle event %s — continuing", err)
+				// Don't return: a bad event (nil challenge, etc.) should not kill the bot.
+			}
 		}
 	})
 
-	// Board update handler: processes per-game move events. Never exits on errors.
+	// Board update handler: processes per-game move events. Never exits on errors,
+	// only when Run stops.
 	g.Go(func() error {
 		for {
-			ge := <-l.GameEvents
+			var ge GameEvent
+			select {
+			case ge = <-l.GameEvents:
+			case <-l.exit:
+				return nil
+			}
 			// Contain panics per-event: a desynced board (e.g. an illegal move that
 			// Lichess rejected, leaving local state out of sync) must not crash the
 			// whole bot and forfeit every game on time. Recover, drop the corrupted
@@ -922,10 +1268,75 @@ func (l *Lichess) Run() {
 		}
 	})
 
-	err := g.Wait()
+	// The handlers only return once exit is closed, so Run waits for exit
+	// rather than for them.
+	waited := make(chan error, 1)
+	go func() { waited <- g.Wait() }()
+	select {
+	case err := <-waited:
+		if err != nil {
+			log.Fatal(err)
+		}
+	case <-l.exit:
+	}
+	// Stop pondering and close the board stream of a game we are leaving,
+	// e.g. one resigned at the end of a shutdown's grace period.
+	l.Mutex.Lock()
+	l.resetGame()
+	l.Mutex.Unlock()
+	log.Infof("done playing, exiting")
+}
+
+// FetchOngoingGames returns the games the account is playing now.
+func (l *Lichess) FetchOngoingGames() ([]Game, error) {
+	r, err := l.Client.newRequest("GET", "/api/account/playing?nb=50", nil)
+	if err != nil {
+		return nil, err
+	}
+	var playing struct {
+		NowPlaying []Game `json:"nowPlaying"`
+	}
+	resp, err := l.Client.do(r, &playing)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ongoing games request failed: %s", resp.Status)
 	}
+	return playing.NowPlaying, nil
+}
+
+// gameInProgress reports whether Lichess lists game id among our games in
+// progress. It assumes so when the list cannot be fetched.
+func (l *Lichess) gameInProgress(id string) bool {
+	games, err := l.FetchOngoingGames()
+	if err != nil {
+		log.Errorf("failed to fetch ongoing games: %s", err)
+		return true
+	}
+	for _, g := range games {
+		if g.GameID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchAccount returns the account that owns the API token.
+func (l *Lichess) FetchAccount() (*Account, error) {
+	r, err := l.Client.newRequest("GET", "/api/account", nil)
+	if err != nil {
+		return nil, err
+	}
+	account := &Account{}
+	resp, err := l.Client.do(r, account)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("account request failed: %s", resp.Status)
+	}
+	return account, nil
 }
 
 func (l *Lichess) DeclineChallenge(challengeID, reason string) error {
@@ -970,6 +1381,9 @@ func (l *Lichess) AcceptChallenge(challengeID string) error {
 		return err
 	}
 	log.Infof("accept challenge %s status %s %s", challengeID, resp.Status, string(bodyBytes))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("accept challenge rejected: %s", string(bodyBytes))
+	}
 	return nil
 }
 
@@ -979,7 +1393,7 @@ func (l *Lichess) MakeMove(gameID string, move *board.LastMove) error {
 		moveStr += strings.ToLower(string((*move.PromotionPiece).GetChar()))
 	}
 	oferringDraw := "false"
-	if l.offerDrawNextMove || game.IsClaimableDraw(l.Game.GameStatus) {
+	if l.offerDrawNextMove || l.drawClaimable() {
 		oferringDraw = "true"
 	}
 	l.offerDrawNextMove = false
@@ -988,6 +1402,7 @@ func (l *Lichess) MakeMove(gameID string, move *board.LastMove) error {
 	if err != nil {
 		return err
 	}
+	sentAt := time.Now()
 	resp, err := l.Client.HttpClient.Do(r)
 	if err != nil {
 		return err
@@ -1000,6 +1415,7 @@ func (l *Lichess) MakeMove(gameID string, move *board.LastMove) error {
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("make move rejected: %s", string(bodyBytes))
 	}
+	l.lag.moveSent(l.movesApplied, sentAt)
 	return nil
 }
 
@@ -1010,6 +1426,14 @@ func (l *Lichess) Stream(s chan<- Event) error {
 	// The watchdog is reset on every successful line read so normal streams stay open.
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	// Run stopping (see exit) ends the stream too.
+	go func() {
+		select {
+		case <-l.exit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
 
 	const inactivityTimeout = 2 * time.Minute
 	watchdog := time.AfterFunc(inactivityTimeout, cancel)
@@ -1029,7 +1453,10 @@ func (l *Lichess) Stream(s chan<- Event) error {
 		// Rate-limited: back off for 5 minutes before the caller retries.
 		_ = response.Body.Close()
 		log.Warnf("event stream rate limited (HTTP 429) — backing off 5 minutes")
-		time.Sleep(5 * time.Minute)
+		select {
+		case <-time.After(5 * time.Minute):
+		case <-l.exit:
+		}
 		return fmt.Errorf("rate limited (429)")
 	}
 
@@ -1041,21 +1468,32 @@ func (l *Lichess) Stream(s chan<- Event) error {
 		}
 		watchdog.Reset(inactivityTimeout) // data received — reset the inactivity timer
 		if len(line) < 3 {
-			s <- Event{Type: EventTypePing}
+			select {
+			case s <- Event{Type: EventTypePing}:
+			case <-l.exit:
+				return nil
+			}
 			continue
 		}
 		// Detect rate-limit responses delivered as body text (CDN redirect pattern).
 		if bytes.Contains(line, []byte("Too many requests")) || bytes.Contains(line, []byte("/429")) {
 			_ = response.Body.Close()
 			log.Warnf("rate limited in event stream body — backing off 5 minutes")
-			time.Sleep(5 * time.Minute)
+			select {
+			case <-time.After(5 * time.Minute):
+			case <-l.exit:
+			}
 			return fmt.Errorf("rate limited (body 429)")
 		}
 		var event Event
 		if err := json.Unmarshal(line, &event); err != nil {
 			log.Errorf("failed to unmarshal line to event %s", line)
 		}
-		s <- event
+		select {
+		case s <- event:
+		case <-l.exit:
+			return nil
+		}
 	}
 }
 
@@ -1091,7 +1529,12 @@ func (l *Lichess) StreamBoardUpdate(ctx context.Context, gameID string, s chan<-
 			log.Errorf("failed to unmarshal line to game event %s", line)
 		}
 		event.GameID = gameID
-		s <- event
+		event.ReceivedAt = time.Now()
+		select {
+		case s <- event:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 }
 
@@ -1115,7 +1558,12 @@ func (c *Client) newRequest(method, path string, body interface{}) (*http.Reques
 		return nil, errors.New("APIKey is undefined")
 	}
 
-	rel := &url.URL{Path: path}
+	// Parse rather than set Path so a query string (e.g. offeringDraw on
+	// moves) stays a query instead of being escaped into the path.
+	rel, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
 	u := c.BaseURL.ResolveReference(rel)
 
 	var buf io.ReadWriter
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/server/lichesstest"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPlaysFullGamesAgainstFakeLichess runs the whole bot — event stream,
+// challenge handling, board stream and move posting — against the in-process
+// fake, once with each color. The scripted opponent resigns when its script
+// runs out, so each game ends after a few real bot moves.
+func TestPlaysFullGamesAgainstFakeLichess(t *testing.T) {
+	fake := lichesstest.NewServer("TestBot")
+	fake.Token = "test-token"
+	fake.Opponent = lichesstest.ScriptedOpponent("e7e5", "g8f6")
+	defer fake.Close()
+	t.Setenv("LICHESS_TOKEN", fake.Token)
+
+	bot := ConnectLichess(fake.URL).(*Lichess)
+	done := runUntilDone(bot)
+	defer stopBot(t, bot, done)
+
+	casual := fake.Challenge(lichesstest.ChallengeOptions{Challenger: "Casual"})
+	for _, botColor := range []color.Color{color.White, color.Black} {
+		if botColor == color.Black {
+			fake.Opponent = lichesstest.ScriptedOpponent("e2e4", "g1f3", "f1c4")
+		}
+		id := fake.Challenge(lichesstest.ChallengeOptions{
+			Rated:     true,
+			Initial:   time.Minute,
+			Increment: time.Second,
+			BotColor:  botColor,
+		})
+		result, err := fake.WaitGame(id, 30*time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, lichesstest.StatusResign, result.Status, "moves %v", result.Moves)
+		assert.Equal(t, color.Names[botColor] == "White", result.Winner == "white")
+		assert.Zero(t, result.RejectedMoves, "the bot posted an illegal or out-of-turn move")
+		assert.True(t, result.Clock[botColor] > 0 && result.Clock[botColor] < time.Minute+4*time.Second,
+			"bot clock should have run: %s", result.Clock[botColor])
+	}
+
+	reason, declined := fake.DeclineReason(casual)
+	assert.True(t, declined, "the casual challenge should have been declined")
+	assert.Equal(t, DeclineRated, reason)
+	assert.Equal(t, "testbot", bot.botID, "the bot should learn its identity from /api/account")
+}
+
+// TestResumesGameAfterRestart plays the bot's first moves by hand, as a
+// previous process would have, up to a mate in one, then starts the bot: it
+// must pick the game up from /api/account/playing, replay the moves and mate.
+func TestResumesGameAfterRestart(t *testing.T) {
+	fake := lichesstest.NewServer("TestBot")
+	fake.Token = "test-token"
+	fake.Opponent = lichesstest.ScriptedOpponent("e7e5", "b8c6", "g8f6")
+	defer fake.Close()
+	t.Setenv("LICHESS_TOKEN", fake.Token)
+
+	id := fake.Challenge(lichesstest.ChallengeOptions{Rated: true, Initial: time.Minute})
+	bot := ConnectLichess(fake.URL).(*Lichess)
+	assert.NoError(t, bot.AcceptChallenge(id))
+	for i, move := range []string{"e2e4", "d1h5", "f1c4"} {
+		assert.NoError(t, bot.post("/api/bot/game/"+id+"/move/"+move))
+		for result, _ := fake.Game(id); len(result.Moves) < 2*(i+1); result, _ = fake.Game(id) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	done := runUntilDone(bot)
+	defer stopBot(t, bot, done)
+	result, err := fake.WaitGame(id, 30*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, lichesstest.StatusMate, result.Status, "moves %v", result.Moves)
+	assert.Equal(t, "white", result.Winner)
+	assert.Equal(t, "h5f7", result.Moves[len(result.Moves)-1])
+	assert.Zero(t, result.RejectedMoves, "the resumed bot must not replay an opening move")
+}
+
+// TestPlaysGameFromPosition plays a game that starts from a FEN with Black,
+// the bot, to move: the local board comes from gameFull's initialFen.
+func TestPlaysGameFromPosition(t *testing.T) {
+	fake := lichesstest.NewServer("TestBot")
+	fake.Token = "test-token"
+	fake.Opponent = lichesstest.ScriptedOpponent()
+	defer fake.Close()
+	t.Setenv("LICHESS_TOKEN", fake.Token)
+
+	bot := ConnectLichess(fake.URL).(*Lichess)
+	policy := game_config.DefaultChallengePolicy()
+	policy.Variants = []string{"standard", "fromPosition"}
+	bot.Policy = policy
+	done := runUntilDone(bot)
+	defer stopBot(t, bot, done)
+
+	id := fake.Challenge(lichesstest.ChallengeOptions{
+		Rated:      true,
+		Variant:    "fromPosition",
+		Initial:    time.Minute,
+		BotColor:   color.Black,
+		InitialFEN: "4k3/8/8/8/8/8/3QP3/4K3 b - - 0 40",
+	})
+	result, err := fake.WaitGame(id, 30*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, lichesstest.StatusResign, result.Status, "moves %v", result.Moves)
+	assert.Equal(t, "black", result.Winner)
+	assert.Len(t, result.Moves, 1)
+	assert.Zero(t, result.RejectedMoves)
+}
+
+// stopBot shuts down bot, started with runUntilDone, at the end of a test,
+// resigning any game left after a short grace period, and waits for Run to
+// return so that nothing of the bot outlives the test.
+func stopBot(t *testing.T, bot *Lichess, done <-chan struct{}) {
+	t.Helper()
+	bot.Mutex.Lock()
+	bot.shutdown.grace = 100 * time.Millisecond
+	bot.Mutex.Unlock()
+	bot.Shutdown()
+	waitExit(t, done, 5*time.Second)
+}
@@ -0,0 +1,1048 @@
+// Package lichesstest provides an in-process fake of the subset of the Lichess
+// bot API that server.Lichess uses, so full games can be played against it in
+// go test.
+//
+// The fake streams NDJSON events (challenge, gameStart, gameFinish) and
+// per-game board updates (gameFull, then gameState after every move), accepts
+// and declines challenges, validates every posted move against the engine's
+// own move generator, and runs real clocks: a side whose clock runs out loses
+// on time, exactly as on lichess.org. The bot's opponent is either scripted
+// (Server.Opponent) or driven by the test (Server.PlayOpponentMove), and can
+// offer draws and propose takebacks for the bot to answer. Online bots listed
+// in Server.OnlineBots accept the bot's challenges unless DeclinesFrom says
+// otherwise. Arenas and Swiss tournaments added with AddTournament pair the
+// bot against the opponent while they run.
+package lichesstest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/analysis"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+)
+
+// Game statuses, as reported by Lichess.
+const (
+	StatusStarted   = "started"
+	StatusMate      = "mate"
+	StatusResign    = "resign"
+	StatusStalemate = "stalemate"
+	StatusOutOfTime = "outoftime"
+	StatusDraw      = "draw"
+	StatusAborted   = "aborted"
+)
+
+const (
+	defaultOpponentName = "opponent"
+	defaultInitial      = 3 * time.Minute
+	defaultKeepAlive    = time.Second
+	defaultRating       = 1500
+	sourceFriend        = "friend"
+	// streamBuffer is how many undelivered lines a stream may fall behind by
+	// before further lines are dropped.
+	streamBuffer = 256
+)
+
+var (
+	errGameOver    = errors.New("Not your turn, or game already over")
+	errIllegalMove = errors.New("illegal move")
+)
+
+// Opponent picks the opponent's next move in b, in UCI notation. moves is the
+// game so far. An empty result resigns.
+type Opponent func(b *board.Board, side color.Color, last *board.LastMove, moves []string) string
+
+// ScriptedOpponent plays moves in order, one per turn, and resigns once the
+// script runs out or its next move is illegal in the actual position.
+func ScriptedOpponent(moves ...string) Opponent {
+	return func(b *board.Board, side color.Color, last *board.LastMove, played []string) string {
+		turn := len(played) / 2
+		if turn >= len(moves) {
+			return ""
+		}
+		if _, err := analysis.MatchUCIMove(b, side, last, moves[turn]); err != nil {
+			return ""
+		}
+		return moves[turn]
+	}
+}
+
+// ChallengeOptions describes an incoming challenge to the bot. Zero values
+// select a casual standard 3+0 game with the bot playing white. InitialFEN
+// starts the game from a position (Variant should then be "fromPosition").
+type ChallengeOptions struct {
+	Challenger string
+	Title      string
+	Rating     int
+	Rated      bool
+	Variant    string
+	Initial    time.Duration
+	Increment  time.Duration
+	BotColor   color.Color
+	InitialFEN string
+}
+
+// GameResult is a snapshot of a game on the fake server.
+type GameResult struct {
+	Moves  []string
+	Status string
+	// Winner is "white", "black", or "" for a draw or unfinished game.
+	Winner string
+	Clock  [color.NumColors]time.Duration
+	// DrawOffers counts bot moves posted with offeringDraw=true.
+	DrawOffers int
+	// RejectedMoves counts bot moves refused as illegal or out of turn.
+	RejectedMoves int
+	// DrawAnswers and TakebackAnswers record the bot's "yes"/"no" answers to
+	// the opponent's offers, in order.
+	DrawAnswers     []string
+	TakebackAnswers []string
+	// Chat holds the lines the bot posted, in order.
+	Chat []ChatLine
+}
+
+// ChatLine is a line in a game's "player" or "spectator" chat room.
+type ChatLine struct {
+	Room string
+	Text string
+}
+
+type Server struct {
+	*httptest.Server
+	// BotID is the bot's account ID, returned from /api/account.
+	BotID string
+	// Token, when set, must be sent as the bearer token on every request.
+	Token string
+	// Opponent plays the other side; nil leaves it to PlayOpponentMove.
+	Opponent Opponent
+	// OpponentDelay is how long the opponent thinks before each move.
+	OpponentDelay time.Duration
+	// OpponentAcceptsDraws ends the game drawn whenever the bot offers a draw.
+	OpponentAcceptsDraws bool
+	// Lag is charged to the bot's clock on every move on top of its thinking
+	// time, as if each move spent that long on the network.
+	Lag time.Duration
+	// KeepAlive is the interval of the empty keepalive lines on streams.
+	KeepAlive time.Duration
+	// BotRating is the bot's rating in every speed category.
+	BotRating int
+	// OnlineBots are listed by /api/bot/online.
+	OnlineBots []OnlineBot
+	// DeclinesFrom maps a user to the reason it declines the bot's challenges
+	// with; everyone else accepts at once.
+	DeclinesFrom map[string]string
+
+	mu          sync.Mutex
+	nextID      int
+	challenges  map[string]ChallengeOptions
+	declined    map[string]string
+	games       map[string]*fakeGame
+	outgoing    []OutgoingChallenge
+	tournaments map[string]*fakeTournament
+	eventSubs   map[chan []byte]struct{}
+	closed      chan struct{}
+}
+
+// OnlineBot is another bot account listed as online, rated Rating in every
+// speed category.
+type OnlineBot struct {
+	Username string
+	Rating   int
+}
+
+// OutgoingChallenge is a challenge the bot sent. Declined holds the reason
+// the opponent declined with, if it did.
+type OutgoingChallenge struct {
+	ID       string
+	Options  ChallengeOptions
+	Declined string
+}
+
+type fakeGame struct {
+	id      string
+	options ChallengeOptions
+	// source is the Lichess game source: "friend", "arena" or "swiss".
+	source     string
+	tournament *fakeTournament
+	berserk    bool
+	board      *board.Board
+	side       color.Color
+	last       *board.LastMove
+	moves      []string
+	clock      [color.NumColors]time.Duration
+	turnStart  time.Time
+	flag       *time.Timer
+	status     string
+	winner     string
+	offers     int
+	rejected   int
+	// drawOffer and takebackOffer are the opponent's pending offers; a move
+	// clears them.
+	drawOffer       bool
+	takebackOffer   bool
+	drawAnswers     []string
+	takebackAnswers []string
+	chat            []ChatLine
+	subs            map[chan []byte]struct{}
+	done            chan struct{}
+}
+
+// NewServer starts a fake Lichess for the bot account botID.
+func NewServer(botID string) *Server {
+	s := &Server{
+		BotID:       botID,
+		KeepAlive:   defaultKeepAlive,
+		BotRating:   defaultRating,
+		challenges:  map[string]ChallengeOptions{},
+		declined:    map[string]string{},
+		games:       map[string]*fakeGame{},
+		tournaments: map[string]*fakeTournament{},
+		eventSubs:   map[chan []byte]struct{}{},
+		closed:      make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/account", s.handleAccount)
+	mux.HandleFunc("GET /api/account/playing", s.handlePlaying)
+	mux.HandleFunc("GET /api/stream/event", s.handleEventStream)
+	mux.HandleFunc("POST /api/challenge/{username}", s.handleOutgoingChallenge)
+	mux.HandleFunc("POST /api/challenge/{id}/accept", s.handleAccept)
+	mux.HandleFunc("POST /api/challenge/{id}/decline", s.handleDecline)
+	mux.HandleFunc("POST /api/challenge/{id}/cancel", s.handleCancel)
+	mux.HandleFunc("GET /api/bot/online", s.handleOnlineBots)
+	mux.HandleFunc("GET /api/bot/game/stream/{id}", s.handleGameStream)
+	mux.HandleFunc("POST /api/bot/game/{id}/move/{move}", s.handleMove)
+	mux.HandleFunc("POST /api/bot/game/{id}/resign", s.handleResign)
+	mux.HandleFunc("POST /api/bot/game/{id}/draw/{answer}", s.handleDraw)
+	mux.HandleFunc("POST /api/bot/game/{id}/takeback/{answer}", s.handleTakeback)
+	mux.HandleFunc("POST /api/bot/game/{id}/chat", s.handleChat)
+	mux.HandleFunc("POST /api/bot/game/{id}/berserk", s.handleBerserk)
+	mux.HandleFunc("GET /api/tournament", s.handleTournaments)
+	mux.HandleFunc("GET /api/tournament/{id}", s.handleTournament(false))
+	mux.HandleFunc("POST /api/tournament/{id}/join", s.handleJoin(false))
+	mux.HandleFunc("POST /api/tournament/{id}/withdraw", s.handleWithdraw(false))
+	mux.HandleFunc("GET /api/swiss/{id}", s.handleTournament(true))
+	mux.HandleFunc("POST /api/swiss/{id}/join", s.handleJoin(true))
+	mux.HandleFunc("POST /api/swiss/{id}/withdraw", s.handleWithdraw(true))
+	s.Server = httptest.NewServer(s.authorize(mux))
+	return s
+}
+
+// Close ends all open streams and shuts the server down.
+func (s *Server) Close() {
+	s.mu.Lock()
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
+	for _, g := range s.games {
+		if g.flag != nil {
+			g.flag.Stop()
+		}
+	}
+	s.mu.Unlock()
+	s.Server.Close()
+}
+
+// Challenge sends the bot an incoming challenge and returns its ID, which is
+// also the ID of the game if the bot accepts.
+func (s *Server) Challenge(opts ChallengeOptions) string {
+	if opts.Challenger == "" {
+		opts.Challenger = defaultOpponentName
+	}
+	if opts.Variant == "" {
+		opts.Variant = "standard"
+	}
+	if opts.Initial == 0 {
+		opts.Initial = defaultInitial
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	id := fmt.Sprintf("game%04d", s.nextID)
+	s.challenges[id] = opts
+	s.broadcastEvent(challengeEvent(id, opts))
+	return id
+}
+
+// DeclineReason returns the reason the bot gave for declining a challenge.
+func (s *Server) DeclineReason(id string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reason, ok := s.declined[id]
+	return reason, ok
+}
+
+// Outgoing returns the challenges the bot has sent, oldest first.
+func (s *Server) Outgoing() []OutgoingChallenge {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]OutgoingChallenge(nil), s.outgoing...)
+}
+
+// Game returns a snapshot of a game, if the bot has accepted it.
+func (s *Server) Game(id string) (GameResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.games[id]
+	if !ok {
+		return GameResult{}, false
+	}
+	return g.result(), true
+}
+
+// WaitGame blocks until the game ends or timeout passes.
+func (s *Server) WaitGame(id string, timeout time.Duration) (GameResult, error) {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		g, ok := s.games[id]
+		s.mu.Unlock()
+		if ok {
+			select {
+			case <-g.done:
+				result, _ := s.Game(id)
+				return result, nil
+			case <-deadline:
+				result, _ := s.Game(id)
+				return result, fmt.Errorf("game %s still %s after %s", id, result.Status, timeout)
+			}
+		}
+		select {
+		case <-deadline:
+			return GameResult{}, fmt.Errorf("game %s never started", id)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// PlayOpponentMove plays uci for the opponent.
+func (s *Server) PlayOpponentMove(id, uci string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.games[id]
+	if !ok {
+		return fmt.Errorf("no game %s", id)
+	}
+	if g.side == g.options.BotColor {
+		return errGameOver
+	}
+	return s.applyMove(g, uci)
+}
+
+// OfferDraw makes the opponent offer a draw, which the bot sees on its next
+// gameState.
+func (s *Server) OfferDraw(id string) error {
+	return s.opponentOffer(id, func(g *fakeGame) { g.drawOffer = true })
+}
+
+// ProposeTakeback makes the opponent propose to take back its last move.
+func (s *Server) ProposeTakeback(id string) error {
+	return s.opponentOffer(id, func(g *fakeGame) { g.takebackOffer = true })
+}
+
+// OpponentResigns makes the opponent resign game id.
+func (s *Server) OpponentResigns(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.games[id]
+	if !ok {
+		return fmt.Errorf("no game %s", id)
+	}
+	if g.status != StatusStarted {
+		return errGameOver
+	}
+	s.finish(g, StatusResign, colorName(g.options.BotColor))
+	return nil
+}
+
+func (s *Server) opponentOffer(id string, offer func(g *fakeGame)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.games[id]
+	if !ok {
+		return fmt.Errorf("no game %s", id)
+	}
+	if g.status != StatusStarted {
+		return errGameOver
+	}
+	offer(g)
+	s.broadcastGame(g, gameState(g))
+	return nil
+}
+
+// Say posts text to a chat room of game id as username, which the bot sees as
+// a chatLine on its game stream.
+func (s *Server) Say(id, username, room, text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.games[id]
+	if !ok {
+		return fmt.Errorf("no game %s", id)
+	}
+	s.broadcastGame(g, chatLine(username, room, text))
+	return nil
+}
+
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Token != "" && r.Header.Get("Authorization") != "Bearer "+s.Token {
+			writeError(w, http.StatusUnauthorized, "No such token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleAccount(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, user(s.BotID, s.BotRating))
+}
+
+// handlePlaying lists the games in progress, like /api/account/playing.
+func (s *Server) handlePlaying(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	playing := []interface{}{}
+	for _, id := range s.sortedGameIDs() {
+		if g := s.games[id]; g.status == StatusStarted {
+			playing = append(playing, gameEvent("", g)["game"])
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"nowPlaying": playing})
+}
+
+func (s *Server) handleOnlineBots(w http.ResponseWriter, r *http.Request) {
+	nb, err := strconv.Atoi(r.URL.Query().Get("nb"))
+	if err != nil || nb <= 0 {
+		nb = 50
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	for i, bot := range s.OnlineBots {
+		if i == nb {
+			break
+		}
+		_, _ = w.Write(append(mustMarshal(user(bot.Username, bot.Rating)), '\n'))
+	}
+}
+
+func (s *Server) handleOutgoingChallenge(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	opts := ChallengeOptions{
+		Challenger: r.PathValue("username"),
+		Rated:      r.Form.Get("rated") == "true",
+		Variant:    r.Form.Get("variant"),
+	}
+	limit, _ := strconv.Atoi(r.Form.Get("clock.limit"))
+	inc, _ := strconv.Atoi(r.Form.Get("clock.increment"))
+	opts.Initial = time.Duration(limit) * time.Second
+	opts.Increment = time.Duration(inc) * time.Second
+	if r.Form.Get("color") == "black" {
+		opts.BotColor = color.Black
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	id := fmt.Sprintf("game%04d", s.nextID)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"challenge": map[string]string{"id": id}})
+	// The opponent answers at once: like Lichess, a decline is reported on
+	// the event stream.
+	reason, declines := s.DeclinesFrom[opts.Challenger]
+	s.outgoing = append(s.outgoing, OutgoingChallenge{ID: id, Options: opts, Declined: reason})
+	if declines {
+		s.broadcastEvent(map[string]interface{}{
+			"type":      "challengeDeclined",
+			"challenge": map[string]interface{}{"id": id, "status": "declined", "declineReason": reason},
+		})
+		return
+	}
+	s.startGame(id, opts, sourceFriend)
+}
+
+func (s *Server) handleAccept(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	opts, ok := s.challenges[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Challenge not found")
+		return
+	}
+	delete(s.challenges, id)
+	s.startGame(id, opts, sourceFriend)
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (s *Server) handleDecline(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	_ = r.ParseForm()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.challenges[id]; !ok {
+		writeError(w, http.StatusNotFound, "Challenge not found")
+		return
+	}
+	delete(s.challenges, id)
+	reason := r.Form.Get("reason")
+	if reason == "" {
+		reason = "generic"
+	}
+	s.declined[id] = reason
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// handleCancel always fails: outgoing challenges are answered as soon as they
+// are made, so none is ever open to cancel.
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, "Challenge not found: "+r.PathValue("id"))
+}
+
+func (s *Server) handleMove(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.games[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such game")
+		return
+	}
+	if g.status != StatusStarted || g.side != g.options.BotColor {
+		g.rejected++
+		writeError(w, http.StatusBadRequest, errGameOver.Error())
+		return
+	}
+	if r.URL.Query().Get("offeringDraw") == "true" {
+		g.offers++
+	}
+	g.clock[g.side] -= s.Lag
+	if err := s.applyMove(g, r.PathValue("move")); err != nil {
+		g.rejected++
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if r.URL.Query().Get("offeringDraw") == "true" && s.OpponentAcceptsDraws && g.status == StatusStarted {
+		s.finish(g, StatusDraw, "")
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (s *Server) handleResign(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.games[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such game")
+		return
+	}
+	if g.status != StatusStarted {
+		writeError(w, http.StatusBadRequest, errGameOver.Error())
+		return
+	}
+	s.finish(g, StatusResign, colorName(g.options.BotColor^1))
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// handleDraw answers the opponent's draw offer. Like Lichess, "yes" without a
+// pending offer makes a draw offer of the bot's own.
+func (s *Server) handleDraw(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.games[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such game")
+		return
+	}
+	if g.status != StatusStarted {
+		writeError(w, http.StatusBadRequest, errGameOver.Error())
+		return
+	}
+	accept := r.PathValue("answer") == "yes"
+	switch {
+	case g.drawOffer:
+		g.drawOffer = false
+		g.drawAnswers = append(g.drawAnswers, r.PathValue("answer"))
+		if accept {
+			s.finish(g, StatusDraw, "")
+		} else {
+			s.broadcastGame(g, gameState(g))
+		}
+	case accept:
+		g.offers++
+		if s.OpponentAcceptsDraws {
+			s.finish(g, StatusDraw, "")
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// handleChat records a bot chat line and echoes it on the game stream, as
+// Lichess does. Chat stays open after the game ends.
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.games[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such game")
+		return
+	}
+	room, text := r.PostFormValue("room"), r.PostFormValue("text")
+	if (room != "player" && room != "spectator") || text == "" {
+		writeError(w, http.StatusBadRequest, "Invalid chat line")
+		return
+	}
+	g.chat = append(g.chat, ChatLine{Room: room, Text: text})
+	s.broadcastGame(g, chatLine(s.BotID, room, text))
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// handleTakeback answers the opponent's takeback proposal. Granting it rewinds
+// the game to the opponent's last turn.
+func (s *Server) handleTakeback(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.games[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such game")
+		return
+	}
+	if g.status != StatusStarted || !g.takebackOffer {
+		writeError(w, http.StatusBadRequest, "No takeback proposal")
+		return
+	}
+	g.takebackOffer = false
+	g.takebackAnswers = append(g.takebackAnswers, r.PathValue("answer"))
+	granted := r.PathValue("answer") == "yes"
+	if granted {
+		// The opponent's last move goes, and with it our reply if we made one.
+		undo := 1
+		if g.side != g.options.BotColor {
+			undo = 2
+		}
+		if undo > len(g.moves) {
+			undo = len(g.moves)
+		}
+		s.rewind(g, g.moves[:len(g.moves)-undo])
+	}
+	s.broadcastGame(g, gameState(g))
+	if granted {
+		s.scheduleOpponent(g)
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// rewind replays moves from the start position; clocks are left as they are.
+// Must be called with the mutex held.
+func (s *Server) rewind(g *fakeGame, moves []string) {
+	g.setStart()
+	for _, uci := range moves {
+		m, err := analysis.MatchUCIMove(g.board, g.side, g.last, uci)
+		if err != nil {
+			panic(fmt.Sprintf("replaying own move %s: %s", uci, err))
+		}
+		g.last = board.MakeMove(&m, g.board)
+		g.side ^= 1
+	}
+	g.moves = append([]string(nil), moves...)
+	g.turnStart = time.Now()
+	s.armFlag(g)
+}
+
+// handleEventStream streams account events. Like Lichess, a new connection
+// first receives gameStart for every ongoing game and every open challenge.
+func (s *Server) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	ch := make(chan []byte, streamBuffer)
+	s.mu.Lock()
+	for _, id := range s.sortedGameIDs() {
+		if g := s.games[id]; g.status == StatusStarted {
+			ch <- mustMarshal(gameEvent("gameStart", g))
+		}
+	}
+	for id, opts := range s.challenges {
+		ch <- mustMarshal(challengeEvent(id, opts))
+	}
+	s.eventSubs[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.eventSubs, ch)
+		s.mu.Unlock()
+	}()
+	s.stream(w, r, ch, nil)
+}
+
+// handleGameStream streams one game: gameFull, then a gameState per move. The
+// stream ends after the final state of a finished game.
+func (s *Server) handleGameStream(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	g, ok := s.games[r.PathValue("id")]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "No such game")
+		return
+	}
+	ch := make(chan []byte, streamBuffer)
+	ch <- mustMarshal(gameFull(g, s.BotID))
+	g.subs[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(g.subs, ch)
+		s.mu.Unlock()
+	}()
+	s.stream(w, r, ch, g.done)
+}
+
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, ch chan []byte, done chan struct{}) {
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	write := func(line []byte) bool {
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	}
+	keepAlive := time.NewTicker(s.KeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case line := <-ch:
+			if !write(line) {
+				return
+			}
+		case <-done:
+			for {
+				select {
+				case line := <-ch:
+					write(line)
+				default:
+					return
+				}
+			}
+		case <-keepAlive.C:
+			if !write(nil) {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// startGame must be called with the mutex held.
+func (s *Server) startGame(id string, opts ChallengeOptions, source string) *fakeGame {
+	g := &fakeGame{
+		id:        id,
+		options:   opts,
+		source:    source,
+		clock:     [color.NumColors]time.Duration{opts.Initial, opts.Initial},
+		turnStart: time.Now(),
+		status:    StatusStarted,
+		subs:      map[chan []byte]struct{}{},
+		done:      make(chan struct{}),
+	}
+	g.setStart()
+	s.games[id] = g
+	s.armFlag(g)
+	s.broadcastEvent(gameEvent("gameStart", g))
+	s.scheduleOpponent(g)
+	return g
+}
+
+// applyMove plays uci for the side to move, charging its clock. Must be called
+// with the mutex held.
+func (s *Server) applyMove(g *fakeGame, uci string) error {
+	if g.status != StatusStarted {
+		return errGameOver
+	}
+	mover := g.side
+	g.clock[mover] -= time.Since(g.turnStart)
+	if g.clock[mover] <= 0 {
+		g.clock[mover] = 0
+		s.finish(g, StatusOutOfTime, colorName(mover^1))
+		return errGameOver
+	}
+	m, err := analysis.MatchUCIMove(g.board, mover, g.last, uci)
+	if err != nil {
+		return fmt.Errorf("%w %s: %s", errIllegalMove, uci, err)
+	}
+	g.last = board.MakeMove(&m, g.board)
+	g.moves = append(g.moves, uci)
+	g.drawOffer, g.takebackOffer = false, false
+	g.clock[mover] += g.increment(mover)
+	g.side ^= 1
+	g.turnStart = time.Now()
+
+	next := g.side
+	switch {
+	case g.board.IsInCheckmate(next, g.last):
+		s.finish(g, StatusMate, colorName(mover))
+	case g.board.IsStalemate(next, g.last):
+		s.finish(g, StatusStalemate, "")
+	case g.board.IsInsufficientMaterial(), g.board.CurrentPositionRepeats >= 4, g.board.MovesSinceNoDraw >= 150:
+		// Lichess ends the game itself on fivefold repetition and the
+		// seventy-five-move rule.
+		s.finish(g, StatusDraw, "")
+	default:
+		s.armFlag(g)
+		s.broadcastGame(g, gameState(g))
+		s.scheduleOpponent(g)
+	}
+	return nil
+}
+
+func (s *Server) armFlag(g *fakeGame) {
+	if g.flag != nil {
+		g.flag.Stop()
+	}
+	side, ply := g.side, len(g.moves)
+	g.flag = time.AfterFunc(g.clock[side], func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if g.status == StatusStarted && len(g.moves) == ply {
+			g.clock[side] = 0
+			s.finish(g, StatusOutOfTime, colorName(side^1))
+		}
+	})
+}
+
+func (s *Server) scheduleOpponent(g *fakeGame) {
+	if s.Opponent == nil || g.side == g.options.BotColor {
+		return
+	}
+	ply := len(g.moves)
+	go func() {
+		time.Sleep(s.OpponentDelay)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if g.status != StatusStarted || len(g.moves) != ply {
+			return
+		}
+		uci := s.Opponent(g.board.Copy(), g.side, g.last, append([]string(nil), g.moves...))
+		if uci == "" {
+			s.finish(g, StatusResign, colorName(g.options.BotColor))
+			return
+		}
+		if err := s.applyMove(g, uci); err != nil && g.status == StatusStarted {
+			// A broken script must not leave the bot waiting forever.
+			s.finish(g, StatusAborted, "")
+		}
+	}()
+}
+
+// finish ends the game and notifies both streams. Must be called with the
+// mutex held.
+func (s *Server) finish(g *fakeGame, status, winner string) {
+	g.status, g.winner = status, winner
+	if g.flag != nil {
+		g.flag.Stop()
+	}
+	s.broadcastGame(g, gameState(g))
+	close(g.done)
+	s.broadcastEvent(gameEvent("gameFinish", g))
+	if g.tournament != nil {
+		s.pair(g.tournament)
+	}
+}
+
+func (s *Server) broadcastEvent(v interface{}) {
+	line := mustMarshal(v)
+	for ch := range s.eventSubs {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+func (s *Server) broadcastGame(g *fakeGame, v interface{}) {
+	line := mustMarshal(v)
+	for ch := range g.subs {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+func (s *Server) sortedGameIDs() []string {
+	ids := make([]string, 0, len(s.games))
+	for id := range s.games {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// setStart puts g at its start position with no moves played.
+func (g *fakeGame) setStart() {
+	if g.options.InitialFEN == "" {
+		g.board, g.side, g.last = &board.Board{}, color.White, nil
+		g.board.ResetDefault()
+		return
+	}
+	parsed, err := analysis.ParseFEN(g.options.InitialFEN)
+	if err != nil {
+		panic(fmt.Sprintf("bad InitialFEN %q: %s", g.options.InitialFEN, err))
+	}
+	g.board, g.side, g.last = parsed.Board, parsed.Active, parsed.Previous
+}
+
+// increment is side's increment; a berserked bot has none.
+func (g *fakeGame) increment(side color.Color) time.Duration {
+	if g.berserk && side == g.options.BotColor {
+		return 0
+	}
+	return g.options.Increment
+}
+
+func (g *fakeGame) result() GameResult {
+	return GameResult{
+		Moves:           append([]string(nil), g.moves...),
+		Status:          g.status,
+		Winner:          g.winner,
+		Clock:           g.clock,
+		DrawOffers:      g.offers,
+		RejectedMoves:   g.rejected,
+		DrawAnswers:     append([]string(nil), g.drawAnswers...),
+		TakebackAnswers: append([]string(nil), g.takebackAnswers...),
+		Chat:            append([]ChatLine(nil), g.chat...),
+	}
+}
+
+func challengeEvent(id string, opts ChallengeOptions) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "challenge",
+		"challenge": map[string]interface{}{
+			"id":     id,
+			"status": "created",
+			"challenger": map[string]interface{}{
+				"id":     strings.ToLower(opts.Challenger),
+				"name":   opts.Challenger,
+				"title":  opts.Title,
+				"rating": opts.Rating,
+			},
+			"rated":   opts.Rated,
+			"variant": map[string]string{"key": opts.Variant},
+			"timeControl": map[string]interface{}{
+				"type":      "clock",
+				"limit":     int(opts.Initial / time.Second),
+				"increment": int(opts.Increment / time.Second),
+			},
+			"color": colorName(opts.BotColor ^ 1),
+		},
+	}
+}
+
+func gameEvent(eventType string, g *fakeGame) map[string]interface{} {
+	fullMove := len(g.moves)/2 + 1
+	return map[string]interface{}{
+		"type": eventType,
+		"game": map[string]interface{}{
+			"id":          g.id,
+			"gameId":      g.id,
+			"fullId":      g.id + "bot1",
+			"color":       colorName(g.options.BotColor),
+			"fen":         analysis.BoardToFEN(g.board, g.side, g.last, fullMove),
+			"hasMoved":    len(g.moves) > int(g.options.BotColor),
+			"isMyTurn":    g.status == StatusStarted && g.side == g.options.BotColor,
+			"secondsLeft": g.clock[g.options.BotColor].Seconds(),
+			"source":      g.source,
+			"rated":       g.options.Rated,
+			"status":      map[string]string{"name": g.status},
+			"winner":      g.winner,
+			"opponent":    map[string]interface{}{"id": strings.ToLower(g.options.Challenger), "username": g.options.Challenger, "rating": g.options.Rating},
+		},
+	}
+}
+
+func gameState(g *fakeGame) map[string]interface{} {
+	state := map[string]interface{}{
+		"type":   "gameState",
+		"moves":  strings.Join(g.moves, " "),
+		"wtime":  g.clock[color.White].Milliseconds(),
+		"btime":  g.clock[color.Black].Milliseconds(),
+		"winc":   g.increment(color.White).Milliseconds(),
+		"binc":   g.increment(color.Black).Milliseconds(),
+		"status": g.status,
+	}
+	if g.winner != "" {
+		state["winner"] = g.winner
+	}
+	opponent := colorName(g.options.BotColor ^ 1)[:1]
+	if g.drawOffer {
+		state[opponent+"draw"] = true
+	}
+	if g.takebackOffer {
+		state[opponent+"takeback"] = true
+	}
+	return state
+}
+
+func gameFull(g *fakeGame, botID string) map[string]interface{} {
+	players := [color.NumColors]map[string]interface{}{}
+	players[g.options.BotColor] = map[string]interface{}{"id": strings.ToLower(botID), "name": botID, "title": "BOT"}
+	players[g.options.BotColor^1] = map[string]interface{}{"id": strings.ToLower(g.options.Challenger), "name": g.options.Challenger, "rating": g.options.Rating}
+	initialFen := "startpos"
+	if g.options.InitialFEN != "" {
+		initialFen = g.options.InitialFEN
+	}
+	return map[string]interface{}{
+		"type":       "gameFull",
+		"id":         g.id,
+		"rated":      g.options.Rated,
+		"variant":    map[string]string{"key": g.options.Variant},
+		"clock":      map[string]int64{"initial": g.options.Initial.Milliseconds(), "increment": g.options.Increment.Milliseconds()},
+		"white":      players[color.White],
+		"black":      players[color.Black],
+		"initialFen": initialFen,
+		"state":      gameState(g),
+	}
+}
+
+func chatLine(username, room, text string) map[string]interface{} {
+	return map[string]interface{}{"type": "chatLine", "username": username, "room": room, "text": text}
+}
+
+func user(name string, rating int) map[string]interface{} {
+	perfs := map[string]interface{}{}
+	for _, speed := range []string{"ultraBullet", "bullet", "blitz", "rapid", "classical"} {
+		perfs[speed] = map[string]interface{}{"rating": rating, "games": 100, "prov": false}
+	}
+	return map[string]interface{}{"id": strings.ToLower(name), "username": name, "title": "BOT", "perfs": perfs}
+}
+
+func colorName(c color.Color) string {
+	return strings.ToLower(color.Names[c])
+}
+
+func mustMarshal(v interface{}) []byte {
+	line, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return line
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,104 @@
+package lichesstest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func post(t *testing.T, s *Server, path string) int {
+	t.Helper()
+	resp, err := http.Post(s.URL+path, "application/x-www-form-urlencoded", nil)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestRejectsIllegalAndOutOfTurnMoves(t *testing.T) {
+	s := NewServer("bot")
+	defer s.Close()
+	id := s.Challenge(ChallengeOptions{})
+	assert.Equal(t, http.StatusOK, post(t, s, "/api/challenge/"+id+"/accept"))
+
+	assert.Equal(t, http.StatusBadRequest, post(t, s, "/api/bot/game/"+id+"/move/e2e5"))
+	assert.Equal(t, http.StatusOK, post(t, s, "/api/bot/game/"+id+"/move/e2e4"))
+	assert.Equal(t, http.StatusBadRequest, post(t, s, "/api/bot/game/"+id+"/move/d2d4"), "it is the opponent's turn")
+	assert.Error(t, s.PlayOpponentMove(id, "e7e4"))
+	assert.NoError(t, s.PlayOpponentMove(id, "e7e5"))
+
+	result, ok := s.Game(id)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"e2e4", "e7e5"}, result.Moves)
+	assert.Equal(t, 2, result.RejectedMoves)
+	assert.Equal(t, StatusStarted, result.Status)
+}
+
+func TestFlagsBotOnTime(t *testing.T) {
+	s := NewServer("bot")
+	defer s.Close()
+	id := s.Challenge(ChallengeOptions{Initial: 100 * time.Millisecond, BotColor: color.Black})
+	assert.Equal(t, http.StatusOK, post(t, s, "/api/challenge/"+id+"/accept"))
+	assert.NoError(t, s.PlayOpponentMove(id, "e2e4"))
+
+	result, err := s.WaitGame(id, 2*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusOutOfTime, result.Status)
+	assert.Equal(t, "white", result.Winner)
+	assert.Equal(t, time.Duration(0), result.Clock[color.Black])
+	assert.Equal(t, http.StatusBadRequest, post(t, s, "/api/bot/game/"+id+"/move/e7e5"))
+}
+
+func TestGameStreamStartsWithGameFullAndEndsAfterFinalState(t *testing.T) {
+	s := NewServer("bot")
+	s.Opponent = ScriptedOpponent("e7e5")
+	defer s.Close()
+	id := s.Challenge(ChallengeOptions{})
+	assert.Equal(t, http.StatusOK, post(t, s, "/api/challenge/"+id+"/accept"))
+
+	resp, err := http.Get(s.URL + "/api/bot/game/stream/" + id)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, post(t, s, "/api/bot/game/"+id+"/move/e2e4"))
+	for result, _ := s.Game(id); len(result.Moves) < 2; result, _ = s.Game(id) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, http.StatusOK, post(t, s, "/api/bot/game/"+id+"/move/d2d4"))
+
+	var types, statuses []string
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var line struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+			Moves  string `json:"moves"`
+		}
+		if err := decoder.Decode(&line); err != nil {
+			break
+		}
+		types = append(types, line.Type)
+		statuses = append(statuses, line.Status)
+	}
+	assert.Equal(t, "gameFull", types[0])
+	// One state per move, then the final state with the result.
+	assert.Equal(t, []string{"gameState", "gameState", "gameState", "gameState"}, types[1:])
+	// The script is exhausted after e7e5, so the opponent resigns on its second turn.
+	assert.Equal(t, StatusResign, statuses[len(statuses)-1])
+	result, _ := s.Game(id)
+	assert.Equal(t, "white", result.Winner)
+	assert.Equal(t, "e2e4 e7e5 d2d4", strings.Join(result.Moves, " "))
+}
+
+func TestRequiresToken(t *testing.T) {
+	s := NewServer("bot")
+	s.Token = "secret"
+	defer s.Close()
+	resp, err := http.Get(s.URL + "/api/account")
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
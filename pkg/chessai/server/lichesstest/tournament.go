@@ -0,0 +1,286 @@
+package lichesstest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+)
+
+// Tournament statuses.
+const (
+	TournamentCreated  = "created"
+	TournamentStarted  = "started"
+	TournamentFinished = "finished"
+)
+
+// Tournament is an arena, or with Swiss a Swiss tournament, on the fake
+// server. While it is started, the fake pairs the bot, once it has joined and
+// unless it is paused, against the opponent whenever its last game in the
+// event has ended, alternating colors. A Swiss is paired like an arena,
+// without rounds. StartsAt orders created arenas in the /api/tournament
+// listing.
+type Tournament struct {
+	ID          string
+	Name        string
+	Swiss       bool
+	BotsAllowed bool
+	Berserkable bool
+	Rated       bool
+	Initial     time.Duration
+	Increment   time.Duration
+	StartsAt    time.Time
+}
+
+// TournamentResult is a snapshot of a tournament and the bot's part in it.
+type TournamentResult struct {
+	Status string
+	// Joined reports whether the bot is in the pairing pool.
+	Joined bool
+	// Joins and Withdrawals count the bot's join and withdraw requests.
+	Joins       int
+	Withdrawals int
+	// Games are the IDs of the bot's games in the event, oldest first.
+	Games []string
+	// Berserks counts the bot's berserked games.
+	Berserks int
+}
+
+type fakeTournament struct {
+	Tournament
+	status      string
+	joined      bool
+	everJoined  bool
+	joins       int
+	withdrawals int
+	games       []string
+	berserks    int
+}
+
+// AddTournament creates t, not yet started.
+func (s *Server) AddTournament(t Tournament) {
+	if t.Initial == 0 {
+		t.Initial = defaultInitial
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tournaments[t.ID] = &fakeTournament{Tournament: t, status: TournamentCreated}
+}
+
+// StartTournament starts pairing in tournament id.
+func (s *Server) StartTournament(id string) error {
+	return s.updateTournament(id, func(t *fakeTournament) {
+		t.status = TournamentStarted
+		s.pair(t)
+	})
+}
+
+// FinishTournament ends tournament id. Games in progress play on, but no
+// more are paired.
+func (s *Server) FinishTournament(id string) error {
+	return s.updateTournament(id, func(t *fakeTournament) { t.status = TournamentFinished })
+}
+
+// PauseTournamentPlayer takes the bot out of the pairing pool, as Lichess
+// does to a player who misses the start of a game; it must join again to be
+// paired.
+func (s *Server) PauseTournamentPlayer(id string) error {
+	return s.updateTournament(id, func(t *fakeTournament) { t.joined = false })
+}
+
+// TournamentState returns a snapshot of tournament id.
+func (s *Server) TournamentState(id string) (TournamentResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tournaments[id]
+	if !ok {
+		return TournamentResult{}, false
+	}
+	return TournamentResult{
+		Status:      t.status,
+		Joined:      t.joined,
+		Joins:       t.joins,
+		Withdrawals: t.withdrawals,
+		Games:       append([]string(nil), t.games...),
+		Berserks:    t.berserks,
+	}, true
+}
+
+func (s *Server) updateTournament(id string, update func(t *fakeTournament)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tournaments[id]
+	if !ok {
+		return fmt.Errorf("no tournament %s", id)
+	}
+	update(t)
+	return nil
+}
+
+// pair starts the bot's next game in t if it is due one. Must be called with
+// the mutex held.
+func (s *Server) pair(t *fakeTournament) {
+	if t.status != TournamentStarted || !t.joined {
+		return
+	}
+	if n := len(t.games); n > 0 && s.games[t.games[n-1]].status == StatusStarted {
+		return
+	}
+	s.nextID++
+	id := fmt.Sprintf("game%04d", s.nextID)
+	opts := ChallengeOptions{
+		Challenger: defaultOpponentName,
+		Rated:      t.Rated,
+		Variant:    "standard",
+		Initial:    t.Initial,
+		Increment:  t.Increment,
+		BotColor:   color.Color(len(t.games) % 2),
+	}
+	t.games = append(t.games, id)
+	source := "arena"
+	if t.Swiss {
+		source = "swiss"
+	}
+	// The game is linked to t before it can finish, so that its end pairs
+	// the next one.
+	g := s.startGame(id, opts, source)
+	g.tournament = t
+}
+
+// tournament finds the arena or Swiss in the request path, writing a 404
+// when there is none. Must be called with the mutex held.
+func (s *Server) tournament(w http.ResponseWriter, r *http.Request, swiss bool) (*fakeTournament, bool) {
+	t, ok := s.tournaments[r.PathValue("id")]
+	if !ok || t.Swiss != swiss {
+		writeError(w, http.StatusNotFound, "Tournament not found")
+		return nil, false
+	}
+	return t, true
+}
+
+// handleTournaments lists created and started arenas, like /api/tournament.
+func (s *Server) handleTournaments(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	listing := map[string][]interface{}{"created": {}, "started": {}, "finished": {}}
+	ids := make([]string, 0, len(s.tournaments))
+	for id := range s.tournaments {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		t := s.tournaments[id]
+		if t.Swiss {
+			continue
+		}
+		listing[t.status] = append(listing[t.status], map[string]interface{}{
+			"id":          t.ID,
+			"fullName":    t.Name,
+			"clock":       tournamentClock(t),
+			"variant":     map[string]string{"key": "standard"},
+			"rated":       t.Rated,
+			"botsAllowed": t.BotsAllowed,
+			"startsAt":    t.StartsAt.UnixMilli(),
+		})
+	}
+	writeJSON(w, http.StatusOK, listing)
+}
+
+// handleTournament describes an arena, with "me" once the bot has joined,
+// or a Swiss.
+func (s *Server) handleTournament(swiss bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		t, ok := s.tournament(w, r, swiss)
+		if !ok {
+			return
+		}
+		if swiss {
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"id":     t.ID,
+				"name":   t.Name,
+				"clock":  tournamentClock(t),
+				"status": t.status,
+			})
+			return
+		}
+		arena := map[string]interface{}{
+			"id":          t.ID,
+			"fullName":    t.Name,
+			"clock":       tournamentClock(t),
+			"variant":     "standard",
+			"rated":       t.Rated,
+			"berserkable": t.Berserkable,
+			"isStarted":   t.status == TournamentStarted,
+			"isFinished":  t.status == TournamentFinished,
+		}
+		if t.everJoined {
+			arena["me"] = map[string]interface{}{"rank": 1, "withdraw": !t.joined}
+		}
+		writeJSON(w, http.StatusOK, arena)
+	}
+}
+
+func (s *Server) handleJoin(swiss bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		t, ok := s.tournament(w, r, swiss)
+		if !ok {
+			return
+		}
+		if t.status == TournamentFinished {
+			writeError(w, http.StatusBadRequest, "Tournament is finished")
+			return
+		}
+		t.joins++
+		t.joined, t.everJoined = true, true
+		s.pair(t)
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	}
+}
+
+func (s *Server) handleWithdraw(swiss bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		t, ok := s.tournament(w, r, swiss)
+		if !ok {
+			return
+		}
+		t.withdrawals++
+		t.joined = false
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	}
+}
+
+// handleBerserk halves the bot's clock and drops its increment in an arena
+// game it has not moved in yet.
+func (s *Server) handleBerserk(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.games[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such game")
+		return
+	}
+	if g.status != StatusStarted || g.tournament == nil || g.tournament.Swiss || !g.tournament.Berserkable ||
+		g.berserk || len(g.moves) > int(g.options.BotColor) {
+		writeError(w, http.StatusBadRequest, "Cannot berserk")
+		return
+	}
+	g.berserk = true
+	g.tournament.berserks++
+	g.clock[g.options.BotColor] /= 2
+	if g.side == g.options.BotColor {
+		s.armFlag(g)
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+func tournamentClock(t *fakeTournament) map[string]int {
+	return map[string]int{"limit": int(t.Initial / time.Second), "increment": int(t.Increment / time.Second)}
+}
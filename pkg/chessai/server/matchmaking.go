@@ -0,0 +1,458 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	log "github.com/sirupsen/logrus"
+)
+
+// errRateLimited is returned when Lichess answers HTTP 429.
+var errRateLimited = errors.New("rate limited (429)")
+
+const (
+	defaultMatchmakingTick = 5 * time.Second
+	// Lichess asks API clients to wait a full minute after a 429.
+	rateLimitBackoff  = time.Minute
+	onlineBotsToFetch = 100
+	// defaultRating is the Lichess rating of an unplayed speed category.
+	defaultRating = 1500
+)
+
+// matchmaker is the state of outgoing challenges to online bots (see
+// game_config.Matchmaking). It is guarded by the Lichess mutex.
+type matchmaker struct {
+	// tick is how often the matchmaker checks whether to challenge; 0 uses
+	// defaultMatchmakingTick.
+	tick      time.Duration
+	idleSince time.Time
+	// pendingID is our unanswered challenge to pendingUser, if any.
+	pendingID    string
+	pendingUser  string
+	pendingSince time.Time
+	// sending is set while the challenge to pendingUser is being sent, before
+	// its ID is known; sendingDeclined is a decline that arrived meanwhile.
+	sending         bool
+	sendingDeclined string
+	// nextChallenge is the earliest time the next challenge may go out.
+	nextChallenge   time.Time
+	nextTimeControl int
+	results         *opponentResults
+}
+
+// gameStarted clears the pending challenge once it turns into a game (a
+// game's ID is its challenge's ID).
+func (m *matchmaker) gameStarted(gameID string) {
+	if gameID == m.pendingID {
+		m.pendingID, m.pendingUser = "", ""
+	}
+}
+
+// opponentRecord is our history against one opponent.
+type opponentRecord struct {
+	Wins           int
+	Losses         int
+	Draws          int
+	Declines       int
+	LastChallenged time.Time
+	LastPlayed     time.Time
+}
+
+func (r *opponentRecord) games() int {
+	return r.Wins + r.Losses + r.Draws
+}
+
+// opponentResults is the per-opponent results file, keyed by lowercase
+// Lichess user ID.
+type opponentResults struct {
+	path      string
+	Opponents map[string]*opponentRecord
+}
+
+func loadOpponentResults(path string) *opponentResults {
+	results := &opponentResults{path: path, Opponents: map[string]*opponentRecord{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("failed to read matchmaking results %s, starting afresh: %s", path, err)
+		}
+		return results
+	}
+	if err := json.Unmarshal(data, results); err != nil {
+		log.Errorf("failed to parse matchmaking results %s, starting afresh: %s", path, err)
+		results.Opponents = map[string]*opponentRecord{}
+	}
+	if results.Opponents == nil {
+		results.Opponents = map[string]*opponentRecord{}
+	}
+	return results
+}
+
+// save writes the file atomically, so a crash mid-write keeps the old results.
+func (r *opponentResults) save() {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		log.Errorf("failed to encode matchmaking results: %s", err)
+		return
+	}
+	tmp := filepath.Join(filepath.Dir(r.path), "."+filepath.Base(r.path)+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		log.Errorf("failed to write matchmaking results %s: %s", r.path, err)
+		return
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		log.Errorf("failed to write matchmaking results %s: %s", r.path, err)
+	}
+}
+
+func (r *opponentResults) get(id string) *opponentRecord {
+	id = strings.ToLower(id)
+	record, ok := r.Opponents[id]
+	if !ok {
+		record = &opponentRecord{}
+		r.Opponents[id] = record
+	}
+	return record
+}
+
+func (l *Lichess) matchmakingConfig() *game_config.Matchmaking {
+	if l.Matchmaking != nil {
+		return l.Matchmaking
+	}
+	if m := game_config.Get().Matchmaking; m != nil {
+		return m
+	}
+	return game_config.DefaultMatchmaking()
+}
+
+// opponentResultsLocked loads the results file on first use. Must be called
+// with the mutex held.
+func (l *Lichess) opponentResultsLocked() *opponentResults {
+	if l.matchmaker.results == nil {
+		l.matchmaker.results = loadOpponentResults(l.matchmakingConfig().ResultsFile)
+	}
+	return l.matchmaker.results
+}
+
+// recordGameResult adds a finished game to the results file and restarts the
+// idle timer. Results are only kept while matchmaking is enabled. Must be
+// called with the mutex held, before resetGame.
+func (l *Lichess) recordGameResult(g *Game) {
+	l.matchmaker.idleSince = time.Now()
+	if !l.matchmakingConfig().Enabled || g == nil || g.Opponent == nil || l.Player == nil {
+		return
+	}
+	if g.Status != nil && g.Status.Name == "aborted" {
+		return
+	}
+	results := l.opponentResultsLocked()
+	record := results.get(g.Opponent.ID)
+	switch g.Winner {
+	case "":
+		record.Draws++
+	case strings.ToLower(color.Names[l.Player.PlayerColor]):
+		record.Wins++
+	default:
+		record.Losses++
+	}
+	record.LastPlayed = time.Now()
+	log.Infof("result against %s: +%d -%d =%d", g.Opponent.ID, record.Wins, record.Losses, record.Draws)
+	results.save()
+}
+
+// outgoingChallengeDeclined records a decline of our pending challenge. Must
+// be called with the mutex held.
+func (l *Lichess) outgoingChallengeDeclined(c *Challenge) {
+	if l.matchmaker.sending {
+		// The challenge being sent may be declined before we learn its ID
+		l.matchmaker.sendingDeclined = c.ID
+	}
+	if c.ID != l.matchmaker.pendingID {
+		return
+	}
+	log.Infof("%s declined our challenge %s: %q", l.matchmaker.pendingUser, c.ID, c.DeclineReason)
+	l.recordDeclineLocked()
+}
+
+func (l *Lichess) recordDeclineLocked() {
+	results := l.opponentResultsLocked()
+	results.get(l.matchmaker.pendingUser).Declines++
+	results.save()
+	l.matchmaker.pendingID, l.matchmaker.pendingUser = "", ""
+}
+
+// runMatchmaking challenges online bots whenever the bot has been idle long
+// enough, until the bot exits.
+func (l *Lichess) runMatchmaking() {
+	l.Mutex.Lock()
+	l.matchmaker.idleSince = time.Now()
+	tick := l.matchmaker.tick
+	l.Mutex.Unlock()
+	if tick == 0 {
+		tick = defaultMatchmakingTick
+	}
+	log.Infof("matchmaking enabled")
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.matchmake()
+		case <-l.exit:
+			return
+		}
+	}
+}
+
+// matchmake challenges an online bot if the bot is ready for another game.
+// Requests to Lichess are made without the mutex, so that the event and game
+// streams are not held up meanwhile.
+func (l *Lichess) matchmake() {
+	cfg := l.matchmakingConfig()
+	l.Mutex.Lock()
+	unanswered := l.unansweredChallengeLocked(cfg)
+	ready := l.matchmakeReadyLocked(cfg)
+	l.Mutex.Unlock()
+	if unanswered != "" {
+		if err := l.CancelChallenge(unanswered); err != nil {
+			log.Errorf("failed to cancel challenge %s: %s", unanswered, err)
+		}
+	}
+	if !ready {
+		return
+	}
+
+	// Fetched without the lock: a game or challenge may arrive meanwhile, so
+	// readiness is checked again before challenging.
+	account, err := l.FetchAccount()
+	if err != nil {
+		log.Errorf("matchmaking: failed to fetch our ratings: %s", err)
+		l.deferMatchmaking(err, cfg)
+		return
+	}
+	bots, err := l.FetchOnlineBots(onlineBotsToFetch)
+	if err != nil {
+		log.Errorf("matchmaking: failed to fetch online bots: %s", err)
+		l.deferMatchmaking(err, cfg)
+		return
+	}
+
+	l.Mutex.Lock()
+	if !l.matchmakeReadyLocked(cfg) {
+		l.Mutex.Unlock()
+		return
+	}
+	now := time.Now()
+	l.matchmaker.nextChallenge = now.Add(time.Duration(cfg.MinChallengeIntervalSec) * time.Second)
+	tc := cfg.TimeControls[l.matchmaker.nextTimeControl%len(cfg.TimeControls)]
+	l.matchmaker.nextTimeControl++
+	speed := speedCategory(tc)
+	rating := defaultRating
+	if perf, ok := account.Perfs[speed]; ok {
+		rating = perf.Rating
+	}
+	results := l.opponentResultsLocked()
+	opponent := pickOpponent(bots, results, cfg, l.challengePolicy().BlockUsers, account.ID, speed, rating, now)
+	if opponent == nil {
+		l.Mutex.Unlock()
+		log.Debugf("matchmaking: no %s opponent within %d of %d among %d online bots", speed, cfg.RatingWindow, rating, len(bots))
+		return
+	}
+	log.Infof("matchmaking: challenging %s (%d) to %d+%d %s", opponent.Username, opponent.Perfs[speed].Rating, tc.InitialSec, tc.IncrementSec, speed)
+	results.get(opponent.ID).LastChallenged = now
+	results.save()
+	l.matchmaker.pendingUser, l.matchmaker.pendingSince = opponent.ID, now
+	l.matchmaker.sending = true
+	l.Mutex.Unlock()
+
+	id, err := l.ChallengeUser(&ChallengeConfig{
+		Username:      opponent.Username,
+		ClockLimitSec: tc.InitialSec,
+		ClockIncSec:   tc.IncrementSec,
+		Rated:         cfg.Rated,
+	})
+
+	l.Mutex.Lock()
+	l.matchmaker.sending = false
+	declined := err == nil && l.matchmaker.sendingDeclined == id
+	l.matchmaker.sendingDeclined = ""
+	cancel := false
+	switch {
+	case err != nil:
+		log.Errorf("matchmaking: failed to challenge %s: %s", opponent.Username, err)
+		if errors.Is(err, errRateLimited) {
+			l.matchmaker.nextChallenge = now.Add(rateLimitBackoff)
+		}
+		l.matchmaker.pendingUser = ""
+	case l.shutdown.started:
+		// Shutdown has passed the pending challenge by
+		l.matchmaker.pendingUser = ""
+		cancel = true
+	case declined:
+		log.Infof("%s declined our challenge %s", opponent.Username, id)
+		l.recordDeclineLocked()
+	case l.GameID == id:
+		// Accepted already
+		l.matchmaker.pendingUser = ""
+	default:
+		l.matchmaker.pendingID, l.matchmaker.pendingSince = id, time.Now()
+	}
+	l.Mutex.Unlock()
+	if cancel {
+		if err := l.CancelChallenge(id); err != nil {
+			log.Errorf("failed to cancel challenge %s: %s", id, err)
+		}
+	}
+}
+
+// unansweredChallengeLocked returns our pending challenge once it has gone
+// unanswered for the challenge timeout, recording it as declined, or "". The
+// caller cancels it. Must be called with the mutex held.
+func (l *Lichess) unansweredChallengeLocked(cfg *game_config.Matchmaking) string {
+	id := l.matchmaker.pendingID
+	if id == "" || time.Since(l.matchmaker.pendingSince) < time.Duration(cfg.ChallengeTimeoutSec)*time.Second {
+		return ""
+	}
+	log.Infof("matchmaking: %s did not answer challenge %s, cancelling", l.matchmaker.pendingUser, id)
+	l.recordDeclineLocked()
+	return id
+}
+
+// matchmakeReadyLocked reports whether a challenge may go out now: none of
+// ours is pending or being sent, and the bot is idle. Must be called with the
+// mutex held.
+func (l *Lichess) matchmakeReadyLocked(cfg *game_config.Matchmaking) bool {
+	now := time.Now()
+	return l.matchmaker.pendingUser == "" &&
+		l.Game == nil &&
+		!l.shutdown.started &&
+		len(l.challengeQueue) == 0 &&
+		len(cfg.TimeControls) > 0 &&
+		!now.Before(l.matchmaker.nextChallenge) &&
+		now.Sub(l.matchmaker.idleSince) >= time.Duration(cfg.IdleSec)*time.Second
+}
+
+func (l *Lichess) deferMatchmaking(err error, cfg *game_config.Matchmaking) {
+	wait := time.Duration(cfg.MinChallengeIntervalSec) * time.Second
+	if errors.Is(err, errRateLimited) {
+		wait = rateLimitBackoff
+	}
+	l.Mutex.Lock()
+	l.matchmaker.nextChallenge = time.Now().Add(wait)
+	l.Mutex.Unlock()
+}
+
+// pickOpponent returns the online bot to challenge, or nil. Candidates are
+// rated within cfg.RatingWindow of rating in speed and out of cooldown; those
+// that declined us least, then those we have played least, then the closest
+// rated come first.
+func pickOpponent(bots []Account, results *opponentResults, cfg *game_config.Matchmaking, blocked []string, selfID, speed string, rating int, now time.Time) *Account {
+	cooldown := time.Duration(cfg.CooldownMin) * time.Minute
+	var candidates []Account
+	for _, bot := range bots {
+		if strings.EqualFold(bot.ID, selfID) || containsUser(blocked, bot.ID) {
+			continue
+		}
+		perf, ok := bot.Perfs[speed]
+		if !ok || perf.Provisional || abs(perf.Rating-rating) > cfg.RatingWindow {
+			continue
+		}
+		if record, ok := results.Opponents[strings.ToLower(bot.ID)]; ok &&
+			(now.Sub(record.LastChallenged) < cooldown || now.Sub(record.LastPlayed) < cooldown) {
+			continue
+		}
+		candidates = append(candidates, bot)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	record := func(a Account) opponentRecord {
+		if r, ok := results.Opponents[strings.ToLower(a.ID)]; ok {
+			return *r
+		}
+		return opponentRecord{}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ri, rj := record(candidates[i]), record(candidates[j])
+		if ri.Declines != rj.Declines {
+			return ri.Declines < rj.Declines
+		}
+		if ri.games() != rj.games() {
+			return ri.games() < rj.games()
+		}
+		return abs(candidates[i].Perfs[speed].Rating-rating) < abs(candidates[j].Perfs[speed].Rating-rating)
+	})
+	return &candidates[0]
+}
+
+// speedCategory maps a clock to its Lichess rating category, by the
+// estimated game duration initial + 40 × increment.
+func speedCategory(tc game_config.TimeControl) string {
+	switch estimate := tc.InitialSec + 40*tc.IncrementSec; {
+	case estimate < 30:
+		return "ultraBullet"
+	case estimate < 180:
+		return "bullet"
+	case estimate < 480:
+		return "blitz"
+	case estimate < 1500:
+		return "rapid"
+	default:
+		return "classical"
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// FetchOnlineBots returns up to nb bots that are online now.
+func (l *Lichess) FetchOnlineBots(nb int) ([]Account, error) {
+	r, err := l.Client.newRequest("GET", fmt.Sprintf("/api/bot/online?nb=%d", nb), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := l.Client.HttpClient.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, errRateLimited
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("online bots request failed: %s", resp.Status)
+	}
+	var bots []Account
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var bot Account
+		if err := json.Unmarshal(scanner.Bytes(), &bot); err != nil {
+			log.Errorf("failed to unmarshal online bot %s", scanner.Text())
+			continue
+		}
+		bots = append(bots, bot)
+	}
+	return bots, scanner.Err()
+}
+
+func (l *Lichess) CancelChallenge(challengeID string) error {
+	return l.post(fmt.Sprintf("/api/challenge/%s/cancel", challengeID))
+}
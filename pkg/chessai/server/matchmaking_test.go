@@ -0,0 +1,119 @@
+package server
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/server/lichesstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func onlineBot(id string, rating int, provisional bool) Account {
+	return Account{ID: id, Username: id, Title: "BOT", Perfs: map[string]Perf{"blitz": {Rating: rating, Games: 50, Provisional: provisional}}}
+}
+
+func TestPickOpponent(t *testing.T) {
+	cfg := game_config.DefaultMatchmaking()
+	now := time.Now()
+	results := &opponentResults{Opponents: map[string]*opponentRecord{
+		"recent":   {Wins: 1, LastPlayed: now.Add(-time.Minute)},
+		"played":   {Wins: 2, LastPlayed: now.Add(-24 * time.Hour)},
+		"declines": {Declines: 3, LastChallenged: now.Add(-24 * time.Hour)},
+	}}
+	bots := []Account{
+		onlineBot("us", 1500, false),
+		onlineBot("far", 1900, false),
+		onlineBot("new", 1500, true),
+		onlineBot("blocked", 1500, false),
+		onlineBot("recent", 1500, false),
+		onlineBot("declines", 1500, false),
+		onlineBot("played", 1510, false),
+		onlineBot("fresh", 1700, false),
+	}
+	pick := func() string {
+		if opponent := pickOpponent(bots, results, cfg, []string{"Blocked"}, "us", "blitz", 1500, now); opponent != nil {
+			return opponent.ID
+		}
+		return ""
+	}
+	// Unplayed opponents come before closer-rated ones we have already played.
+	assert.Equal(t, "fresh", pick())
+	results.get("fresh").LastChallenged = now
+	assert.Equal(t, "played", pick())
+	results.get("played").LastChallenged = now
+	// An opponent that keeps declining is only tried when nobody else is left.
+	assert.Equal(t, "declines", pick())
+	results.get("declines").LastChallenged = now
+	assert.Equal(t, "", pick())
+
+	assert.Equal(t, "bullet", speedCategory(game_config.TimeControl{InitialSec: 60, IncrementSec: 1}))
+	assert.Equal(t, "blitz", speedCategory(game_config.TimeControl{InitialSec: 180, IncrementSec: 2}))
+	assert.Equal(t, "rapid", speedCategory(game_config.TimeControl{InitialSec: 600}))
+}
+
+// TestMatchmakesAgainstOnlineBots lets the idle bot challenge online bots on
+// the fake server: the closest-rated one declines, the next one is played,
+// and afterwards everyone is either in cooldown or out of the rating window.
+func TestMatchmakesAgainstOnlineBots(t *testing.T) {
+	fake := lichesstest.NewServer("TestBot")
+	fake.Token = "test-token"
+	fake.OnlineBots = []lichesstest.OnlineBot{
+		{Username: "TestBot", Rating: 1500},
+		{Username: "FarBot", Rating: 1800},
+		{Username: "DeclineBot", Rating: 1500},
+		{Username: "NearBot", Rating: 1550},
+	}
+	fake.DeclinesFrom = map[string]string{"DeclineBot": "later"}
+	fake.Opponent = lichesstest.ScriptedOpponent()
+	defer fake.Close()
+	t.Setenv("LICHESS_TOKEN", fake.Token)
+
+	resultsFile := filepath.Join(t.TempDir(), "results.json")
+	bot := ConnectLichess(fake.URL).(*Lichess)
+	bot.Matchmaking = &game_config.Matchmaking{
+		Enabled:             true,
+		TimeControls:        []game_config.TimeControl{{InitialSec: 60}},
+		Rated:               true,
+		RatingWindow:        100,
+		CooldownMin:         60,
+		ChallengeTimeoutSec: 30,
+		ResultsFile:         resultsFile,
+	}
+	bot.matchmaker.tick = 20 * time.Millisecond
+	done := runUntilDone(bot)
+	defer stopBot(t, bot, done)
+
+	deadline := time.Now().Add(30 * time.Second)
+	for len(fake.Outgoing()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	outgoing := fake.Outgoing()
+	if !assert.Len(t, outgoing, 2) {
+		return
+	}
+	assert.Equal(t, "DeclineBot", outgoing[0].Options.Challenger)
+	assert.Equal(t, "later", outgoing[0].Declined)
+	assert.Equal(t, "NearBot", outgoing[1].Options.Challenger)
+	assert.True(t, outgoing[1].Options.Rated)
+	assert.Equal(t, time.Minute, outgoing[1].Options.Initial)
+
+	result, err := fake.WaitGame(outgoing[1].ID, 30*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, lichesstest.StatusResign, result.Status)
+	for time.Now().Before(deadline) {
+		if record := loadOpponentResults(resultsFile).Opponents["nearbot"]; record != nil && record.Wins == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	results := loadOpponentResults(resultsFile)
+	if assert.Contains(t, results.Opponents, "nearbot") && assert.Contains(t, results.Opponents, "declinebot") {
+		assert.Equal(t, 1, results.Opponents["nearbot"].Wins)
+		assert.Equal(t, 1, results.Opponents["declinebot"].Declines)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Len(t, fake.Outgoing(), 2, "remaining bots are in cooldown or outside the rating window")
+}
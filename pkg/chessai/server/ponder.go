@@ -0,0 +1,118 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/analysis"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/board"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/location"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/player/ai"
+	log "github.com/sirupsen/logrus"
+)
+
+// ponderTimeCap bounds a ponder search while the opponent thinks.
+const ponderTimeCap = 60 * time.Second
+
+// predictedPonder is the search of our answer to move, the opponent reply our
+// last search predicted when the game was at ply. result is set once the
+// search is done (ponderDone is closed).
+type predictedPonder struct {
+	player  *ai.AIPlayer
+	move    location.Move
+	ply     int
+	started time.Time
+	result  *location.Move
+}
+
+// ponderStats counts the opponent moves we had a prediction for and how many
+// of them we predicted right.
+type ponderStats struct {
+	predicted int
+	hits      int
+}
+
+func (s ponderStats) String() string {
+	if s.predicted == 0 {
+		return "0/0"
+	}
+	return fmt.Sprintf("%d/%d (%d%%)", s.hits, s.predicted, 100*s.hits/s.predicted)
+}
+
+// startPredictedPonder ponders our answer to the second move of our last
+// search's PV, so that when the opponent plays it the search goes on instead
+// of starting over. It reports false, leaving the caller to warm the TT
+// instead, when there is no usable prediction: after a book move, or when the
+// predicted reply is illegal or ends the game. Must be called with the mutex
+// held and no ponder running.
+func (l *Lichess) startPredictedPonder() bool {
+	opponent := l.Player.PlayerColor ^ 1
+	if len(l.Player.LastLine) < 2 || l.Game.CurrentTurnColor != opponent {
+		return false
+	}
+	b := l.Game.CurrentBoard.Copy()
+	move, err := analysis.MatchUCIMove(b, opponent, l.Game.PreviousMove, l.Player.LastLine[1].UCIString())
+	if err != nil {
+		return false
+	}
+	previousMove := board.MakeMove(&move, b)
+	if len(*b.GetAllMoves(l.Player.PlayerColor, previousMove)) == 0 {
+		return false
+	}
+	ponderPlayer := l.Player.NewPonderPlayer(l.Player.PlayerColor)
+	ponderPlayer.TurnCount, ponderPlayer.Opening = l.Player.TurnCount, l.Player.Opening
+	ponderPlayer.MaxThinkTime = ponderTimeCap
+	ponderPlayer.StartPondering()
+	pp := &predictedPonder{player: ponderPlayer, move: move, ply: l.movesApplied, started: time.Now()}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	l.ponderStop, l.ponderDone, l.predicted = stop, done, pp
+	go func() {
+		defer close(done)
+		go func() {
+			select {
+			case <-stop:
+				ponderPlayer.Abort()
+			case <-done:
+			}
+		}()
+		pp.result = ponderPlayer.GetBestMove(b, previousMove, nil)
+	}()
+	log.Debugf("pondering on predicted reply %s", move.UCIString())
+	return true
+}
+
+// ponderHitLocked reports the running predicted ponder if the opponent's
+// move m, which makes the game ply plies long, is the one it predicted, and
+// keeps the hit rate. Must be called with the mutex held.
+func (l *Lichess) ponderHitLocked(ply int, m *location.Move) *predictedPonder {
+	pp := l.predicted
+	if pp == nil {
+		return nil
+	}
+	l.ponderStats.predicted++
+	if ply != pp.ply+1 || !m.Start.Equals(pp.move.Start) || !m.End.Equals(pp.move.End) {
+		log.Infof("ponder miss: predicted %s, ponder hits %s", pp.move.UCIString(), l.ponderStats)
+		return nil
+	}
+	l.ponderStats.hits++
+	log.Infof("ponder hit on %s after %s pondering, ponder hits %s",
+		pp.move.UCIString(), time.Since(pp.started).Round(time.Millisecond), l.ponderStats)
+	return pp
+}
+
+// playPonderHitLocked lets the ponder search for pp, which the opponent's
+// move just confirmed, finish within l.Player.MaxThinkTime and plays its
+// move as if l.Player had searched it. Must be called with the mutex held.
+func (l *Lichess) playPonderHitLocked(pp *predictedPonder) {
+	pp.player.PonderHit(l.Player.MaxThinkTime)
+	<-l.ponderDone
+	l.stopPonder()
+	if pp.result == nil {
+		l.Game.PlayTurn()
+		return
+	}
+	l.Player.AdoptSearch(pp.player)
+	l.Game.PlayAdoptedMove(pp.result)
+}
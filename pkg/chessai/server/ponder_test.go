@@ -0,0 +1,106 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/server/lichesstest"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPondersPredictedReply answers the bot's predicted reply whenever it has
+// one, so every prediction is a ponder hit, then deviates once for a miss.
+func TestPondersPredictedReply(t *testing.T) {
+	fake := lichesstest.NewServer("TestBot")
+	fake.Token = "test-token"
+	defer fake.Close()
+	t.Setenv("LICHESS_TOKEN", fake.Token)
+
+	bot := ConnectLichess(fake.URL).(*Lichess)
+	policy := game_config.DefaultChallengePolicy()
+	policy.Variants = []string{"fromPosition"}
+	bot.Policy = policy
+	done := runUntilDone(bot)
+	defer stopBot(t, bot, done)
+
+	id := fake.Challenge(lichesstest.ChallengeOptions{
+		Rated:      true,
+		Variant:    "fromPosition",
+		Initial:    time.Minute,
+		BotColor:   color.White,
+		InitialFEN: "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+	})
+	waitForMoves := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(30 * time.Second)
+		for {
+			if r, _ := fake.Game(id); len(r.Moves) >= n {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %d moves", n)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	// reply is the predicted reply when the bot ponders one, else (or with
+	// miss) any other legal move.
+	reply := func(miss bool) (string, bool) {
+		bot.Mutex.Lock()
+		defer bot.Mutex.Unlock()
+		var predicted string
+		if bot.predicted != nil {
+			predicted = bot.predicted.move.UCIString()
+			if !miss {
+				return predicted, true
+			}
+		}
+		for _, m := range *bot.Game.CurrentBoard.GetAllMoves(color.Black, bot.Game.PreviousMove) {
+			if m.UCIString() != predicted {
+				return m.UCIString(), false
+			}
+		}
+		t.Fatal("no legal reply")
+		return "", false
+	}
+
+	hits := 0
+	// hitMoves are the indices in the game's history of the bot's moves
+	// played from a ponder hit.
+	var hitMoves []int
+	for ply := 1; hits < 2 && ply < 16; ply += 2 {
+		waitForMoves(ply)
+		uci, predicted := reply(false)
+		if predicted {
+			hits++
+			hitMoves = append(hitMoves, ply+1)
+		}
+		assert.NoError(t, fake.PlayOpponentMove(id, uci))
+	}
+	waitForMoves(len(mustGame(t, fake, id).Moves) + 1)
+	uci, _ := reply(true)
+	assert.NoError(t, fake.PlayOpponentMove(id, uci))
+	waitForMoves(len(mustGame(t, fake, id).Moves) + 1)
+
+	r := mustGame(t, fake, id)
+	assert.Equal(t, lichesstest.StatusStarted, r.Status)
+	assert.Zero(t, r.RejectedMoves)
+	bot.Mutex.Lock()
+	defer bot.Mutex.Unlock()
+	assert.Equal(t, 2, hits)
+	assert.Equal(t, ponderStats{predicted: 3, hits: 2}, bot.ponderStats)
+	for _, i := range hitMoves {
+		assert.NotNil(t, bot.Game.History[i].Eval, "move %s played from a ponder hit has no search recorded", bot.Game.History[i].UCI)
+	}
+}
+
+func mustGame(t *testing.T, fake *lichesstest.Server, id string) lichesstest.GameResult {
+	t.Helper()
+	r, ok := fake.Game(id)
+	if !ok {
+		t.Fatalf("no game %s", id)
+	}
+	return r
+}
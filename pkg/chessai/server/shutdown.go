@@ -0,0 +1,72 @@
+package server
+
+import (
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	log "github.com/sirupsen/logrus"
+)
+
+// shutdownState is the state of a graceful shutdown. It is guarded by the
+// Lichess mutex.
+type shutdownState struct {
+	// started is set by Shutdown; from then on no new game is taken on.
+	started bool
+	// grace overrides game_config's ShutdownGraceSec when nonzero.
+	grace time.Duration
+	// deadline resigns the game still in progress when the grace period ends.
+	deadline *time.Timer
+}
+
+// Shutdown stops the bot gracefully, as for a deploy: challenges are declined
+// with "later", matchmaking stops, a tournament is withdrawn from, and Run
+// returns once the game in progress has ended. A game still going when the
+// grace period (ShutdownGraceSec) ends is resigned. Calls after the first do
+// nothing.
+func (l *Lichess) Shutdown() {
+	l.Mutex.Lock()
+	defer l.Mutex.Unlock()
+	if l.shutdown.started {
+		return
+	}
+	l.shutdown.started = true
+	for _, id := range l.challengeQueue {
+		log.Infof("declining queued challenge %s: shutting down", id)
+		if err := l.DeclineChallenge(id, DeclineLater); err != nil {
+			log.Errorf("failed to decline challenge %s: %s", id, err)
+		}
+	}
+	l.challengeQueue = nil
+	if id := l.matchmaker.pendingID; id != "" {
+		if err := l.CancelChallenge(id); err != nil {
+			log.Errorf("failed to cancel challenge %s: %s", id, err)
+		}
+		l.matchmaker.pendingID, l.matchmaker.pendingUser = "", ""
+	}
+	l.leaveTournamentLocked()
+	if l.Game == nil {
+		log.Infof("shutting down: no game in progress")
+		l.exitLocked()
+		return
+	}
+	grace := l.shutdown.grace
+	if grace == 0 {
+		grace = time.Duration(game_config.Get().ShutdownGraceSec) * time.Second
+	}
+	log.Infof("shutting down: finishing game %s, resigning it in %s", l.GameID, grace)
+	gameID := l.GameID
+	l.shutdown.deadline = time.AfterFunc(grace, func() { l.graceExpired(gameID) })
+}
+
+// graceExpired resigns gameID if it is still in progress and stops Run.
+func (l *Lichess) graceExpired(gameID string) {
+	l.Mutex.Lock()
+	defer l.Mutex.Unlock()
+	if l.Game != nil && l.GameID == gameID {
+		log.Warnf("shutdown grace period over, resigning game %s", gameID)
+		if err := l.postGameAction(gameID, "resign"); err != nil {
+			log.Errorf("failed to resign game %s: %s", gameID, err)
+		}
+	}
+	l.exitLocked()
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/color"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/server/lichesstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func waitExit(t *testing.T, done <-chan struct{}, timeout time.Duration) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Fatal("Run did not return after Shutdown")
+	}
+}
+
+func waitBotMoves(t *testing.T, fake *lichesstest.Server, id string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		if r, _ := fake.Game(id); len(r.Moves) >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d moves", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestShutdownWhenIdle(t *testing.T) {
+	fake := lichesstest.NewServer("TestBot")
+	fake.Token = "test-token"
+	defer fake.Close()
+	t.Setenv("LICHESS_TOKEN", fake.Token)
+
+	bot := ConnectLichess(fake.URL).(*Lichess)
+	done := runUntilDone(bot)
+	bot.Shutdown()
+	waitExit(t, done, 5*time.Second)
+}
+
+// TestShutdownFinishesGame shuts down mid-game: the bot declines new
+// challenges, plays the game out and only then stops.
+func TestShutdownFinishesGame(t *testing.T) {
+	fake := lichesstest.NewServer("TestBot")
+	fake.Token = "test-token"
+	defer fake.Close()
+	t.Setenv("LICHESS_TOKEN", fake.Token)
+
+	bot := ConnectLichess(fake.URL).(*Lichess)
+	done := runUntilDone(bot)
+	id := fake.Challenge(lichesstest.ChallengeOptions{Rated: true, Initial: time.Minute, BotColor: color.White})
+	waitBotMoves(t, fake, id, 1)
+
+	bot.Shutdown()
+	late := fake.Challenge(lichesstest.ChallengeOptions{Challenger: "Late", Rated: true})
+	assert.NoError(t, fake.PlayOpponentMove(id, "e7e5"))
+	waitBotMoves(t, fake, id, 3)
+	select {
+	case <-done:
+		t.Fatal("Run returned with the game still in progress")
+	default:
+	}
+	assert.NoError(t, fake.OpponentResigns(id))
+	waitExit(t, done, 5*time.Second)
+
+	result, _ := fake.Game(id)
+	assert.Equal(t, lichesstest.StatusResign, result.Status)
+	assert.Equal(t, "white", result.Winner)
+	assert.Zero(t, result.RejectedMoves)
+	reason, _ := fake.DeclineReason(late)
+	assert.Equal(t, DeclineLater, reason)
+}
+
+// TestShutdownResignsAfterGrace resigns a game that outlasts the grace
+// period.
+func TestShutdownResignsAfterGrace(t *testing.T) {
+	fake := lichesstest.NewServer("TestBot")
+	fake.Token = "test-token"
+	defer fake.Close()
+	t.Setenv("LICHESS_TOKEN", fake.Token)
+
+	bot := ConnectLichess(fake.URL).(*Lichess)
+	bot.shutdown.grace = 200 * time.Millisecond
+	done := runUntilDone(bot)
+	id := fake.Challenge(lichesstest.ChallengeOptions{Rated: true, Initial: time.Minute, BotColor: color.White})
+	waitBotMoves(t, fake, id, 1)
+
+	start := time.Now()
+	bot.Shutdown()
+	waitExit(t, done, 5*time.Second)
+	assert.True(t, time.Since(start) >= bot.shutdown.grace, "resigned before the grace period ended")
+
+	result, _ := fake.Game(id)
+	assert.Equal(t, lichesstest.StatusResign, result.Status)
+	assert.Equal(t, "black", result.Winner)
+	bot.Mutex.Lock()
+	defer bot.Mutex.Unlock()
+	assert.Nil(t, bot.Game, "the resigned game should be torn down")
+	assert.Nil(t, bot.ponderStop, "pondering should have stopped")
+}
@@ -0,0 +1,313 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultTournamentPoll = 10 * time.Second
+	// sourceArena is Game.Source for an arena pairing.
+	sourceArena = "arena"
+)
+
+// TournamentClock is an event's clock, in seconds.
+type TournamentClock struct {
+	Limit     int `json:"limit"`
+	Increment int `json:"increment"`
+}
+
+// String formats the clock the way Lichess does, e.g. "3+2".
+func (c TournamentClock) String() string {
+	return fmt.Sprintf("%g+%d", float64(c.Limit)/60, c.Increment)
+}
+
+// arenaInfo is the subset of /api/tournament/{id} the bot needs. Me is only
+// present once we have joined; Withdraw is set while Lichess has us paused.
+type arenaInfo struct {
+	ID          string          `json:"id"`
+	FullName    string          `json:"fullName"`
+	Clock       TournamentClock `json:"clock"`
+	Berserkable bool            `json:"berserkable"`
+	IsStarted   bool            `json:"isStarted"`
+	IsFinished  bool            `json:"isFinished"`
+	Me          *struct {
+		Withdraw bool `json:"withdraw"`
+	} `json:"me"`
+}
+
+// swissInfo is the subset of /api/swiss/{id} the bot needs.
+type swissInfo struct {
+	ID     string          `json:"id"`
+	Name   string          `json:"name"`
+	Clock  TournamentClock `json:"clock"`
+	Status string          `json:"status"` // "created", "started" or "finished"
+}
+
+// arenaSummary is an arena in the /api/tournament listing.
+type arenaSummary struct {
+	ID          string           `json:"id"`
+	FullName    string           `json:"fullName"`
+	Clock       TournamentClock  `json:"clock"`
+	Variant     ChallengeVariant `json:"variant"`
+	Rated       bool             `json:"rated"`
+	BotsAllowed bool             `json:"botsAllowed"`
+	StartsAt    int64            `json:"startsAt"` // Unix milliseconds
+}
+
+// tournamentInfo is an arena or Swiss as tournament mode tracks it.
+type tournamentInfo struct {
+	ID          string
+	Name        string
+	Swiss       bool
+	Clock       TournamentClock
+	Berserkable bool
+	Started     bool
+	Finished    bool
+	// Joined reports whether we are in an arena's pairing pool; Lichess
+	// pauses players, e.g. after missing a game's start. Lichess does not
+	// report it for a Swiss.
+	Joined bool
+}
+
+func (t *tournamentInfo) kind() string {
+	if t.Swiss {
+		return "swiss"
+	}
+	return "arena"
+}
+
+// tournamentState is the state of tournament mode. It is guarded by the
+// Lichess mutex.
+type tournamentState struct {
+	// poll is how often the event is checked; 0 uses the config's PollSec.
+	poll time.Duration
+	// id and swiss select the event; id is "" until a bot arena is found.
+	id    string
+	swiss bool
+	// info is the event as last fetched, nil before the first fetch.
+	info *tournamentInfo
+	// joinedSwiss is set once we have joined the Swiss.
+	joinedSwiss bool
+	// leaving is set once the event has finished or we withdrew on shutdown;
+	// no game is started after the current one.
+	leaving bool
+}
+
+// runTournament joins the configured event and keeps the bot in it until the
+// event finishes or the bot leaves. It returns when the bot is leaving or
+// exits.
+func (l *Lichess) runTournament() {
+	cfg := l.Tournament
+	l.Mutex.Lock()
+	l.tournament.id, l.tournament.swiss = cfg.ArenaID, false
+	if cfg.SwissID != "" {
+		l.tournament.id, l.tournament.swiss = cfg.SwissID, true
+	}
+	poll := l.tournament.poll
+	l.Mutex.Unlock()
+	if poll == 0 {
+		poll = time.Duration(cfg.PollSec) * time.Second
+	}
+	if poll <= 0 {
+		poll = defaultTournamentPoll
+	}
+	for !l.tournamentTick() {
+		select {
+		case <-time.After(poll):
+		case <-l.exit:
+			return
+		}
+	}
+}
+
+// tournamentTick refreshes the event, (re)joining it when we are out of its
+// pairings, and reports whether the bot is leaving it.
+func (l *Lichess) tournamentTick() bool {
+	l.Mutex.Lock()
+	id, swiss, leaving := l.tournament.id, l.tournament.swiss, l.tournament.leaving
+	l.Mutex.Unlock()
+	if leaving {
+		return true
+	}
+	if id == "" {
+		arena, err := l.findBotArena(l.challengePolicy())
+		if err != nil {
+			log.Errorf("failed to list tournaments: %s", err)
+			return false
+		}
+		if arena == nil {
+			log.Debugf("no upcoming bot arena matches the challenge policy")
+			return false
+		}
+		log.Infof("found bot arena %s %q (%s)", arena.ID, arena.FullName, arena.Clock)
+		id = arena.ID
+		l.Mutex.Lock()
+		l.tournament.id = id
+		l.Mutex.Unlock()
+	}
+
+	info, err := l.fetchTournament(id, swiss)
+	if err != nil {
+		log.Errorf("failed to fetch %s: %s", id, err)
+		return false
+	}
+	l.Mutex.Lock()
+	defer l.Mutex.Unlock()
+	if l.tournament.leaving {
+		return true
+	}
+	l.tournament.info = info
+	if info.Finished {
+		log.Infof("%s %s %q has finished", info.kind(), info.ID, info.Name)
+		l.tournament.leaving = true
+		if l.Game == nil {
+			l.exitLocked()
+		}
+		return true
+	}
+	if info.Joined || (info.Swiss && l.tournament.joinedSwiss) {
+		return false
+	}
+	log.Infof("joining %s %s %q (%s, started: %t)", info.kind(), info.ID, info.Name, info.Clock, info.Started)
+	if err := l.post(fmt.Sprintf("/api/%s/%s/join", tournamentPath(info.Swiss), info.ID)); err != nil {
+		log.Errorf("failed to join %s %s: %s", info.kind(), info.ID, err)
+		return false
+	}
+	l.tournament.joinedSwiss = info.Swiss
+	return false
+}
+
+// leaveTournamentLocked withdraws from the event so that Lichess pairs the
+// bot no more. Must be called with the mutex held.
+func (l *Lichess) leaveTournamentLocked() {
+	if l.Tournament == nil || l.tournament.leaving {
+		return
+	}
+	l.tournament.leaving = true
+	if info := l.tournament.info; info != nil && !info.Finished {
+		log.Infof("withdrawing from %s %s", info.kind(), info.ID)
+		if err := l.post(fmt.Sprintf("/api/%s/%s/withdraw", tournamentPath(info.Swiss), info.ID)); err != nil {
+			log.Errorf("failed to withdraw from %s %s: %s", info.kind(), info.ID, err)
+		}
+	}
+}
+
+// berserkLocked berserks a fresh arena game when the tournament config says
+// so, halving our clock in g. Must be called with the mutex held.
+func (l *Lichess) berserkLocked(g *Game) {
+	if l.Tournament == nil || !shouldBerserk(l.Tournament, l.tournament.info, g) {
+		return
+	}
+	if err := l.postGameAction(g.GameID, "berserk"); err != nil {
+		log.Errorf("failed to berserk game %s: %s", g.GameID, err)
+		return
+	}
+	log.Infof("berserked game %s", g.GameID)
+	g.SecondsLeft /= 2
+}
+
+// shouldBerserk reports whether to berserk g, a game in the arena info,
+// before our first move.
+func shouldBerserk(cfg *game_config.Tournament, info *tournamentInfo, g *Game) bool {
+	return cfg.Berserk && info != nil && !info.Swiss && info.Berserkable &&
+		g.Source == sourceArena && !g.HasMoved &&
+		info.Clock.Limit >= cfg.BerserkMinInitialSec &&
+		info.Clock.Increment <= cfg.BerserkMaxIncrementSec
+}
+
+// findBotArena returns the arena open to bots to join next, or nil: one
+// already started, else the soonest to start. Its clock, variant and
+// ratedness must be acceptable under policy.
+func (l *Lichess) findBotArena(policy *game_config.ChallengePolicy) (*arenaSummary, error) {
+	r, err := l.Client.newRequest("GET", "/api/tournament", nil)
+	if err != nil {
+		return nil, err
+	}
+	var listing struct {
+		Created []arenaSummary `json:"created"`
+		Started []arenaSummary `json:"started"`
+	}
+	resp, err := l.Client.do(r, &listing)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tournament list request failed: %s", resp.Status)
+	}
+	sort.SliceStable(listing.Created, func(i, j int) bool {
+		return listing.Created[i].StartsAt < listing.Created[j].StartsAt
+	})
+	for _, arenas := range [][]arenaSummary{listing.Started, listing.Created} {
+		for i := range arenas {
+			a := &arenas[i]
+			variant := a.Variant.Key
+			if variant == "" {
+				variant = variantStandard
+			}
+			tc := &ChallengeTimeControl{Type: timeControlClock, Limit: a.Clock.Limit, Increment: a.Clock.Increment}
+			if a.BotsAllowed && evaluateTerms(policy, variant, tc, a.Rated) == "" {
+				return a, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// fetchTournament returns the arena or Swiss id.
+func (l *Lichess) fetchTournament(id string, swiss bool) (*tournamentInfo, error) {
+	r, err := l.Client.newRequest("GET", fmt.Sprintf("/api/%s/%s", tournamentPath(swiss), id), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := l.Client.HttpClient.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tournament request failed: %s", resp.Status)
+	}
+	decoder := json.NewDecoder(resp.Body)
+	if swiss {
+		var s swissInfo
+		if err := decoder.Decode(&s); err != nil {
+			return nil, err
+		}
+		return &tournamentInfo{
+			ID:       s.ID,
+			Name:     s.Name,
+			Swiss:    true,
+			Clock:    s.Clock,
+			Started:  s.Status == "started",
+			Finished: s.Status == "finished",
+		}, nil
+	}
+	var a arenaInfo
+	if err := decoder.Decode(&a); err != nil {
+		return nil, err
+	}
+	return &tournamentInfo{
+		ID:          a.ID,
+		Name:        a.FullName,
+		Clock:       a.Clock,
+		Berserkable: a.Berserkable,
+		Started:     a.IsStarted,
+		Finished:    a.IsFinished,
+		Joined:      a.Me != nil && !a.Me.Withdraw,
+	}, nil
+}
+
+// tournamentPath is the API path segment for arenas or Swiss events.
+func tournamentPath(swiss bool) string {
+	if swiss {
+		return "swiss"
+	}
+	return "tournament"
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/game_config"
+	"github.com/Vadman97/GolangChessAI/pkg/chessai/server/lichesstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldBerserk(t *testing.T) {
+	cfg := game_config.DefaultTournament()
+	cfg.Berserk = true
+	arena := &tournamentInfo{Clock: TournamentClock{Limit: 180, Increment: 2}, Berserkable: true}
+	fresh := &Game{Source: sourceArena}
+	assert.True(t, shouldBerserk(cfg, arena, fresh))
+
+	assert.False(t, shouldBerserk(cfg, nil, fresh), "no tournament")
+	assert.False(t, shouldBerserk(cfg, arena, &Game{Source: sourceArena, HasMoved: true}), "already moved")
+	assert.False(t, shouldBerserk(cfg, arena, &Game{Source: "friend"}), "not an arena game")
+	assert.False(t, shouldBerserk(cfg, &tournamentInfo{Clock: arena.Clock}, fresh), "not berserkable")
+	assert.False(t, shouldBerserk(cfg, &tournamentInfo{Clock: TournamentClock{Limit: 60}, Berserkable: true}, fresh), "too fast")
+	assert.False(t, shouldBerserk(cfg, &tournamentInfo{Clock: TournamentClock{Limit: 300, Increment: 3}, Berserkable: true}, fresh), "increment too large")
+	cfg.Berserk = false
+	assert.False(t, shouldBerserk(cfg, arena, fresh), "disabled")
+}
+
+// runUntilDone runs the bot and returns a channel closed when Run returns.
+func runUntilDone(bot *Lichess) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		bot.Run()
+		close(done)
+	}()
+	return done
+}
+
+func waitTournament(t *testing.T, fake *lichesstest.Server, id string, what string, cond func(r lichesstest.TournamentResult) bool) lichesstest.TournamentResult {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		r, _ := fake.TournamentState(id)
+		if cond(r) {
+			return r
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s: %+v", what, r)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestPlaysBotArenaUntilItEnds finds the bot arena, joins it before it
+// starts, plays (and berserks) its pairings, rejoins after Lichess pauses it
+// and exits once the arena is over.
+func TestPlaysBotArenaUntilItEnds(t *testing.T) {
+	fake := lichesstest.NewServer("TestBot")
+	fake.Token = "test-token"
+	fake.Opponent = lichesstest.ScriptedOpponent()
+	fake.OpponentDelay = 50 * time.Millisecond
+	defer fake.Close()
+	t.Setenv("LICHESS_TOKEN", fake.Token)
+	fake.AddTournament(lichesstest.Tournament{ID: "humans", Name: "Humans Only Arena", Rated: true, Initial: 3 * time.Minute})
+	fake.AddTournament(lichesstest.Tournament{ID: "bullet", Name: "Casual Bot Arena", BotsAllowed: true, Initial: time.Minute})
+	fake.AddTournament(lichesstest.Tournament{ID: "arena", Name: "Bot Arena", BotsAllowed: true, Berserkable: true, Rated: true, Initial: 3 * time.Minute})
+
+	cfg := game_config.DefaultTournament()
+	cfg.Berserk = true
+	bot := ConnectLichessForTournament(fake.URL, cfg).(*Lichess)
+	bot.tournament.poll = 20 * time.Millisecond
+	done := runUntilDone(bot)
+
+	waitTournament(t, fake, "arena", "join", func(r lichesstest.TournamentResult) bool { return r.Joined })
+	assert.NoError(t, fake.StartTournament("arena"))
+	waitTournament(t, fake, "arena", "two games", func(r lichesstest.TournamentResult) bool { return len(r.Games) >= 2 })
+	assert.NoError(t, fake.PauseTournamentPlayer("arena"))
+	r := waitTournament(t, fake, "arena", "rejoin", func(r lichesstest.TournamentResult) bool { return r.Joins == 2 })
+	played := len(r.Games)
+	waitTournament(t, fake, "arena", "a game after rejoining", func(r lichesstest.TournamentResult) bool { return len(r.Games) > played })
+	assert.NoError(t, fake.FinishTournament("arena"))
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("the bot did not exit after the arena finished")
+	}
+	r, _ = fake.TournamentState("arena")
+	assert.Zero(t, r.Withdrawals, "a finished arena needs no withdrawal")
+	assert.NotZero(t, r.Berserks)
+	for _, id := range r.Games {
+		result, err := fake.WaitGame(id, 30*time.Second)
+		assert.NoError(t, err)
+		assert.Zero(t, result.RejectedMoves, "game %s", id)
+	}
+	for _, other := range []string{"humans", "bullet"} {
+		r, _ := fake.TournamentState(other)
+		assert.Zero(t, r.Joins, "joined %s", other)
+	}
+}
+
+// TestWithdrawsFromSwissOnShutdown plays a Swiss by ID, declines challenges
+// meanwhile, and withdraws when shut down.
+func TestWithdrawsFromSwissOnShutdown(t *testing.T) {
+	fake := lichesstest.NewServer("TestBot")
+	fake.Token = "test-token"
+	fake.Opponent = lichesstest.ScriptedOpponent()
+	fake.OpponentDelay = 50 * time.Millisecond
+	defer fake.Close()
+	t.Setenv("LICHESS_TOKEN", fake.Token)
+	fake.AddTournament(lichesstest.Tournament{ID: "swiss", Name: "Bot Swiss", Swiss: true, Rated: true, Initial: 3 * time.Minute})
+	assert.NoError(t, fake.StartTournament("swiss"))
+
+	cfg := game_config.DefaultTournament()
+	cfg.SwissID = "swiss"
+	bot := ConnectLichessForTournament(fake.URL, cfg).(*Lichess)
+	bot.tournament.poll = 20 * time.Millisecond
+	done := runUntilDone(bot)
+
+	waitTournament(t, fake, "swiss", "a game", func(r lichesstest.TournamentResult) bool { return len(r.Games) >= 1 })
+	challenge := fake.Challenge(lichesstest.ChallengeOptions{Rated: true})
+	bot.Shutdown()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("the bot did not exit after shutting down")
+	}
+
+	r, _ := fake.TournamentState("swiss")
+	assert.Equal(t, 1, r.Joins)
+	assert.Equal(t, 1, r.Withdrawals)
+	assert.False(t, r.Joined)
+	last, err := fake.WaitGame(r.Games[len(r.Games)-1], 30*time.Second)
+	assert.NoError(t, err)
+	assert.NotEqual(t, lichesstest.StatusStarted, last.Status)
+	reason, _ := fake.DeclineReason(challenge)
+	assert.Equal(t, DeclineLater, reason)
+}
@@ -1,9 +1,41 @@
 #!/bin/bash
 cd /home/vadim/code/GolangChessAI
+# SIGTERM or SIGINT to this script stops the bot for good: it is passed on so
+# the bot finishes (or, after ShutdownGraceSec, resigns) its game first. A
+# second signal makes the bot quit at once.
+STOPPING=0
+trap 'STOPPING=1; kill -TERM "$PID" 2>/dev/null' TERM INT
 while true; do
   echo "$(date): starting bot..." >> /tmp/chess.lichess.log
-  LICHESS_TOKEN=${LICHESS_TOKEN} ./main lichess >> /tmp/chess.lichess.log 2>&1
-  EXIT_CODE=$?
-  echo "$(date): bot exited with code $EXIT_CODE, restarting in 30s..." >> /tmp/chess.lichess.log
-  sleep 30
+  STARTED=$(date +%s)
+  LICHESS_TOKEN=${LICHESS_TOKEN} ./main lichess >> /tmp/chess.lichess.log 2>&1 &
+  PID=$!
+  # A trapped signal interrupts wait while the bot is still finishing its game.
+  while true; do
+    wait "$PID"
+    EXIT_CODE=$?
+    kill -0 "$PID" 2>/dev/null || break
+  done
+  if [ "$STOPPING" = 1 ]; then
+    echo "$(date): bot stopped with code $EXIT_CODE" >> /tmp/chess.lichess.log
+    exit 0
+  fi
+  case $EXIT_CODE in
+    0|3)
+      # Shut down by a signal to the bot alone, as a deploy does after
+      # replacing ./main (exit 3: a second signal cut its game short). Start
+      # the new build at once.
+      echo "$(date): bot shut down with code $EXIT_CODE, restarting" >> /tmp/chess.lichess.log
+      continue
+      ;;
+  esac
+  # A crash. A game left running keeps its clock ticking until the new
+  # process resumes it from /api/account/playing, so restart promptly. Only a
+  # crash loop (exiting within a minute) waits longer, to spare the rate limit.
+  DELAY=3
+  if [ $(( $(date +%s) - STARTED )) -lt 60 ]; then
+    DELAY=30
+  fi
+  echo "$(date): bot exited with code $EXIT_CODE, restarting in ${DELAY}s..." >> /tmp/chess.lichess.log
+  sleep $DELAY
 done
@@ -5,6 +5,10 @@ class Game {
   moveLimit = 0;
   timeLimit = 0;
   movesPlayed = 0;
+  // clocks are the remaining milliseconds per color name at clocksReceived,
+  // null for untimed games
+  clocks = null;
+  clocksReceived = 0;
 
   constructor(humanColor, status, moveLimit, timeLimit) {
     this.humanColor = humanColor;
@@ -21,7 +25,16 @@ export const GameStatus = {
   Stalemate: 'Stalemate',
   FiftyMoveDraw: 'Fifty Move Draw',
   RepeatActionDraw: 'Repeated Action Three Times Draw',
+  InsufficientMaterialDraw: 'Insufficient Material Draw',
   Aborted: 'Aborted',
+  WhiteResigned: 'White Resigned',
+  BlackResigned: 'Black Resigned',
+  DrawAgreed: 'Draw Agreed',
+  WhiteTimeout: 'White Timeout',
+  BlackTimeout: 'Black Timeout',
+  FivefoldRepetitionDraw: 'Fivefold Repetition Draw',
+  SeventyFiveMoveDraw: 'Seventy-Five Move Draw',
+  DeadPositionDraw: 'Dead Position Draw',
 };
 
 export default Game;
@@ -2,7 +2,7 @@ import $ from 'jquery'
 import Popper from 'popper.js';
 import fetcher from './fetcher';
 import Game, {GameStatus} from './Game';
-import SocketConstants from './socket/constants';
+import SocketConstants, {PROTOCOL_VERSION} from './socket/constants';
 import GameSocket from './socket/GameSocket'
 import {BOARD_SIZE, boardMatrixToObj, charToColor, chessToRowCol, colorToChar, rowColToChess} from './chess-helpers';
 
@@ -25,18 +25,33 @@ let pawnPromotionPopper;
 let promotionMove;
 
 let game;
+let gameId;
 let gameSocket;
 let availableMoves;
+let clockTicker;
 
-const isSpectate = new URLSearchParams(window.location.search).has('spectate');
+// ?spectate=<game id> watches a web game, ?spectate=true the tournament
+const spectateParam = new URLSearchParams(window.location.search).get('spectate');
+const isSpectate = spectateParam !== null;
+const spectatePath = spectateParam && spectateParam !== 'true'
+  ? `/ws-spectate/${encodeURIComponent(spectateParam)}`
+  : '/ws-spectate';
+// The game this tab plays, kept across page refreshes
+const SESSION_KEY = 'chessGameSession';
 
 // Initial UI
 setup();
 
 if (isSpectate) {
-  gameSocket = new GameSocket(messageHandler, '/ws-spectate');
+  gameSocket = new GameSocket(messageHandler, spectatePath);
   $('.game-status').show();
   $('#start-btn').hide();
+  $('.start-options').hide();
+} else {
+  const session = JSON.parse(window.sessionStorage.getItem(SESSION_KEY) || 'null');
+  if (session) {
+    joinGame(session.gameId, session.token);
+  }
 }
 
 $(document).ready(() => {
@@ -52,12 +67,52 @@ function setup() {
   $('.pawn-promotion').hide();
   $('.white-promotion').hide();
   $('.black-promotion').hide();
-  $('#concede-btn').hide();
+  $('.game-actions').hide();
   $('.chessboard-63f37').addClass('inactive');
   $('.game-error').hide();
   $('.game-status').hide();
   $('.game-status .status-alert').hide();
   $('.ai-thinking').hide();
+  $('.search-info').hide();
+  $('.spectate-share').hide();
+  $('.clocks').hide();
+}
+
+function formatClock(ms) {
+  const totalSecs = Math.max(0, Math.ceil(ms / 1000));
+  const mins = Math.floor(totalSecs / 60);
+  const secs = totalSecs % 60;
+  return `${mins}:${secs < 10 ? '0' : ''}${secs}`;
+}
+
+// The server sends the clocks with every game status; in between, the side
+// to move's clock counts down here.
+function updateClocks(clocks) {
+  game.clocks = clocks || null;
+  game.clocksReceived = Date.now();
+  clearInterval(clockTicker);
+  if (!game.clocks) {
+    $('.clocks').hide();
+    return;
+  }
+  $('.clocks').show();
+  renderClocks();
+  if (game.status === GameStatus.Active) {
+    clockTicker = setInterval(renderClocks, 200);
+  }
+}
+
+function renderClocks() {
+  const running = game.status === GameStatus.Active ? game.currentTurn : null;
+  ['White', 'Black'].forEach(side => {
+    let ms = game.clocks[side];
+    if (side === running) {
+      ms -= Date.now() - game.clocksReceived;
+    }
+    const clock = $(`.${side.toLowerCase()}-clock`);
+    clock.toggleClass('running', side === running);
+    clock.find('span').text(formatClock(ms));
+  });
 }
 
 function updateGameStatus() {
@@ -82,9 +137,22 @@ function updateGameStatus() {
       case GameStatus.BlackWin:
         alertText = 'Checkmate!';
         break;
+      case GameStatus.WhiteResigned:
+      case GameStatus.BlackResigned:
+        alertText = 'Resigned';
+        break;
+      case GameStatus.WhiteTimeout:
+      case GameStatus.BlackTimeout:
+        alertText = 'Out of time';
+        break;
       case GameStatus.Stalemate:
       case GameStatus.FiftyMoveDraw:
       case GameStatus.RepeatActionDraw:
+      case GameStatus.InsufficientMaterialDraw:
+      case GameStatus.DrawAgreed:
+      case GameStatus.FivefoldRepetitionDraw:
+      case GameStatus.SeventyFiveMoveDraw:
+      case GameStatus.DeadPositionDraw:
         alertText = 'Draw';
         break;
       case GameStatus.Aborted:
@@ -102,6 +170,35 @@ function updateGameStatus() {
   }
 }
 
+// joinGame connects to the game with the session token its start command
+// returned; the server resyncs the game when it is already under way.
+function joinGame(id, token) {
+  gameId = id;
+  window.sessionStorage.setItem(SESSION_KEY, JSON.stringify({gameId, token}));
+  const query = new URLSearchParams({token, version: PROTOCOL_VERSION});
+  gameSocket = new GameSocket(messageHandler, `/ws/${gameId}?${query}`);
+
+  $('.game-status').show();
+  $('.game-error').text('').hide();
+  $('.game-actions').show();
+  $('#spectate-link').attr('href', `?spectate=${encodeURIComponent(gameId)}`);
+  $('.spectate-share').show();
+  $('#start-btn').hide();
+  $('.start-options').hide();
+  $('.chessboard-63f37').removeClass('inactive');
+}
+
+// leaveGame forgets the game and offers to start another
+function leaveGame(error) {
+  window.sessionStorage.removeItem(SESSION_KEY);
+  gameSocket.close();
+  $('.game-error').text(error).show();
+  $('.game-actions').hide();
+  $('.spectate-share').hide();
+  $('#start-btn').show();
+  $('.start-options').show();
+}
+
 function clearBoard() {
   $('.square-highlight-move').removeClass('square-highlight-move');
   $('.square-active').removeClass('square-active');
@@ -109,16 +206,46 @@ function clearBoard() {
 
 /* Button Events */
 $('#start-btn').click(() => {
-  fetcher.post(`${window.location.protocol}//${window.location.host}/api/game?command=start`)
+  const params = new URLSearchParams({command: 'start'});
+  const fen = $('#start-fen').val().trim();
+  if (fen) {
+    params.set('fen', fen);
+  }
+  params.set('color', $('#start-color').val());
+  params.set('skill', $('#start-skill').val());
+  const thinkSecs = parseFloat($('#start-think').val());
+  if (thinkSecs > 0) {
+    params.set('thinkTimeMs', Math.round(thinkSecs * 1000));
+  }
+  const clockMins = parseFloat($('#start-clock').val());
+  if (clockMins > 0) {
+    params.set('clockSec', Math.round(clockMins * 60));
+    params.set('incrementSec', parseInt($('#start-increment').val(), 10) || 0);
+  }
+
+  fetcher.post(`${window.location.protocol}//${window.location.host}/api/game?${params}`)
   .then(response => {
-    gameSocket = new GameSocket(messageHandler);
+    joinGame(response.gameId, response.token);
+    console.log(response);
+  })
+  .catch(err => {
+    $('.game-error').text(err.error).show();
+    console.error(err);
+  })
+});
+
+// Player actions are handled on the player's turn
+$('#takeback-btn').click(() => gameSocket.send(SocketConstants.Takeback, null));
+$('#draw-btn').click(() => gameSocket.send(SocketConstants.OfferDraw, null));
+$('#claim-btn').click(() => gameSocket.send(SocketConstants.ClaimDraw, null));
+$('#concede-btn').click(() => gameSocket.send(SocketConstants.Resign, null));
 
-    $('.game-status').show();
+$('#restart-btn').click(() => {
+  fetcher.post(`${window.location.protocol}//${window.location.host}/api/game/${gameId}?command=restart`)
+  .then(() => {
+    // The new game sends its state over the open socket
     $('.game-error').text('').hide();
-    $('#concede-btn').show();
-    $('#start-btn').hide();
     $('.chessboard-63f37').removeClass('inactive');
-    console.log(response);
   })
   .catch(err => {
     $('.game-error').text(err.error).show();
@@ -153,6 +280,48 @@ $('.promotion-piece').click((event) => {
   $('.pawn-promotion').hide();
 });
 
+function applyGameState(data) {
+  game = new Game(
+    (data.humanColor || '').toLowerCase(),
+    data.gameStatus,
+    data.moveLimit,
+    data.timeLimit,
+  );
+  game.currentTurn = data.currentTurn;
+  game.movesPlayed = data.movesPlayed;
+  updateClocks(data.clocks);
+
+  board.position(boardMatrixToObj(data.currentBoard), false);
+  board.orientation(game.humanColor || 'white');
+  clearBoard();
+  $('.game-status .status-alert').hide();
+  if (isSpectate) {
+    $('.chessboard-63f37').removeClass('inactive');
+  }
+  updateGameStatus();
+}
+
+function applyGameStatus(data) {
+  game.currentTurn = data.currentTurn;
+  game.movesPlayed = data.movesPlayed;
+  game.status = data.gameStatus;
+  updateClocks(data.clocks);
+
+  // A threefold repetition or fifty-move draw only ends the game when claimed
+  const canClaim = !isSpectate && data.claimableDraw && game.currentTurn.toLowerCase() === game.humanColor;
+  $('#claim-btn').toggle(Boolean(canClaim)).attr('title', data.claimableDraw || '');
+
+  if (game.status === GameStatus.Active && data.kingInCheck) {
+    $('.game-status .status-alert').text('Check!').show();
+    speak('Check');
+  }
+  else {
+    $('.game-status .status-alert').hide();
+  }
+
+  updateGameStatus();
+}
+
 function messageHandler(event) {
   const message = JSON.parse(event.data);
   const data = message.data ? JSON.parse(message.data) : null;
@@ -162,23 +331,15 @@ function messageHandler(event) {
   switch (message.type) {
     case SocketConstants.GameState:
       gameSocket.resetReconnectAttempts();
-      game = new Game(
-        (data.humanColor || '').toLowerCase(),
-        data.gameStatus,
-        data.moveLimit,
-        data.timeLimit,
-      );
-      game.currentTurn = data.currentTurn;
-      game.movesPlayed = data.movesPlayed;
-
-      board.position(boardMatrixToObj(data.currentBoard), false);
-      board.orientation(game.humanColor || 'white');
-      clearBoard();
-      $('.game-status .status-alert').hide();
-      if (isSpectate) {
-        $('.chessboard-63f37').removeClass('inactive');
-      }
-      updateGameStatus();
+      applyGameState(data);
+      break;
+
+    case SocketConstants.Resync:
+      // A reconnect: the whole game, and our moves if it is our turn
+      gameSocket.resetReconnectAttempts();
+      applyGameState(data.state);
+      applyGameStatus(data.status);
+      availableMoves = data.availableMoves ? data.availableMoves.availableMoves : null;
       break;
 
     case SocketConstants.TournamentInfo:
@@ -188,19 +349,7 @@ function messageHandler(event) {
       break;
 
     case SocketConstants.GameStatus:
-      game.currentTurn = data.currentTurn;
-      game.movesPlayed = data.movesPlayed;
-      game.status = data.gameStatus;
-
-      if (game.status === GameStatus.Active && data.kingInCheck) {
-        $('.game-status .status-alert').text('Check!').show();
-        speak('Check');
-      }
-      else {
-        $('.game-status .status-alert').hide();
-      }
-
-      updateGameStatus();
+      applyGameStatus(data);
       break;
 
     case SocketConstants.AvailablePlayerMoves:
@@ -208,17 +357,43 @@ function messageHandler(event) {
       break;
 
     case SocketConstants.AIMove:
+    case SocketConstants.MovePlayed:
+      // Players are sent the AI's moves, spectators every move
+      $('.game-error').text('').hide();
       makeAIMove(data.start, data.end, data.piece, data.promotionPiece);
       break;
 
-    case SocketConstants.GameFull:
-      $('.game-error').text('A game is currently in progress...')
-      $("#start-btn").attr("disabled", true);
+    case SocketConstants.SearchInfo:
+      renderSearchInfo(data);
+      break;
+
+    case SocketConstants.InvalidMove:
+      // The gameState that follows puts the board back
+      $('.game-error').text(`Move refused: ${data.error}`).show();
+      break;
+
+    case SocketConstants.InvalidAction:
+      $('.game-error').text(data.error).show();
+      break;
+
+    case SocketConstants.DrawOfferAnswer:
+      // An accepted offer ends the game, which the gameStatus that follows shows
+      if (!data.accepted) {
+        $('.game-error').text('The AI declined your draw offer').show();
+      }
       break;
 
     case SocketConstants.GameNotAvailable:
-      gameSocket.close();
-      $('.game-error').text('Connection lost. Please reload the page.').show();
+      if (isSpectate) {
+        gameSocket.close();
+        $('.game-error').text('The game is no longer available.').show();
+      } else {
+        leaveGame('The game is no longer available. Start a new one.');
+      }
+      break;
+
+    case SocketConstants.ConnectionRejected:
+      leaveGame(data.error);
       break;
 
     case SocketConstants.TournamentResult:
@@ -230,6 +405,15 @@ function messageHandler(event) {
   }
 }
 
+// renderSearchInfo shows spectators the AI's latest completed search depth
+function renderSearchInfo(info) {
+  const pawns = (info.score / 100).toFixed(2);
+  const score = info.score > 0 ? `+${pawns}` : pawns;
+  $('.search-info')
+    .text(`${info.color} AI — depth ${info.depth}, ${score} (${(info.elapsedMs / 1000).toFixed(1)}s): ${info.pvSan.join(' ')}`)
+    .show();
+}
+
 function renderTournamentResult(leaderboard) {
   const tbody = document.getElementById('tournament-results-body');
   tbody.innerHTML = '';
@@ -1,11 +1,24 @@
+// PROTOCOL_VERSION is the version of the game socket protocol this page speaks
+export const PROTOCOL_VERSION = 2;
+
 export default {
 	PlayerMove: 'playerMove',
 	AIMove: 'aiMove',
 	AvailablePlayerMoves: 'availablePlayerMoves',
 	GameState: 'gameState',
   GameStatus: 'gameStatus',
-	GameFull: 'gameFull',
   GameNotAvailable: 'gameNotAvailable',
   TournamentInfo: 'tournamentInfo',
   TournamentResult: 'tournamentResult',
+  InvalidMove: 'invalidMove',
+  Takeback: 'takeback',
+  Resign: 'resign',
+  OfferDraw: 'offerDraw',
+  ClaimDraw: 'claimDraw',
+  DrawOfferAnswer: 'drawOfferAnswer',
+  InvalidAction: 'invalidAction',
+  Resync: 'resync',
+  ConnectionRejected: 'connectionRejected',
+  MovePlayed: 'movePlayed',
+  SearchInfo: 'searchInfo',
 };